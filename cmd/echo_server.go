@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/FabioSM46/network-stability-logger/monitor"
+	"github.com/spf13/cobra"
+)
+
+var echoServerCmd = &cobra.Command{
+	Use:   "echo-server",
+	Short: "Answer heartbeats from monitors in heartbeat mode",
+	Long: `Runs the echo server that TCP monitors in heartbeat mode connect to.
+Run it on a host you control (a VPS, for example) and list it in tcp.targets
+with tcp.mode: heartbeat. Every heartbeat is echoed back unchanged, so the
+monitor detects a dead path within seconds and logs the round-trip time.
+
+Logs go to --log-path, or echo-server.log next to the executable.`,
+	RunE: runEchoServer,
+}
+
+func init() {
+	echoServerCmd.Flags().String("listen", ":9400", "Address to listen on")
+	echoServerCmd.Flags().Duration("idle-timeout", 2*time.Minute,
+		"Close connections that send no heartbeat for this long")
+}
+
+func runEchoServer(cmd *cobra.Command, _ []string) error {
+	listen, _ := cmd.Flags().GetString("listen")
+	idleTimeout, _ := cmd.Flags().GetDuration("idle-timeout")
+	if idleTimeout <= 0 {
+		return fmt.Errorf("--idle-timeout must be positive")
+	}
+
+	logFile := logPath
+	if logFile == "" {
+		logFile = filepath.Join(filepath.Dir(monitor.GetDefaultLogPath()), "echo-server.log")
+	}
+	logger, err := monitor.NewLogger(logFile, monitor.LoggerOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+	defer func() { _ = logger.Close() }()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return monitor.NewEchoServer(ctx, logger, listen, idleTimeout).Run()
+}
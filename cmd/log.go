@@ -4,19 +4,24 @@ package cmd
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/FabioSM46/network-stability-logger/monitor"
 	"github.com/spf13/cobra"
 )
 
 var logCmd = &cobra.Command{
 	Use:   "log",
 	Short: "Display network monitor logs",
-	Long:  `Display logs from the network stability monitor.`,
-	RunE:  runLog,
+	Long: `Display logs from the network stability monitor.
+
+Both the text and the JSON log formats are recognized; JSON lines are
+rendered in the same layout as the text format.`,
+	RunE: runLog,
 }
 
 func init() {
@@ -29,12 +34,23 @@ func init() {
 		"",
 		"Filter by category: SYSTEM, LINK, ADDRESS, ROUTE, DNS, TCP, WATCHDOG, MONITOR",
 	)
+	logCmd.Flags().String("level", "", "Only show events at or above this severity: DEBUG, INFO, WARN, ERROR, CRITICAL")
 }
 
 func runLog(cmd *cobra.Command, _ []string) error {
 	lines, _ := cmd.Flags().GetInt("lines")
 	follow, _ := cmd.Flags().GetBool("follow")
-	filter, _ := cmd.Flags().GetString("filter")
+	category, _ := cmd.Flags().GetString("filter")
+	level, _ := cmd.Flags().GetString("level")
+
+	filter := logFilter{category: category, level: monitor.SeverityDebug}
+	if level != "" {
+		severity, err := monitor.ParseSeverity(level)
+		if err != nil {
+			return fmt.Errorf("invalid --level: %w", err)
+		}
+		filter.level = severity
+	}
 
 	logFile := getLogPath()
 
@@ -50,44 +66,58 @@ func runLog(cmd *cobra.Command, _ []string) error {
 	return displayLog(logFile, lines, filter)
 }
 
-func displayLog(logFile string, numLines int, filter string) error {
-	// Clean the path to mitigate path traversal concerns
-	cleanPath := filepath.Clean(logFile)
-	file, err := os.Open(cleanPath)
+func displayLog(logFile string, numLines int, filter logFilter) error {
+	segments, err := monitor.LogSegments(logFile)
 	if err != nil {
-		return fmt.Errorf("failed to open log file: %w", err)
+		return err
 	}
-	defer func() { _ = file.Close() }()
 
-	// Read all lines
+	// Read every segment, oldest first, keeping only the last N lines
 	var allLines []string
+	for _, segment := range segments {
+		lines, err := readSegment(segment, filter)
+		if err != nil {
+			return err
+		}
+		allLines = append(allLines, lines...)
+		if numLines > 0 && len(allLines) > numLines {
+			allLines = allLines[len(allLines)-numLines:]
+		}
+	}
+
+	for _, line := range allLines {
+		fmt.Println(line)
+	}
+
+	return nil
+}
+
+// readSegment returns the rendered lines of one log segment that match filter.
+func readSegment(segment string, filter logFilter) ([]string, error) {
+	file, err := monitor.OpenLogSegment(segment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var lines []string
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		line := scanner.Text()
-		if filter != "" && !matchesFilter(line, filter) {
+		if !filter.matches(line) {
 			continue
 		}
-		allLines = append(allLines, line)
+		lines = append(lines, renderLine(line))
 	}
 
 	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading log file: %w", err)
+		return nil, fmt.Errorf("error reading log file %s: %w", segment, err)
 	}
 
-	// Display last N lines
-	start := 0
-	if numLines > 0 && len(allLines) > numLines {
-		start = len(allLines) - numLines
-	}
-
-	for i := start; i < len(allLines); i++ {
-		fmt.Println(allLines[i])
-	}
-
-	return nil
+	return lines, nil
 }
 
-func followLog(logFile string, filter string) error {
+func followLog(logFile string, filter logFilter) error {
 	// Clean the path to mitigate path traversal concerns
 	cleanPath := filepath.Clean(logFile)
 	file, err := os.Open(cleanPath)
@@ -97,37 +127,119 @@ func followLog(logFile string, filter string) error {
 	defer func() { _ = file.Close() }()
 
 	// Seek to end of file
-	_, _ = file.Seek(0, 2)
+	_, _ = file.Seek(0, io.SeekEnd)
 
 	fmt.Printf("Following log file: %s\n", logFile)
-	if filter != "" {
-		fmt.Printf("Filtering by: [%s]\n", filter)
+	if filter.category != "" {
+		fmt.Printf("Filtering by: [%s]\n", filter.category)
+	}
+	if filter.level > monitor.SeverityDebug {
+		fmt.Printf("Minimum level: %s\n", filter.level)
 	}
 	fmt.Println("Press Ctrl+C to stop...")
 
-	scanner := bufio.NewScanner(file)
+	tail := &lineTail{reader: bufio.NewReader(file), filter: filter}
 	for {
-		if !scanner.Scan() {
-			// No new data, wait a bit
-			time.Sleep(100 * time.Millisecond)
-			// Check if file still exists
-			if _, err := os.Stat(cleanPath); os.IsNotExist(err) {
-				return fmt.Errorf("log file was removed")
-			}
-			if err := scanner.Err(); err != nil {
-				return fmt.Errorf("error reading log file: %w", err)
-			}
+		if err := tail.drain(); err != nil {
+			return err
+		}
+
+		// No new data, wait a bit
+		time.Sleep(100 * time.Millisecond)
+
+		// After a rotation the path names a new file; finish the old one
+		// and continue from the start of the new one
+		if !fileReplaced(cleanPath, file) {
 			continue
 		}
+		next, err := os.Open(cleanPath)
+		if err != nil {
+			continue // Rotated file not recreated yet
+		}
+		if err := tail.drain(); err != nil {
+			_ = next.Close()
+			return err
+		}
+		_ = file.Close()
+		file = next
+		tail.reader.Reset(file)
+	}
+}
 
-		line := scanner.Text()
-		if filter != "" && !matchesFilter(line, filter) {
+// lineTail prints complete lines from a growing file, holding back a
+// trailing partial line until its newline arrives.
+type lineTail struct {
+	reader  *bufio.Reader
+	filter  logFilter
+	partial string
+}
+
+// drain prints every complete line available and returns at end of file.
+func (t *lineTail) drain() error {
+	for {
+		chunk, err := t.reader.ReadString('\n')
+		t.partial += chunk
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading log file: %w", err)
+		}
+
+		line := strings.TrimRight(t.partial, "\r\n")
+		t.partial = ""
+		if !t.filter.matches(line) {
 			continue
 		}
-		fmt.Println(line)
+		fmt.Println(renderLine(line))
+	}
+}
+
+// fileReplaced reports whether path now refers to a different file than the
+// one open in file, as happens after the monitor rotates its log.
+func fileReplaced(path string, file *os.File) bool {
+	pathInfo, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return false
 	}
+	return !os.SameFile(pathInfo, fileInfo)
+}
+
+// logFilter selects log lines by category and minimum severity.
+type logFilter struct {
+	category string
+	level    monitor.Severity
 }
 
-func matchesFilter(line, filter string) bool {
-	return strings.Contains(line, fmt.Sprintf("[%s]", filter))
+func (f logFilter) matches(line string) bool {
+	e, ok := monitor.ParseLine(line)
+	if !ok {
+		// Unrecognized lines only pass when no filter is active
+		if f.level > monitor.SeverityDebug {
+			return false
+		}
+		return f.category == "" || strings.Contains(line, fmt.Sprintf("[%s]", f.category))
+	}
+
+	if f.category != "" && !strings.EqualFold(e.Category, f.category) {
+		return false
+	}
+	return e.Severity >= f.level
+}
+
+// renderLine pretty-prints JSON log lines in the text layout and returns
+// text lines unchanged.
+func renderLine(line string) string {
+	if !strings.HasPrefix(strings.TrimSpace(line), "{") {
+		return line
+	}
+	e, ok := monitor.ParseLine(line)
+	if !ok {
+		return line
+	}
+	return monitor.TextFormatter{}.Format(e)
 }
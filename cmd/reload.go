@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+var reloadCmd = &cobra.Command{
+	Use:   "reload",
+	Short: "Reload the configuration of the running monitor",
+	Long: `Signals the running network stability monitor (SIGHUP) to re-read its
+config file. New targets, intervals and timeouts are applied without a
+restart; connectivity state and open outages are kept. An invalid config is
+rejected with an error in the log and the previous config keeps running.
+
+The config file is validated here first, so most mistakes are reported
+before the monitor is signaled; pass the same --config as to start.`,
+	RunE: runReload,
+}
+
+func runReload(_ *cobra.Command, _ []string) error {
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+
+	pidFile, err := pidFilePath()
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("network monitor is not running (no PID file found)")
+		}
+		return fmt.Errorf("failed to read PID file: %w", err)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return fmt.Errorf("invalid PID in file: %w", err)
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("process %d not found: %w", pid, err)
+	}
+
+	if err := process.Signal(syscall.SIGHUP); err != nil {
+		return fmt.Errorf("failed to send SIGHUP: %w", err)
+	}
+
+	fmt.Printf("Sent reload signal to network monitor (PID: %d)\n", pid)
+	fmt.Println("Use 'network-monitor log -F MONITOR' to confirm the new configuration was applied")
+	return nil
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/FabioSM46/network-stability-logger/monitor"
+	"github.com/spf13/cobra"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Summarize uptime and outages from the log",
+	Long: `Summarize connectivity over a time range from the monitor's log:
+uptime percentage, number of outages, longest and median outage, mean time
+to recovery, outages per day and the most common root causes.
+
+An outage is a period in which the monitor's connectivity state reached
+OFFLINE; periods that only reached DEGRADED are reported separately.
+
+With --format html the report is written as a self-contained HTML document
+with a connectivity timeline, the outage table, latency graphs and host
+details, suitable for sending to an ISP.`,
+	RunE: runReport,
+}
+
+func init() {
+	reportCmd.Flags().String("since", "7d", "Start of the range: age (7d, 12h, 2w), date (2006-01-02) or RFC 3339")
+	reportCmd.Flags().String("until", "", "End of the range, same formats as --since (default: now)")
+	reportCmd.Flags().String("format", "text", "Output format: text or html")
+	reportCmd.Flags().StringP("output", "o", "", "Write the report to this file instead of stdout")
+}
+
+func runReport(cmd *cobra.Command, _ []string) error {
+	since, _ := cmd.Flags().GetString("since")
+	until, _ := cmd.Flags().GetString("until")
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+
+	if format != "text" && format != "html" {
+		return fmt.Errorf("invalid --format %q (expected text or html)", format)
+	}
+
+	from, to, err := reportRange(since, until)
+	if err != nil {
+		return err
+	}
+
+	logFile := getLogPath()
+	events, err := monitor.ReadEvents(logFile, to)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("no log entries found in %s", logFile)
+	}
+
+	report := monitor.BuildReport(events, from, to)
+
+	var out io.Writer = os.Stdout
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create report file: %w", err)
+		}
+		defer func() { _ = file.Close() }()
+		out = file
+	}
+
+	if format == "html" {
+		if err := monitor.WriteHTMLReport(out, report, monitor.CollectHostInfo(logFile)); err != nil {
+			return err
+		}
+	} else {
+		printReport(out, report)
+	}
+
+	if output != "" {
+		fmt.Printf("Report written to %s\n", output)
+	}
+	return nil
+}
+
+// reportRange resolves the --since and --until flags into a time range.
+func reportRange(since, until string) (time.Time, time.Time, error) {
+	now := time.Now()
+
+	from, err := monitor.ParseSince(since, now)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid --since: %w", err)
+	}
+
+	to := now
+	if until != "" {
+		if to, err = monitor.ParseSince(until, now); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("--until must be after --since")
+	}
+	return from, to, nil
+}
+
+func printReport(out io.Writer, r monitor.Report) {
+	const layout = "2006-01-02 15:04:05"
+
+	_, _ = fmt.Fprintf(out, "Network report: %s -> %s\n\n", r.From.Format(layout), r.To.Format(layout))
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "Monitored:\t%s\n", monitor.HumanDuration(r.Monitored))
+	_, _ = fmt.Fprintf(w, "Uptime:\t%.3f%% (offline %s, degraded %s)\n",
+		r.UptimePercent(), monitor.HumanDuration(r.Offline), monitor.HumanDuration(r.Degraded))
+	_, _ = fmt.Fprintf(w, "Outages:\t%d (%.2f/day)\n", len(r.Outages), r.OutagesPerDay())
+	if longest, ok := r.LongestOutage(); ok {
+		_, _ = fmt.Fprintf(w, "Longest outage:\t%s (started %s, %s)\n",
+			monitor.HumanDuration(longest.Duration()), longest.Start.Format(layout), causeName(longest.Cause))
+		_, _ = fmt.Fprintf(w, "Median outage:\t%s\n", monitor.HumanDuration(r.MedianOutage()))
+		_, _ = fmt.Fprintf(w, "MTTR:\t%s\n", monitor.HumanDuration(r.MTTR()))
+	}
+	_, _ = fmt.Fprintf(w, "Degraded episodes:\t%d\n", r.DegradedEpisodes)
+	_ = w.Flush()
+
+	if len(r.Outages) == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintln(out, "\nTop root causes:")
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, c := range r.TopCauses(5) {
+		_, _ = fmt.Fprintf(w, "  %s\t%d\n", c.Cause, c.Count)
+	}
+	_ = w.Flush()
+
+	_, _ = fmt.Fprintln(out, "\nOutages:")
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "  START\tDURATION\tCAUSE\tREASONS")
+	for _, o := range r.Outages {
+		duration := monitor.HumanDuration(o.Duration())
+		if o.Ongoing || o.Stopped {
+			duration += "+"
+		}
+		_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n",
+			o.Start.Format(layout), duration, causeName(o.Cause), strings.Join(o.Reasons, ","))
+	}
+	_ = w.Flush()
+}
+
+func causeName(cause monitor.OutageCause) string {
+	if cause == "" {
+		return "unclassified"
+	}
+	return string(cause)
+}
@@ -1,14 +1,17 @@
 package cmd
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 
+	"github.com/FabioSM46/network-stability-logger/monitor"
 	"github.com/spf13/cobra"
 )
 
 var (
-	logPath string
+	logPath    string
+	configPath string
 )
 
 var rootCmd = &cobra.Command{
@@ -31,10 +34,16 @@ func Execute() error {
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&logPath, "log-path", "l", "",
 		"Path to log file (default: $HOME/.network-monitor/network-monitor.log)")
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "",
+		"Path to config file (default: $HOME/.network-monitor/config.yaml if it exists)")
 
 	rootCmd.AddCommand(startCmd)
 	rootCmd.AddCommand(stopCmd)
+	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(logCmd)
+	rootCmd.AddCommand(reloadCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(echoServerCmd)
 }
 
 func getLogPath() string {
@@ -49,3 +58,17 @@ func getLogPath() string {
 	exeDir := filepath.Dir(exePath)
 	return filepath.Join(exeDir, "network-monitor.log")
 }
+
+// loadConfig reads the file given with --config, or the default config file
+// when it exists; otherwise the built-in defaults apply.
+func loadConfig() (*monitor.Config, error) {
+	if configPath != "" {
+		return monitor.LoadConfig(configPath)
+	}
+
+	path := monitor.DefaultConfigPath()
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return monitor.DefaultConfig(), nil
+	}
+	return monitor.LoadConfig(path)
+}
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/FabioSM46/network-stability-logger/monitor"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var startCmd = &cobra.Command{
@@ -21,12 +23,36 @@ var startCmd = &cobra.Command{
 }
 
 func init() {
-	startCmd.Flags().BoolP("foreground", "f", false, "Run in foreground (don't daemonize)")
+	addStartFlags(startCmd.Flags())
+}
+
+// addStartFlags defines the start command flags on flags.
+func addStartFlags(flags *pflag.FlagSet) {
+	flags.BoolP("foreground", "f", false, "Run in foreground (don't daemonize)")
+	flags.String("log-format", monitor.FormatText, "Log file format: text or json")
+	flags.Int("log-max-size", 0, "Rotate the log file when it exceeds this many megabytes (0 disables)")
+	flags.Bool("log-daily", false, "Rotate the log file at midnight")
+	flags.Int("log-max-backups", 0, "Number of rotated log files to keep (0 keeps all)")
+	flags.Bool("log-compress", false, "Gzip rotated log files")
+	flags.String("log-level", "INFO", "Minimum severity to log: DEBUG, INFO, WARN, ERROR, CRITICAL")
+	flags.StringSlice("category-level", nil,
+		"Per-category minimum severity as CATEGORY=LEVEL, e.g. WATCHDOG=WARN (repeatable)")
 }
 
 func runStart(cmd *cobra.Command, _ []string) error {
 	foreground, _ := cmd.Flags().GetBool("foreground")
 
+	// Reject bad log options and config before daemonizing so the error reaches the user
+	logOpts, err := loggerOptions(cmd)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
 	logFile := getLogPath()
 
 	// If not in foreground and not already a daemon child, re-exec as daemon
@@ -43,7 +69,7 @@ func runStart(cmd *cobra.Command, _ []string) error {
 	}
 
 	// Create and start the monitor
-	nm, err := monitor.NewNetworkMonitor(logFile)
+	nm, err := monitor.NewNetworkMonitor(logFile, logOpts, cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create network monitor: %w", err)
 	}
@@ -52,19 +78,32 @@ func runStart(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("failed to start network monitor: %w", err)
 	}
 
+	statusFile, err := statusFilePath()
+	if err != nil {
+		return err
+	}
+	stopStatus := publishStatus(nm, statusFile)
+
 	if foreground {
 		fmt.Printf("Network monitor started successfully\n")
 		fmt.Printf("Logging to: %s\n", logFile)
 		fmt.Printf("Press Ctrl+C to stop...\n\n")
 	}
 
-	// Wait for interrupt signal
+	// Reload the config on SIGHUP until an interrupt or termination signal
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 
-	<-sigChan
+	for sig := range sigChan {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		// The outcome is logged; a rejected config leaves the current one running
+		_ = nm.Reload(loadConfig)
+	}
 
 	fmt.Println("\nStopping network monitor...")
+	stopStatus()
 	if err := nm.Stop(); err != nil {
 		return fmt.Errorf("failed to stop network monitor: %w", err)
 	}
@@ -72,3 +111,77 @@ func runStart(cmd *cobra.Command, _ []string) error {
 	fmt.Println("Network monitor stopped")
 	return nil
 }
+
+// daemonArgs returns the flags the user set as arguments for the daemon child.
+// Slice flags are passed once per element, since their String form ("[a,b]")
+// does not parse back.
+func daemonArgs(flags *pflag.FlagSet) []string {
+	var args []string
+	flags.Visit(func(f *pflag.Flag) {
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			for _, v := range slice.GetSlice() {
+				args = append(args, fmt.Sprintf("--%s=%s", f.Name, v))
+			}
+			return
+		}
+		args = append(args, fmt.Sprintf("--%s=%s", f.Name, f.Value.String()))
+	})
+	return args
+}
+
+// loggerOptions builds the logger options from the start command flags.
+func loggerOptions(cmd *cobra.Command) (monitor.LoggerOptions, error) {
+	logFormat, _ := cmd.Flags().GetString("log-format")
+	maxSize, _ := cmd.Flags().GetInt("log-max-size")
+	daily, _ := cmd.Flags().GetBool("log-daily")
+	maxBackups, _ := cmd.Flags().GetInt("log-max-backups")
+	compress, _ := cmd.Flags().GetBool("log-compress")
+	logLevel, _ := cmd.Flags().GetString("log-level")
+	categoryLevels, _ := cmd.Flags().GetStringSlice("category-level")
+
+	if _, err := monitor.NewFormatter(logFormat); err != nil {
+		return monitor.LoggerOptions{}, err
+	}
+	if maxSize < 0 || maxBackups < 0 {
+		return monitor.LoggerOptions{}, fmt.Errorf("--log-max-size and --log-max-backups must not be negative")
+	}
+
+	minLevel, err := monitor.ParseSeverity(logLevel)
+	if err != nil {
+		return monitor.LoggerOptions{}, fmt.Errorf("invalid --log-level: %w", err)
+	}
+
+	levels, err := parseCategoryLevels(categoryLevels)
+	if err != nil {
+		return monitor.LoggerOptions{}, err
+	}
+
+	return monitor.LoggerOptions{
+		Format:         logFormat,
+		MinLevel:       minLevel,
+		CategoryLevels: levels,
+		Rotation: monitor.RotationOptions{
+			MaxSize:    int64(maxSize) * 1024 * 1024,
+			Daily:      daily,
+			MaxBackups: maxBackups,
+			Compress:   compress,
+		},
+	}, nil
+}
+
+// parseCategoryLevels parses CATEGORY=LEVEL pairs into a level map.
+func parseCategoryLevels(pairs []string) (map[string]monitor.Severity, error) {
+	levels := make(map[string]monitor.Severity, len(pairs))
+	for _, pair := range pairs {
+		category, level, ok := strings.Cut(pair, "=")
+		if !ok || category == "" {
+			return nil, fmt.Errorf("invalid --category-level %q (expected CATEGORY=LEVEL)", pair)
+		}
+		severity, err := monitor.ParseSeverity(level)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --category-level %q: %w", pair, err)
+		}
+		levels[strings.ToUpper(category)] = severity
+	}
+	return levels, nil
+}
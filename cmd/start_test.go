@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestDaemonArgs(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []string
+	}{
+		{name: "none"},
+		{name: "scalars", args: []string{"--log-format=json", "--log-max-size", "5", "--log-daily", "--log-level=warn"}},
+		{name: "one category level", args: []string{"--category-level=WATCHDOG=WARN"}},
+		{name: "category levels", args: []string{"--category-level", "WATCHDOG=WARN,tcp=error", "--category-level=DNS=DEBUG"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			parent := &cobra.Command{}
+			addStartFlags(parent.Flags())
+			if err := parent.Flags().Parse(tc.args); err != nil {
+				t.Fatal(err)
+			}
+			want, err := loggerOptions(parent)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			argv := daemonArgs(parent.Flags())
+			child := &cobra.Command{}
+			addStartFlags(child.Flags())
+			if err := child.Flags().Parse(argv); err != nil {
+				t.Fatalf("daemon child rejected %q: %v", argv, err)
+			}
+			got, err := loggerOptions(child)
+			if err != nil {
+				t.Fatalf("daemon child rejected %q: %v", argv, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("daemon child options from %q = %+v, want %+v", argv, got, want)
+			}
+		})
+	}
+}
@@ -15,19 +15,7 @@ import (
 // daemonize forks the process to run as a background daemon (Unix-like systems).
 func daemonize(cmd *cobra.Command, logFile string) error {
 	// Re-exec self as daemon child
-	argv := []string{os.Args[0], "start"}
-
-	// Preserve foreground flag (will be false by default)
-	foreground, _ := cmd.Flags().GetBool("foreground")
-	if foreground {
-		argv = append(argv, "-f")
-	}
-
-	// Preserve log path if custom
-	logPath, _ := cmd.Flags().GetString("log-path")
-	if logPath != "" {
-		argv = append(argv, "-l", logPath)
-	}
+	argv := append([]string{os.Args[0], "start"}, daemonArgs(cmd.Flags())...)
 
 	attr := &syscall.ProcAttr{
 		Dir:   ".",
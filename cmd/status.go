@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/FabioSM46/network-stability-logger/monitor"
+	"github.com/spf13/cobra"
+)
+
+// statusInterval is how often the running monitor refreshes its status file.
+const statusInterval = 5 * time.Second
+
+const statusTimeLayout = "2006-01-02 15:04:05"
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of the running monitor",
+	Long: `Shows the connectivity state of the running network stability monitor,
+the open outage if there is one, and the state of every TCP keepalive target.`,
+	RunE: runStatus,
+}
+
+func runStatus(_ *cobra.Command, _ []string) error {
+	statusFile, err := statusFilePath()
+	if err != nil {
+		return err
+	}
+
+	s, err := monitor.ReadStatusFile(statusFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Println("Network monitor is not running (no status file found)")
+			return nil
+		}
+		return fmt.Errorf("failed to read status: %w", err)
+	}
+
+	now := time.Now()
+
+	if age := now.Sub(s.Updated); age > 3*statusInterval {
+		fmt.Printf("Network monitor is not responding (PID %d, last update %s ago)\n",
+			s.PID, monitor.HumanDuration(age))
+	} else {
+		fmt.Printf("Network monitor is running (PID %d, started %s)\n", s.PID, s.Started.Format(statusTimeLayout))
+	}
+
+	config := s.ConfigPath
+	if config == "" {
+		config = "built-in defaults"
+	}
+	fmt.Printf("Config: %s\n\n", config)
+
+	fmt.Printf("State:  %s since %s (%s)", s.State, s.Since.Format(statusTimeLayout), monitor.HumanDuration(now.Sub(s.Since)))
+	if len(s.Reasons) > 0 {
+		fmt.Printf(" reason=%s", strings.Join(s.Reasons, ","))
+	}
+	fmt.Println()
+	for _, f := range s.Families {
+		fmt.Printf("  %-5s %s since %s", f.Family, f.State, f.Since.Format(statusTimeLayout))
+		if len(f.Reasons) > 0 {
+			fmt.Printf(" reason=%s", strings.Join(f.Reasons, ","))
+		}
+		fmt.Println()
+	}
+
+	if o := s.Outage; o != nil {
+		cause := string(o.Cause)
+		if cause == "" {
+			cause = "unclassified"
+		}
+		fmt.Printf("Outage: #%d since %s (%s), worst %s, cause %s\n",
+			o.ID, o.Start.Format(statusTimeLayout), monitor.HumanDuration(now.Sub(o.Start)), o.Worst, cause)
+	}
+
+	fmt.Printf("\nTCP keepalive targets: %s\n", describeTargets(s.TCPTargets, s.TCPQuorum))
+	if err := printTargets(s.TCPTargets); err != nil {
+		return err
+	}
+	if len(s.TCP6Targets) > 0 {
+		fmt.Printf("\nIPv6 TCP keepalive targets: %s\n", describeTargets(s.TCP6Targets, s.TCP6Quorum))
+		if err := printTargets(s.TCP6Targets); err != nil {
+			return err
+		}
+	}
+
+	for _, u := range s.Uplinks {
+		var bound []string
+		if u.Interface != "" {
+			bound = append(bound, "dev "+u.Interface)
+		}
+		if u.Source != "" {
+			bound = append(bound, "src "+u.Source)
+		}
+		fmt.Printf("\nUplink %s (%s): %s since %s",
+			u.Name, strings.Join(bound, ", "), u.State, u.Since.Format(statusTimeLayout))
+		if len(u.Reasons) > 0 {
+			fmt.Printf(" reason=%s", strings.Join(u.Reasons, ","))
+		}
+		fmt.Printf("\n  TCP keepalive targets: %s\n", describeTargets(u.TCPTargets, s.TCPQuorum))
+		if err := printTargets(u.TCPTargets); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describeTargets summarizes how many targets are down against the quorum.
+func describeTargets(targets []monitor.TargetStatus, quorum int) string {
+	down := 0
+	for _, t := range targets {
+		if t.State == monitor.TargetDown {
+			down++
+		}
+	}
+	return fmt.Sprintf("%d of %d down (down at %d)", down, len(targets), quorum)
+}
+
+// printTargets prints one aligned line per keepalive target.
+func printTargets(targets []monitor.TargetStatus) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, t := range targets {
+		line := fmt.Sprintf("  %s\t%s\tsince %s", t.Target, t.State, t.Since.Format(statusTimeLayout))
+		if t.Failures > 1 {
+			line += fmt.Sprintf("\t%d failed attempts", t.Failures)
+		}
+		if t.RTT > 0 {
+			line += fmt.Sprintf("\trtt %v", t.RTT.Round(100*time.Microsecond))
+		}
+		if i := t.TCPInfo; i != nil {
+			line += fmt.Sprintf("\ttcp rtt %v ±%v, %d retransmits",
+				i.RTT.Round(100*time.Microsecond), i.RTTVar.Round(100*time.Microsecond), i.TotalRetrans)
+		}
+		if t.Degraded != "" {
+			line += "\tdegraded: " + t.Degraded
+		}
+		if t.LastError != "" {
+			line += "\t" + t.LastError
+		}
+		_, _ = fmt.Fprintln(w, line)
+	}
+	return w.Flush()
+}
+
+// publishStatus writes the monitor's status file now and every
+// statusInterval. The returned function stops publishing and removes the file.
+func publishStatus(nm *monitor.NetworkMonitor, path string) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+
+		ticker := time.NewTicker(statusInterval)
+		defer ticker.Stop()
+
+		for {
+			// Best effort: the status command reports a stale or missing file
+			_ = monitor.WriteStatusFile(path, nm.Status())
+
+			select {
+			case <-done:
+				_ = os.Remove(path)
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
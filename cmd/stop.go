@@ -18,13 +18,29 @@ var stopCmd = &cobra.Command{
 	RunE:  runStop,
 }
 
-func runStop(_ *cobra.Command, _ []string) error {
+// pidFilePath returns the PID file written when the monitor daemonizes.
+func pidFilePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Clean(filepath.Join(homeDir, ".network-monitor", "monitor.pid")), nil
+}
+
+// statusFilePath returns the status file the running monitor keeps up to date.
+func statusFilePath() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return "", fmt.Errorf("failed to get home directory: %w", err)
 	}
+	return filepath.Clean(filepath.Join(homeDir, ".network-monitor", "status.json")), nil
+}
 
-	pidFile := filepath.Clean(filepath.Join(homeDir, ".network-monitor", "monitor.pid"))
+func runStop(_ *cobra.Command, _ []string) error {
+	pidFile, err := pidFilePath()
+	if err != nil {
+		return err
+	}
 
 	// Read PID file
 	data, err := os.ReadFile(pidFile)
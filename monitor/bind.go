@@ -0,0 +1,51 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// newDialer returns a dialer for network ("tcp", "udp" or a variant) whose
+// connections, including the DNS lookups for them, leave through uplink.
+// A nil uplink follows the routing table.
+func newDialer(uplink *UplinkConfig, network string, timeout time.Duration) *net.Dialer {
+	if uplink == nil {
+		return &net.Dialer{Timeout: timeout}
+	}
+
+	d := pinnedDialer(uplink, network, timeout)
+	d.Resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return pinnedDialer(uplink, network, timeout).DialContext(ctx, network, address)
+		},
+	}
+	return d
+}
+
+// pinnedDialer returns a dialer for network bound to the interface and
+// source address of uplink.
+func pinnedDialer(uplink *UplinkConfig, network string, timeout time.Duration) *net.Dialer {
+	d := &net.Dialer{Timeout: timeout}
+
+	if ip := net.ParseIP(uplink.Source); ip != nil {
+		if strings.HasPrefix(network, "udp") {
+			d.LocalAddr = &net.UDPAddr{IP: ip}
+		} else {
+			d.LocalAddr = &net.TCPAddr{IP: ip}
+		}
+	}
+	if iface := uplink.Interface; iface != "" {
+		d.Control = func(_, _ string, c syscall.RawConn) error {
+			var bindErr error
+			if err := c.Control(func(fd uintptr) { bindErr = bindToDevice(fd, iface) }); err != nil {
+				return err
+			}
+			return bindErr
+		}
+	}
+	return d
+}
@@ -0,0 +1,21 @@
+//go:build linux
+
+package monitor
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// bindToDeviceSupported reports whether sockets can be bound to an interface.
+const bindToDeviceSupported = true
+
+// bindToDevice restricts the socket to iface with SO_BINDTODEVICE, which
+// needs CAP_NET_RAW on kernels before 5.7.
+func bindToDevice(fd uintptr, iface string) error {
+	if err := unix.BindToDevice(int(fd), iface); err != nil {
+		return fmt.Errorf("failed to bind to %s: %w", iface, err)
+	}
+	return nil
+}
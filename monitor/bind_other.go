@@ -0,0 +1,13 @@
+//go:build !linux
+
+package monitor
+
+import "errors"
+
+// bindToDeviceSupported reports whether sockets can be bound to an interface.
+const bindToDeviceSupported = false
+
+// bindToDevice is not supported on non-Linux platforms.
+func bindToDevice(fd uintptr, iface string) error {
+	return errors.New("binding to an interface is only supported on Linux")
+}
@@ -0,0 +1,352 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	RegisterCheck("captive_portal", newCaptivePortalCheck)
+}
+
+// Reasons of the captive portal check, in the order they are reported when
+// several apply.
+const (
+	reasonPortalRedirect = "captive_portal_redirect"
+	reasonPortalInjected = "captive_portal_injected"
+	reasonPortalTLS      = "captive_portal_tls"
+)
+
+// portalBodyLimit bounds how much of an answer is read. Expected bodies are
+// short and a portal page is recognized by its first bytes.
+const portalBodyLimit = 64 << 10
+
+// portalReasonOrder is the order of the reasons, most specific first.
+var portalReasonOrder = []string{reasonPortalRedirect, reasonPortalInjected, reasonPortalTLS}
+
+// portalPagePatterns find where a portal page sends the browser: a meta
+// refresh or a script assigning the location.
+var portalPagePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)<meta[^>]+http-equiv=["']?refresh["']?[^>]+content=["'][^"']*url=([^"'>]+)`),
+	regexp.MustCompile(`(?i)(?:window\.|document\.)?location(?:\.href)?\s*=\s*["']([^"']+)["']`),
+	regexp.MustCompile(`(?i)location\.replace\(\s*["']([^"']+)["']`),
+}
+
+// validatePortalEndpoint checks that an endpoint is a plain-HTTP URL with
+// an expected status. Over HTTPS a portal cannot answer in place of the
+// endpoint without breaking TLS, which the TLS probe detects.
+func validatePortalEndpoint(ep PortalEndpoint) error {
+	if u, err := url.Parse(ep.URL); err != nil || u.Scheme != "http" || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http URL, got %q", ep.URL)
+	}
+	if ep.Status < 100 || ep.Status > 599 {
+		return fmt.Errorf("status must be an HTTP status code, got %d", ep.Status)
+	}
+	return nil
+}
+
+// validatePortalTLSURL checks the URL of the TLS probe; empty disables it.
+func validatePortalTLSURL(tlsURL string) error {
+	if tlsURL == "" {
+		return nil
+	}
+	if u, err := url.Parse(tlsURL); err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("must be an absolute https URL, got %q", tlsURL)
+	}
+	return nil
+}
+
+// portalDetector fetches connectivity-check endpoints and compares their
+// answers with what is expected. The watchdog check and the outage
+// classifier share it.
+type portalDetector struct {
+	endpoints []PortalEndpoint
+	tlsURL    string
+	client    *http.Client
+}
+
+func newPortalDetector(endpoints []PortalEndpoint, tlsURL string, timeout time.Duration, uplink *UplinkConfig) *portalDetector {
+	return &portalDetector{
+		endpoints: endpoints,
+		tlsURL:    tlsURL,
+		client:    newWatchdogHTTPClient(timeout, uplink),
+	}
+}
+
+// portalProbe is the outcome of fetching one endpoint or the TLS URL.
+// Reason is set when the answer shows a portal.
+type portalProbe struct {
+	url       string
+	status    int
+	latency   time.Duration
+	reason    string
+	portalURL string
+	detail    string // what shows the portal, for messages
+	err       error
+}
+
+func (p portalProbe) details() map[string]any {
+	d := map[string]any{"url": p.url}
+	if p.err != nil {
+		d["error"] = p.err.Error()
+		return d
+	}
+	if p.status != 0 {
+		d["http_status"] = p.status
+	}
+	d["latency_ms"] = durationMillis(p.latency)
+	if p.reason != "" {
+		d["reason"] = p.reason
+	}
+	if p.portalURL != "" {
+		d["portal_url"] = p.portalURL
+	}
+	return d
+}
+
+// detect fetches every endpoint and the TLS URL concurrently. The TLS probe
+// comes last.
+func (d *portalDetector) detect(ctx context.Context) []portalProbe {
+	probes := make([]portalProbe, len(d.endpoints), len(d.endpoints)+1)
+	if d.tlsURL != "" {
+		probes = append(probes, portalProbe{})
+	}
+
+	var wg sync.WaitGroup
+	for i := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i < len(d.endpoints) {
+				probes[i] = d.probeEndpoint(ctx, d.endpoints[i])
+			} else {
+				probes[i] = d.probeTLS(ctx)
+			}
+		}()
+	}
+	wg.Wait()
+	return probes
+}
+
+// portalFound returns the probe that shows a portal most specifically.
+func portalFound(probes []portalProbe) (portalProbe, bool) {
+	found := -1
+	for i, p := range probes {
+		if p.reason == "" {
+			continue
+		}
+		if found < 0 || slices.Index(portalReasonOrder, p.reason) < slices.Index(portalReasonOrder, probes[found].reason) {
+			found = i
+		}
+	}
+	if found < 0 {
+		return portalProbe{}, false
+	}
+	return probes[found], true
+}
+
+// probeEndpoint fetches an endpoint. An answer that is neither the expected
+// one nor an error is a portal: a redirect leads to its login page, any
+// other answer is its page injected in place of the endpoint's.
+func (d *portalDetector) probeEndpoint(ctx context.Context, ep PortalEndpoint) portalProbe {
+	p := portalProbe{url: ep.URL}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ep.URL, nil)
+	if err != nil {
+		p.err = err
+		return p
+	}
+	// Keep caching proxies from answering with a stale copy
+	req.Header.Set("Cache-Control", "no-cache")
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	if err != nil {
+		p.latency, p.err = time.Since(start), shortNetError(unwrapURLError(err))
+		return p
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, portalBodyLimit))
+	p.latency, p.status = time.Since(start), resp.StatusCode
+	if err != nil {
+		p.err = shortNetError(err)
+		return p
+	}
+
+	bodyMatches := ep.Body == "" || strings.TrimSpace(string(body)) == strings.TrimSpace(ep.Body)
+	switch {
+	case resp.StatusCode == ep.Status && bodyMatches:
+	case resp.StatusCode >= 300 && resp.StatusCode < 400 && resp.Header.Get("Location") != "":
+		p.reason = reasonPortalRedirect
+		p.portalURL = resolveReference(req.URL, resp.Header.Get("Location"))
+		p.detail = fmt.Sprintf("%s redirected to %s", ep.URL, p.portalURL)
+	default:
+		p.reason = reasonPortalInjected
+		p.portalURL = portalPageURL(req.URL, body)
+		p.detail = fmt.Sprintf("%s answered %d with a page of %d bytes instead of %d", ep.URL, resp.StatusCode, len(body), ep.Status)
+		if resp.StatusCode == ep.Status {
+			p.detail = fmt.Sprintf("%s answered %d with an unexpected body of %d bytes", ep.URL, resp.StatusCode, len(body))
+		}
+		if p.portalURL != "" {
+			p.detail += " leading to " + p.portalURL
+		}
+	}
+	return p
+}
+
+// probeTLS fetches the TLS URL. A certificate that does not verify means a
+// middlebox answers in place of the server, typically a portal that
+// intercepts HTTPS to show its login page; its certificate often names the
+// portal.
+func (d *portalDetector) probeTLS(ctx context.Context) portalProbe {
+	p := portalProbe{url: d.tlsURL}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, d.tlsURL, nil)
+	if err != nil {
+		p.err = err
+		return p
+	}
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	p.latency = time.Since(start)
+	if err == nil {
+		_ = resp.Body.Close()
+		p.status = resp.StatusCode
+		return p
+	}
+
+	var certErr *tls.CertificateVerificationError
+	if !errors.As(err, &certErr) || len(certErr.UnverifiedCertificates) == 0 {
+		p.err = shortNetError(unwrapURLError(err))
+		return p
+	}
+	cert := certErr.UnverifiedCertificates[0]
+	p.reason = reasonPortalTLS
+	desc := "certificate"
+	if name := cert.Subject.CommonName; name != "" || len(cert.DNSNames) > 0 {
+		if name == "" {
+			name = cert.DNSNames[0]
+		}
+		desc += fmt.Sprintf(" for %q", name)
+	}
+	if issuer := cert.Issuer.CommonName; issuer != "" {
+		desc += fmt.Sprintf(" issued by %q", issuer)
+	}
+	p.detail = fmt.Sprintf("TLS to %s intercepted: %s (%v)", req.URL.Host, desc, certErr.Err)
+	switch {
+	case len(cert.DNSNames) > 0 && !strings.HasPrefix(cert.DNSNames[0], "*"):
+		p.portalURL = "https://" + cert.DNSNames[0] + "/"
+	case cert.Subject.CommonName != "" && !strings.ContainsAny(cert.Subject.CommonName, " *"):
+		p.portalURL = "https://" + cert.Subject.CommonName + "/"
+	}
+	return p
+}
+
+// portalPageURL returns where a portal page sends the browser, or "" when
+// the page does not say.
+func portalPageURL(base *url.URL, body []byte) string {
+	for _, pattern := range portalPagePatterns {
+		if m := pattern.FindSubmatch(body); m != nil {
+			return resolveReference(base, html.UnescapeString(strings.TrimSpace(string(m[1]))))
+		}
+	}
+	return ""
+}
+
+// resolveReference resolves ref, e.g. a Location header, against base.
+func resolveReference(base *url.URL, ref string) string {
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+// captivePortalCheck detects captive portals that redirect connectivity
+// checks, answer them with their own page, or intercept TLS.
+type captivePortalCheck struct {
+	name     string
+	detector *portalDetector
+}
+
+func newCaptivePortalCheck(cfg CheckConfig, env CheckEnv) (Check, error) {
+	p := struct {
+		Endpoints []PortalEndpoint `yaml:"endpoints"`
+		TLSURL    string           `yaml:"tls_url"`
+	}{
+		Endpoints: env.Watchdog.PortalEndpoints,
+		TLSURL:    env.Watchdog.PortalTLSURL,
+	}
+	if err := cfg.DecodeParams(&p); err != nil {
+		return nil, err
+	}
+	for i, ep := range p.Endpoints {
+		if err := validatePortalEndpoint(ep); err != nil {
+			return nil, fmt.Errorf("endpoints[%d]: %w", i, err)
+		}
+	}
+	if err := validatePortalTLSURL(p.TLSURL); err != nil {
+		return nil, fmt.Errorf("tls_url: %w", err)
+	}
+	if len(p.Endpoints) == 0 && p.TLSURL == "" {
+		return nil, errors.New("endpoints must list at least one endpoint when tls_url is empty")
+	}
+
+	return &captivePortalCheck{
+		name:     cfg.Name,
+		detector: newPortalDetector(p.Endpoints, p.TLSURL, cfg.Timeout, env.Uplink),
+	}, nil
+}
+
+func (c *captivePortalCheck) Name() string { return c.name }
+
+func (c *captivePortalCheck) Run(ctx context.Context) Result {
+	start := time.Now()
+	probes := c.detector.detect(ctx)
+	duration := time.Since(start)
+
+	details := make([]map[string]any, len(probes))
+	var answered int
+	for i, p := range probes {
+		details[i] = p.details()
+		if p.err == nil {
+			answered++
+		}
+	}
+
+	r := Result{
+		Status:  StatusOK,
+		Latency: duration,
+		Details: map[string]any{"probes": details},
+	}
+	if p, ok := portalFound(probes); ok {
+		r.Severity = SeverityWarn.Override()
+		r.Status, r.Reason = StatusFail, p.reason
+		r.Target = p.url
+		if p.portalURL != "" {
+			r.Details["portal_url"] = p.portalURL
+		}
+		r.Message = fmt.Sprintf("⚠ CAPTIVE PORTAL detected: %s", p.detail)
+		return r
+	}
+	if answered == 0 {
+		// Without answers there is no sign of a portal; the http check
+		// reports the outage
+		r.Severity = SeverityWarn.Override()
+		r.Message = fmt.Sprintf("⚠ Captive portal check: no endpoint answered (took %v)", duration)
+		return r
+	}
+	r.Message = fmt.Sprintf("✓ No captive portal: %d of %d endpoints answered as expected (took %v)",
+		answered, len(probes), duration)
+	return r
+}
@@ -0,0 +1,230 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestPortalDetector(endpoints []PortalEndpoint, tlsURL string) *portalDetector {
+	return newPortalDetector(endpoints, tlsURL, 5*time.Second, nil)
+}
+
+func TestProbeEndpoint(t *testing.T) {
+	const appleBody = "<HTML><HEAD><TITLE>Success</TITLE></HEAD><BODY>Success</BODY></HTML>"
+	for _, tc := range []struct {
+		name       string
+		status     int    // expected by the endpoint
+		body       string // expected by the endpoint
+		handler    http.HandlerFunc
+		wantReason string
+		wantPortal string // relative to the server URL when it starts with /
+		wantDetail string
+	}{
+		{
+			name: "expected status", status: http.StatusNoContent,
+			handler: func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) },
+		},
+		{
+			name: "expected body", status: http.StatusOK, body: appleBody,
+			handler: func(w http.ResponseWriter, _ *http.Request) { _, _ = io.WriteString(w, appleBody+"\n") },
+		},
+		{
+			name: "redirect", status: http.StatusNoContent,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "/login?continue=1", http.StatusFound)
+			},
+			wantReason: reasonPortalRedirect, wantPortal: "/login?continue=1", wantDetail: "redirected to",
+		},
+		{
+			name: "redirect elsewhere", status: http.StatusNoContent,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "http://portal.example/splash", http.StatusTemporaryRedirect)
+			},
+			wantReason: reasonPortalRedirect, wantPortal: "http://portal.example/splash", wantDetail: "redirected to",
+		},
+		{
+			name: "injected page with meta refresh", status: http.StatusNoContent,
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = io.WriteString(w, `<html><head><meta http-equiv="refresh" content="0; url=http://portal.example/login?a=1&amp;b=2"></head></html>`)
+			},
+			wantReason: reasonPortalInjected, wantPortal: "http://portal.example/login?a=1&b=2",
+			wantDetail: "answered 200 with a page of",
+		},
+		{
+			name: "injected page with script", status: http.StatusNoContent,
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = io.WriteString(w, `<script>window.location.href = "/portal";</script>`)
+			},
+			wantReason: reasonPortalInjected, wantPortal: "/portal", wantDetail: "instead of 204",
+		},
+		{
+			name: "unexpected body", status: http.StatusOK, body: appleBody,
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = io.WriteString(w, "<html>Welcome to the hotel network</html>")
+			},
+			wantReason: reasonPortalInjected, wantDetail: "answered 200 with an unexpected body",
+		},
+		{
+			name: "redirect without location", status: http.StatusNoContent,
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusFound)
+			},
+			wantReason: reasonPortalInjected, wantDetail: "answered 302",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(tc.handler)
+			defer srv.Close()
+
+			ep := PortalEndpoint{URL: srv.URL + "/generate_204", Status: tc.status, Body: tc.body}
+			p := newTestPortalDetector(nil, "").probeEndpoint(context.Background(), ep)
+			if p.err != nil {
+				t.Fatal(p.err)
+			}
+			wantPortal := tc.wantPortal
+			if strings.HasPrefix(wantPortal, "/") {
+				wantPortal = srv.URL + wantPortal
+			}
+			if p.reason != tc.wantReason || p.portalURL != wantPortal || !strings.Contains(p.detail, tc.wantDetail) {
+				t.Errorf("probeEndpoint() = reason %q, portal %q, detail %q; want %q, %q, detail containing %q",
+					p.reason, p.portalURL, p.detail, tc.wantReason, wantPortal, tc.wantDetail)
+			}
+		})
+	}
+}
+
+func TestProbeEndpointUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	p := newTestPortalDetector(nil, "").probeEndpoint(context.Background(),
+		PortalEndpoint{URL: "http://" + addr + "/generate_204", Status: http.StatusNoContent})
+	if p.err == nil || p.reason != "" {
+		t.Fatalf("probeEndpoint() = reason %q, error %v, want an error and no portal", p.reason, p.err)
+	}
+}
+
+// newPortalTLSServer starts an HTTPS server with a certificate named
+// commonName and returns it with the detector config that trusts it.
+func newPortalTLSServer(t *testing.T, commonName string) (*httptest.Server, *tls.Config) {
+	t.Helper()
+	cert, pool := selfSignedCert(t, commonName)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0) // failed handshakes
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv, &tls.Config{RootCAs: pool}
+}
+
+func TestProbeTLS(t *testing.T) {
+	t.Run("intercepted", func(t *testing.T) {
+		srv, _ := newPortalTLSServer(t, "portal.example")
+		p := newTestPortalDetector(nil, srv.URL).probeTLS(context.Background())
+		if p.err != nil {
+			t.Fatal(p.err)
+		}
+		if p.reason != reasonPortalTLS || p.portalURL != "https://portal.example/" ||
+			!strings.Contains(p.detail, `certificate for "portal.example"`) {
+			t.Errorf("probeTLS() = reason %q, portal %q, detail %q; want %s naming portal.example",
+				p.reason, p.portalURL, p.detail, reasonPortalTLS)
+		}
+	})
+
+	t.Run("trusted", func(t *testing.T) {
+		srv, trust := newPortalTLSServer(t, "www.example.com")
+		d := newTestPortalDetector(nil, srv.URL)
+		d.client.Transport.(*http.Transport).TLSClientConfig = trust
+		p := d.probeTLS(context.Background())
+		if p.err != nil || p.reason != "" || p.status != http.StatusOK {
+			t.Errorf("probeTLS() = reason %q, status %d, error %v; want 200 and no portal", p.reason, p.status, p.err)
+		}
+	})
+}
+
+func TestPortalFound(t *testing.T) {
+	probes := []portalProbe{
+		{url: "a"},
+		{url: "b", reason: reasonPortalTLS},
+		{url: "c", reason: reasonPortalInjected},
+		{url: "d", reason: reasonPortalRedirect},
+		{url: "e", reason: reasonPortalInjected},
+	}
+	if p, ok := portalFound(probes); !ok || p.url != "d" {
+		t.Errorf("portalFound() = %q, %v, want the redirect", p.url, ok)
+	}
+	if p, ok := portalFound(probes[:3]); !ok || p.url != "c" {
+		t.Errorf("portalFound() = %q, %v, want the injected page", p.url, ok)
+	}
+	if _, ok := portalFound(probes[:1]); ok {
+		t.Error("portalFound() found a portal without reasons")
+	}
+}
+
+func TestCaptivePortalCheck(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ok.Close()
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://portal.example/login", http.StatusFound)
+	}))
+	defer redirect.Close()
+	intercepted, _ := newPortalTLSServer(t, "portal.example")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := "http://" + ln.Addr().String() + "/"
+	_ = ln.Close()
+
+	endpoint := func(url string) PortalEndpoint {
+		return PortalEndpoint{URL: url, Status: http.StatusNoContent}
+	}
+	for _, tc := range []struct {
+		name         string
+		endpoints    []PortalEndpoint
+		tlsURL       string
+		wantStatus   Status
+		wantReason   string
+		wantSeverity Severity
+		wantProbes   int
+	}{
+		{name: "no portal", endpoints: []PortalEndpoint{endpoint(ok.URL), endpoint(closed)},
+			wantStatus: StatusOK, wantSeverity: SeverityInfo, wantProbes: 2},
+		{name: "redirect", endpoints: []PortalEndpoint{endpoint(ok.URL), endpoint(redirect.URL)}, tlsURL: intercepted.URL,
+			wantStatus: StatusFail, wantReason: reasonPortalRedirect, wantSeverity: SeverityWarn, wantProbes: 3},
+		{name: "tls interception", endpoints: []PortalEndpoint{endpoint(ok.URL)}, tlsURL: intercepted.URL,
+			wantStatus: StatusFail, wantReason: reasonPortalTLS, wantSeverity: SeverityWarn, wantProbes: 2},
+		{name: "nothing answers", endpoints: []PortalEndpoint{endpoint(closed)},
+			wantStatus: StatusOK, wantSeverity: SeverityWarn, wantProbes: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &captivePortalCheck{name: "captive_portal", detector: newTestPortalDetector(tc.endpoints, tc.tlsURL)}
+			r := c.Run(context.Background())
+			if r.Status != tc.wantStatus || r.Reason != tc.wantReason {
+				t.Fatalf("Run() = %s %q (%s), want %s %q", r.Status, r.Reason, r.Message, tc.wantStatus, tc.wantReason)
+			}
+			if got := r.severity(); got != tc.wantSeverity {
+				t.Errorf("severity() = %v, want %v", got, tc.wantSeverity)
+			}
+			if probes, _ := r.Details["probes"].([]map[string]any); len(probes) != tc.wantProbes {
+				t.Errorf("got %d probe details, want %d", len(probes), tc.wantProbes)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultCheckTimeout bounds a configured check that does not set a timeout.
+const defaultCheckTimeout = 5 * time.Second
+
+// paramUnknownFieldPattern matches the errors of KnownFields decoding into
+// the anonymous parameter structs of check types.
+var paramUnknownFieldPattern = regexp.MustCompile(`^field (\S+) not found in type`)
+
+// Check is one probe run periodically by the watchdog. Run must return by
+// the deadline of ctx, which is the timeout of the check.
+type Check interface {
+	Name() string
+	Run(ctx context.Context) Result
+}
+
+// Result is the outcome of one run of a Check. A Result without a Status
+// means the check could not be run at all; it is logged but reports nothing
+// about connectivity.
+type Result struct {
+	Status  Status
+	Latency time.Duration
+	// Reason is a short machine-readable code such as "http_failed" when
+	// Status is not ok.
+	Reason  string
+	Target  string
+	Address string
+	Gateway string
+	Message string
+	Err     error
+	// Severity, when set, replaces the severity derived from Status, e.g.
+	// SeverityWarn.Override().
+	Severity *Severity
+	// Details carries check-specific values, logged as event fields.
+	Details map[string]any
+}
+
+// Override returns s for Result.Severity.
+func (s Severity) Override() *Severity { return &s }
+
+// severity returns the severity to log the result with.
+func (r Result) severity() Severity {
+	if r.Severity != nil {
+		return *r.Severity
+	}
+	switch r.Status {
+	case StatusOK:
+		return SeverityInfo
+	case StatusWarn:
+		return SeverityWarn
+	default:
+		return SeverityError
+	}
+}
+
+// CheckConfig configures one watchdog check. An entry named after a
+// built-in check (route, route6, dns, nameservers, http, captive_portal,
+// http6) without a type changes
+// the interval or timeout of that check or disables it; any other entry adds
+// a check of a registered type. Params holds the keys specific to the type.
+type CheckConfig struct {
+	Name     string         `yaml:"name"`
+	Type     string         `yaml:"type"`
+	Interval time.Duration  `yaml:"interval"`
+	Timeout  time.Duration  `yaml:"timeout"`
+	Disabled bool           `yaml:"disabled"`
+	Params   map[string]any `yaml:",inline"`
+}
+
+// DecodeParams decodes the type-specific keys of the check into v, a
+// pointer to a struct with yaml tags. Keys v does not declare are an error.
+func (c CheckConfig) DecodeParams(v any) error {
+	if len(c.Params) == 0 {
+		return nil
+	}
+	data, err := yaml.Marshal(c.Params)
+	if err != nil {
+		return fmt.Errorf("failed to encode parameters: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		// Line numbers refer to the re-encoded parameters, not the file
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return err
+		}
+		msgs := make([]string, 0, len(typeErr.Errors))
+		for _, msg := range typeErr.Errors {
+			if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+				msg = m[2]
+			}
+			if m := paramUnknownFieldPattern.FindStringSubmatch(msg); m != nil {
+				msg = "unknown key " + m[1]
+			}
+			msgs = append(msgs, msg)
+		}
+		return errors.New(strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// CheckEnv is what a check is built for. Uplink is set for the watchdog of
+// an uplink, whose checks must pin their connections to it. ResolvConf is
+// system.resolv_conf, where checks read the system's nameservers from.
+type CheckEnv struct {
+	Watchdog   WatchdogConfig
+	Uplink     *UplinkConfig
+	ResolvConf string
+}
+
+// resolvConf returns the path of the system's resolv.conf.
+func (e CheckEnv) resolvConf() string {
+	if e.ResolvConf == "" {
+		return defaultResolvConf
+	}
+	return e.ResolvConf
+}
+
+// CheckFactory builds a check of one type from its config, or returns an
+// error describing what is wrong with the config.
+type CheckFactory func(cfg CheckConfig, env CheckEnv) (Check, error)
+
+var (
+	checkTypesMu sync.RWMutex
+	checkTypes   = make(map[string]CheckFactory)
+)
+
+// RegisterCheck makes a check type available to the watchdog.checks config.
+// It panics if the type is registered twice.
+func RegisterCheck(typ string, factory CheckFactory) {
+	checkTypesMu.Lock()
+	defer checkTypesMu.Unlock()
+
+	if _, dup := checkTypes[typ]; dup {
+		panic(fmt.Sprintf("monitor: check type %q registered twice", typ))
+	}
+	checkTypes[typ] = factory
+}
+
+// CheckTypes returns the registered check types, sorted.
+func CheckTypes() []string {
+	checkTypesMu.RLock()
+	defer checkTypesMu.RUnlock()
+
+	types := make([]string, 0, len(checkTypes))
+	for typ := range checkTypes {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// NewCheck builds a check of a registered type.
+func NewCheck(cfg CheckConfig, env CheckEnv) (Check, error) {
+	checkTypesMu.RLock()
+	factory, ok := checkTypes[cfg.Type]
+	checkTypesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown check type %q (known: %s)", cfg.Type, strings.Join(CheckTypes(), ", "))
+	}
+	return factory(cfg, env)
+}
+
+// builtinCheck is a check the watchdog runs without being configured. Its
+// results are reported as signal, so they count towards the state.
+type builtinCheck struct {
+	cfg    CheckConfig
+	signal Signal
+}
+
+// builtinChecks returns the checks the watchdog runs by default, configured
+// from the watchdog settings. Uplinks do not check the default route and the
+// nameservers, which are shared by all of them, nor IPv6. Windows has no
+// resolv.conf to read the nameservers from.
+func builtinChecks(cfg WatchdogConfig, uplink bool) []builtinCheck {
+	checks := []builtinCheck{
+		{CheckConfig{Name: "route", Type: "route", Timeout: 2 * time.Second}, SignalRoute},
+		{CheckConfig{Name: "dns", Type: "dns", Timeout: cfg.DNSTimeout}, SignalDNS},
+		{CheckConfig{Name: "nameservers", Type: "nameservers", Timeout: cfg.DNSTimeout}, SignalNameservers},
+		{CheckConfig{Name: "http", Type: "http", Timeout: cfg.HTTPTimeout}, SignalHTTP},
+		{CheckConfig{Name: "captive_portal", Type: "captive_portal", Timeout: cfg.HTTPTimeout}, SignalCaptivePortal},
+		{CheckConfig{Name: "route6", Type: "route", Timeout: 2 * time.Second, Params: map[string]any{"family": 6}}, SignalRoute6},
+		{CheckConfig{Name: "http6", Type: "http", Timeout: cfg.HTTPTimeout, Params: map[string]any{"family": 6}}, SignalHTTP6},
+	}
+
+	enabled := checks[:0]
+	for _, c := range checks {
+		v6 := c.signal == SignalRoute6 || c.signal == SignalHTTP6
+		shared := c.signal == SignalRoute || c.signal == SignalNameservers
+		if uplink && (shared || v6) || v6 && !cfg.IPv6 || c.signal == SignalNameservers && runtime.GOOS == "windows" {
+			continue
+		}
+		enabled = append(enabled, c)
+	}
+	return enabled
+}
+
+// isBuiltinCheck reports whether name is the name of a built-in check.
+func isBuiltinCheck(name string) bool {
+	switch name {
+	case "route", "route6", "dns", "nameservers", "http", "captive_portal", "http6":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package monitor
+
+import "testing"
+
+func TestResultSeverity(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		result Result
+		want   Severity
+	}{
+		{"ok", Result{Status: StatusOK}, SeverityInfo},
+		{"warn", Result{Status: StatusWarn}, SeverityWarn},
+		{"fail", Result{Status: StatusFail}, SeverityError},
+		{"not run", Result{}, SeverityError},
+		{"override", Result{Status: StatusFail, Severity: SeverityWarn.Override()}, SeverityWarn},
+		{"override without status", Result{Severity: SeverityWarn.Override()}, SeverityWarn},
+		{"override to info", Result{Status: StatusFail, Severity: SeverityInfo.Override()}, SeverityInfo},
+		{"override to debug", Result{Status: StatusOK, Severity: SeverityDebug.Override()}, SeverityDebug},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.result.severity(); got != tc.want {
+				t.Errorf("severity() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
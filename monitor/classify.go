@@ -0,0 +1,226 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// OutageCause is the root cause assigned to an outage by the classifier.
+type OutageCause string
+
+// Outage causes, in the order the classifier tests for them.
+const (
+	CauseLocalLink     OutageCause = "local_link_down"
+	CauseNoRoute       OutageCause = "no_default_route"
+	CauseGateway       OutageCause = "gateway_unreachable"
+	CauseUpstream      OutageCause = "upstream_unreachable"
+	CauseDNS           OutageCause = "dns_failure"
+	CauseCaptivePortal OutageCause = "captive_portal"
+	CauseHTTP          OutageCause = "http_failure"
+	CauseIPv6          OutageCause = "ipv6_unreachable"
+	CauseUnknown       OutageCause = "unknown"
+)
+
+// Diagnosis is the result of classifying an outage.
+type Diagnosis struct {
+	Cause   OutageCause
+	Detail  string
+	Gateway string
+}
+
+// OutageClassifier walks the path from the local link to the application
+// layer with targeted probes and reports the first layer that fails.
+type OutageClassifier struct {
+	cfg         ClassifierConfig
+	watchdog    WatchdogConfig
+	ipv6Targets []string
+	httpClient  *http.Client
+	portal      *portalDetector
+}
+
+// NewOutageClassifier constructs a classifier probing the targets in cfg;
+// the DNS, captive portal and HTTP probes reuse the watchdog's.
+func NewOutageClassifier(cfg *Config) *OutageClassifier {
+	w := cfg.Watchdog
+	return &OutageClassifier{
+		cfg:         cfg.Classifier,
+		watchdog:    w,
+		ipv6Targets: cfg.TCP.Targets6,
+		httpClient:  newWatchdogHTTPClient(w.HTTPTimeout, nil),
+		portal:      newPortalDetector(w.PortalEndpoints, w.PortalTLSURL, w.HTTPTimeout, nil),
+	}
+}
+
+// Classify probes, in order, the local link, the default route, the gateway,
+// the upstream Internet, DNS, captive portals, HTTP and, where the host has
+// an IPv6 default route, the IPv6 TCP targets, and returns the first failing
+// layer.
+// CauseUnknown means every probe passed, e.g. because the outage already
+// ended, or the interfaces could not be listed.
+func (c *OutageClassifier) Classify(ctx context.Context) Diagnosis {
+	up, err := upInterfaces()
+	if err != nil {
+		return Diagnosis{Cause: CauseUnknown, Detail: fmt.Sprintf("failed to list network interfaces: %v", err)}
+	}
+	if len(up) == 0 {
+		return Diagnosis{Cause: CauseLocalLink, Detail: "no network interface is up with carrier"}
+	}
+
+	found, gw, err := probeDefaultRoute(ctx, false, c.watchdog.RouteProbe)
+	if err != nil {
+		return Diagnosis{Cause: CauseNoRoute, Detail: fmt.Sprintf("failed to read routing table: %v", err)}
+	}
+	if !found {
+		return Diagnosis{Cause: CauseNoRoute, Detail: "routing table has no default route"}
+	}
+
+	d := Diagnosis{}
+	if gw != nil {
+		d.Gateway = gw.String()
+		if !c.gatewayReachable(ctx, d.Gateway) {
+			d.Cause = CauseGateway
+			d.Detail = fmt.Sprintf("gateway %s did not answer on ports %s",
+				d.Gateway, joinPorts(c.cfg.GatewayPorts))
+			return d
+		}
+	}
+
+	if !c.anyReachable(ctx, c.cfg.UpstreamTargets) {
+		d.Cause = CauseUpstream
+		d.Detail = fmt.Sprintf("gateway answers but %s are unreachable", strings.Join(c.cfg.UpstreamTargets, ", "))
+		return d
+	}
+
+	if err := c.resolve(ctx); err != nil {
+		d.Cause = CauseDNS
+		d.Detail = fmt.Sprintf("upstream reachable but resolving %s failed: %v", c.watchdog.DNSDomain, err)
+		return d
+	}
+
+	return c.classifyHTTP(ctx, d)
+}
+
+// classifyHTTP looks for a captive portal before probing HTTP, since a
+// portal that intercepts TLS also makes the HTTP probe fail.
+func (c *OutageClassifier) classifyHTTP(ctx context.Context, d Diagnosis) Diagnosis {
+	if p, ok := portalFound(c.portal.detect(ctx)); ok {
+		d.Cause = CauseCaptivePortal
+		d.Detail = p.detail
+		return d
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.watchdog.HTTPURL, nil)
+	if err != nil {
+		d.Cause = CauseUnknown
+		d.Detail = fmt.Sprintf("failed to build HTTP probe: %v", err)
+		return d
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		d.Cause = CauseHTTP
+		d.Detail = fmt.Sprintf("DNS works but HTTP to %s failed: %v", c.watchdog.HTTPURL, err)
+		return d
+	}
+	_ = resp.Body.Close()
+
+	if found, _, err := probeDefaultRoute(ctx, true, c.watchdog.RouteProbe6); err == nil && found && len(c.ipv6Targets) > 0 &&
+		!c.anyReachable(ctx, c.ipv6Targets) {
+		d.Cause = CauseIPv6
+		d.Detail = fmt.Sprintf("IPv4 works but %s are unreachable over IPv6", strings.Join(c.ipv6Targets, ", "))
+		return d
+	}
+
+	d.Cause = CauseUnknown
+	d.Detail = "all probes passed"
+	return d
+}
+
+// gatewayReachable reports whether the gateway accepts or actively refuses
+// a TCP connection on any probe port; either proves it is on the wire.
+func (c *OutageClassifier) gatewayReachable(ctx context.Context, gateway string) bool {
+	targets := make([]string, 0, len(c.cfg.GatewayPorts))
+	for _, port := range c.cfg.GatewayPorts {
+		targets = append(targets, net.JoinHostPort(gateway, strconv.Itoa(port)))
+	}
+	return c.probeAny(ctx, targets, func(err error) bool {
+		return err == nil || errors.Is(err, syscall.ECONNREFUSED)
+	})
+}
+
+// anyReachable reports whether a TCP connection to any target succeeds.
+func (c *OutageClassifier) anyReachable(ctx context.Context, targets []string) bool {
+	return c.probeAny(ctx, targets, func(err error) bool { return err == nil })
+}
+
+// probeAny dials all targets concurrently and reports whether any dial
+// result satisfies ok.
+func (c *OutageClassifier) probeAny(ctx context.Context, targets []string, ok func(error) bool) bool {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.ProbeTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	results := make(chan bool, len(targets))
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target string) {
+			defer wg.Done()
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, "tcp", target)
+			if conn != nil {
+				_ = conn.Close()
+			}
+			results <- ok(err)
+		}(target)
+	}
+	wg.Wait()
+	close(results)
+
+	for reachable := range results {
+		if reachable {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *OutageClassifier) resolve(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.watchdog.DNSTimeout)
+	defer cancel()
+
+	resolver := &net.Resolver{PreferGo: true}
+	_, err := resolver.LookupHost(ctx, c.watchdog.DNSDomain)
+	return err
+}
+
+func joinPorts(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, port := range ports {
+		parts[i] = strconv.Itoa(port)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// upInterfaces returns the names of non-loopback interfaces that are up
+// and have carrier.
+func upInterfaces() ([]string, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var up []string
+	for _, iface := range interfaces {
+		if linkStatus(iface.Flags) == StatusOK {
+			up = append(up, iface.Name)
+		}
+	}
+	return up, nil
+}
@@ -0,0 +1,608 @@
+package monitor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the targets, intervals and timeouts of every monitor. Keys
+// missing from a config file keep the defaults from DefaultConfig.
+type Config struct {
+	System     SystemConfig     `yaml:"system"`
+	TCP        TCPConfig        `yaml:"tcp"`
+	Watchdog   WatchdogConfig   `yaml:"watchdog"`
+	Classifier ClassifierConfig `yaml:"classifier"`
+	Uplinks    []UplinkConfig   `yaml:"uplinks"`
+
+	// Path is the file the config was loaded from; empty for the defaults.
+	Path string `yaml:"-"`
+}
+
+// SystemConfig configures the system events monitor.
+type SystemConfig struct {
+	// ResolvConf is polled for DNS configuration changes (Linux and macOS).
+	ResolvConf      string        `yaml:"resolv_conf"`
+	DNSPollInterval time.Duration `yaml:"dns_poll_interval"`
+	// Polling intervals of the Windows monitor, which has no change notifications.
+	InterfacePollInterval time.Duration `yaml:"interface_poll_interval"`
+	RoutePollInterval     time.Duration `yaml:"route_poll_interval"`
+	AddressPollInterval   time.Duration `yaml:"address_poll_interval"`
+}
+
+// TCPConfig configures the persistent TCP keepalive connections.
+type TCPConfig struct {
+	// Targets should be run by different providers so a single blocked or
+	// failing host is not mistaken for an outage.
+	Targets []string `yaml:"targets"`
+	// Quorum is how many targets must be down for the Internet to be
+	// considered down; 0 means a majority of the targets.
+	Quorum int `yaml:"quorum"`
+	// Targets are reached over IPv4 and Targets6 over IPv6, each set with
+	// its own quorum, so either family can fail on its own. An empty
+	// Targets6 disables the IPv6 connections.
+	Targets6 []string `yaml:"targets6"`
+	Quorum6  int      `yaml:"quorum6"`
+	// Mode is TCPModeKeepalive, which only watches for the connection to
+	// break, or TCPModeHeartbeat, which exchanges timestamped pings with
+	// targets running `network-monitor echo-server`.
+	Mode        string        `yaml:"mode"`
+	DialTimeout time.Duration `yaml:"dial_timeout"`
+	// An idle connection sends the first keepalive probe after KeepaliveIdle
+	// and is dropped after KeepaliveCount unanswered probes KeepaliveInterval
+	// apart. UserTimeout bounds how long sent data may stay unacknowledged
+	// (TCP_USER_TIMEOUT, Linux only); 0 leaves the kernel default of ~15m.
+	KeepaliveIdle     time.Duration `yaml:"keepalive_idle"`
+	KeepaliveInterval time.Duration `yaml:"keepalive_interval"`
+	KeepaliveCount    int           `yaml:"keepalive_count"`
+	UserTimeout       time.Duration `yaml:"user_timeout"`
+	// CheckInterval and ReadTimeout apply in keepalive mode.
+	CheckInterval time.Duration `yaml:"check_interval"`
+	ReadTimeout   time.Duration `yaml:"read_timeout"`
+	// HeartbeatInterval and HeartbeatTimeout apply in heartbeat mode; a
+	// heartbeat not echoed within the timeout marks the target down.
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+	HeartbeatTimeout  time.Duration `yaml:"heartbeat_timeout"`
+	// ReconnectDelay is the wait after the first failed attempt; it doubles
+	// with every further failure up to ReconnectMaxDelay.
+	ReconnectDelay    time.Duration `yaml:"reconnect_delay"`
+	ReconnectMaxDelay time.Duration `yaml:"reconnect_max_delay"`
+	// InfoInterval is how often each connection is sampled with TCP_INFO
+	// (Linux only); RetransmitWarn retransmits within one interval mark the
+	// connection degraded.
+	InfoInterval   time.Duration `yaml:"info_interval"`
+	RetransmitWarn int           `yaml:"retransmit_warn"`
+}
+
+// TCP monitor modes.
+const (
+	TCPModeKeepalive = "keepalive"
+	TCPModeHeartbeat = "heartbeat"
+)
+
+// WatchdogConfig configures the periodic route, DNS and HTTP checks.
+type WatchdogConfig struct {
+	// Interval is how often the checks run unless a check sets its own.
+	Interval time.Duration `yaml:"interval"`
+	// RouteProbe is the UDP address used to test routing where the routing
+	// table cannot be read directly (macOS, Windows), by the route check
+	// and the outage classifier.
+	RouteProbe string `yaml:"route_probe"`
+	// IPv6 also checks the IPv6 default route (RouteProbe6 where the
+	// routing table cannot be read) and HTTP over IPv6.
+	IPv6        bool          `yaml:"ipv6"`
+	RouteProbe6 string        `yaml:"route_probe6"`
+	DNSDomain   string        `yaml:"dns_domain"`
+	DNSTimeout  time.Duration `yaml:"dns_timeout"`
+	HTTPURL     string        `yaml:"http_url"`
+	HTTPTimeout time.Duration `yaml:"http_timeout"`
+	// PortalEndpoints are plain-HTTP URLs with a known answer that the
+	// captive portal check fetches; PortalTLSURL is fetched over HTTPS to
+	// catch portals that intercept TLS, unless it is empty.
+	PortalEndpoints []PortalEndpoint `yaml:"portal_endpoints"`
+	PortalTLSURL    string           `yaml:"portal_tls_url"`
+	// Checks adds checks of registered types and adjusts or disables the
+	// built-in ones.
+	Checks []CheckConfig `yaml:"checks"`
+}
+
+// PortalEndpoint is a connectivity-check URL, such as the ones operating
+// systems use, or a self-hosted one. A captive portal answers it with a
+// redirect or a page of its own instead of the expected answer.
+type PortalEndpoint struct {
+	URL    string `yaml:"url"`
+	Status int    `yaml:"status"`
+	// Body is the expected body, compared without surrounding white space.
+	// An empty body is not compared.
+	Body string `yaml:"body"`
+}
+
+// ClassifierConfig configures the outage root cause probes.
+type ClassifierConfig struct {
+	ProbeTimeout time.Duration `yaml:"probe_timeout"`
+	// GatewayPorts are tried on the gateway; home routers usually answer
+	// (or actively refuse) on at least one of them.
+	GatewayPorts []int `yaml:"gateway_ports"`
+	// UpstreamTargets should be IP literals so the probe does not depend on DNS.
+	UpstreamTargets []string `yaml:"upstream_targets"`
+}
+
+// UplinkConfig pins an extra set of TCP keepalive and watchdog probes to one
+// uplink of a multi-homed host, so a backup uplink is known to work before
+// it is needed.
+type UplinkConfig struct {
+	Name string `yaml:"name"`
+	// Interface binds the probes to a network interface (SO_BINDTODEVICE,
+	// Linux only).
+	Interface string `yaml:"interface"`
+	// Source binds the probes to a local IP address of the uplink.
+	Source string `yaml:"source"`
+}
+
+// String names the uplink and what its probes are bound to.
+func (u UplinkConfig) String() string {
+	var bound []string
+	if u.Interface != "" {
+		bound = append(bound, "dev "+u.Interface)
+	}
+	if u.Source != "" {
+		bound = append(bound, "src "+u.Source)
+	}
+	return fmt.Sprintf("%s (%s)", u.Name, strings.Join(bound, ", "))
+}
+
+// DefaultConfig returns the built-in configuration.
+func DefaultConfig() *Config {
+	return &Config{
+		System: SystemConfig{
+			ResolvConf:            "/etc/resolv.conf",
+			DNSPollInterval:       2 * time.Second,
+			InterfacePollInterval: 2 * time.Second,
+			RoutePollInterval:     5 * time.Second,
+			AddressPollInterval:   3 * time.Second,
+		},
+		TCP: TCPConfig{
+			Targets:           []string{"1.1.1.1:443", "8.8.8.8:443", "9.9.9.9:443"}, // Cloudflare, Google, Quad9
+			Targets6:          []string{"[2606:4700:4700::1111]:443", "[2001:4860:4860::8888]:443", "[2620:fe::fe]:443"},
+			Mode:              TCPModeKeepalive,
+			DialTimeout:       10 * time.Second,
+			KeepaliveIdle:     30 * time.Second,
+			KeepaliveInterval: 10 * time.Second,
+			KeepaliveCount:    3,
+			UserTimeout:       60 * time.Second,
+			CheckInterval:     30 * time.Second,
+			ReadTimeout:       60 * time.Second,
+			HeartbeatInterval: 5 * time.Second,
+			HeartbeatTimeout:  3 * time.Second,
+			ReconnectDelay:    5 * time.Second,
+			ReconnectMaxDelay: 2 * time.Minute,
+			InfoInterval:      30 * time.Second,
+			RetransmitWarn:    3,
+		},
+		Watchdog: WatchdogConfig{
+			Interval:    30 * time.Second,
+			RouteProbe:  "8.8.8.8:53",
+			IPv6:        true,
+			RouteProbe6: "[2001:4860:4860::8888]:53",
+			DNSDomain:   "www.google.com",
+			DNSTimeout:  5 * time.Second,
+			HTTPURL:     "https://www.google.com",
+			HTTPTimeout: 10 * time.Second,
+			PortalEndpoints: []PortalEndpoint{
+				{URL: "http://connectivitycheck.gstatic.com/generate_204", Status: 204},
+				{URL: "http://captive.apple.com/hotspot-detect.html", Status: 200,
+					Body: "<HTML><HEAD><TITLE>Success</TITLE></HEAD><BODY>Success</BODY></HTML>"},
+				{URL: "http://www.msftconnecttest.com/connecttest.txt", Status: 200, Body: "Microsoft Connect Test"},
+			},
+			PortalTLSURL: "https://www.google.com",
+		},
+		Classifier: ClassifierConfig{
+			ProbeTimeout:    2 * time.Second,
+			GatewayPorts:    []int{53, 80, 443},
+			UpstreamTargets: []string{"1.1.1.1:443", "8.8.8.8:443"},
+		},
+	}
+}
+
+// EffectiveQuorum returns the number of targets that must be down for the
+// Internet to be considered down.
+func (c TCPConfig) EffectiveQuorum() int {
+	if c.Quorum > 0 {
+		return c.Quorum
+	}
+	return len(c.Targets)/2 + 1
+}
+
+// IPv6 returns the config of the IPv6 connections: Targets6 and Quorum6 in
+// place of Targets and Quorum.
+func (c TCPConfig) IPv6() TCPConfig {
+	c.Targets, c.Quorum = c.Targets6, c.Quorum6
+	c.Targets6, c.Quorum6 = nil, 0
+	return c
+}
+
+// DefaultConfigPath returns ~/.network-monitor/config.yaml.
+func DefaultConfigPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "config.yaml"
+	}
+	return filepath.Join(homeDir, ".network-monitor", "config.yaml")
+}
+
+// ConfigError reports an invalid config value and where it was found.
+type ConfigError struct {
+	File string
+	Line int
+	Key  string
+	Msg  string
+}
+
+func (e *ConfigError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File)
+		if e.Line > 0 {
+			fmt.Fprintf(&b, ":%d", e.Line)
+		}
+		b.WriteString(": ")
+	}
+	if e.Key != "" {
+		b.WriteString(e.Key)
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Msg)
+	return b.String()
+}
+
+// LoadConfig reads and validates the config file at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is chosen by the user
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return ParseConfig(path, data)
+}
+
+var (
+	// yamlLinePattern extracts the line number yaml.v3 puts in its errors.
+	yamlLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+	// yamlUnknownFieldPattern matches the errors of KnownFields decoding.
+	yamlUnknownFieldPattern = regexp.MustCompile(`^field \S+ not found in type \S+$`)
+)
+
+// ParseConfig decodes YAML config data on top of the defaults and validates
+// the result. Errors name the file, line and key of the offending value;
+// when there are several, they are joined.
+func ParseConfig(name string, data []byte) (*Config, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, yamlConfigError(name, &root, err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Path = name
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, yamlConfigError(name, &root, err)
+	}
+
+	problems := cfg.validate()
+	if len(problems) == 0 {
+		return cfg, nil
+	}
+	errs := make([]error, 0, len(problems))
+	for _, p := range problems {
+		p.File = name
+		p.Line = lineOfKey(&root, p.Key)
+		errs = append(errs, p)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// yamlConfigError converts yaml.v3 parse and type errors into ConfigErrors.
+func yamlConfigError(name string, root *yaml.Node, err error) error {
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	errs := make([]error, 0, len(messages))
+	for _, msg := range messages {
+		ce := &ConfigError{File: name, Msg: strings.TrimPrefix(msg, "yaml: ")}
+		if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+			ce.Line, _ = strconv.Atoi(m[1])
+			ce.Msg = m[2]
+			ce.Key = keyAtLine(root, ce.Line)
+		}
+		if yamlUnknownFieldPattern.MatchString(ce.Msg) {
+			ce.Msg = "unknown key"
+		}
+		errs = append(errs, ce)
+	}
+	return errors.Join(errs...)
+}
+
+// keyAtLine returns the dotted path of the mapping key on the given line.
+func keyAtLine(root *yaml.Node, line int) string {
+	var find func(n *yaml.Node, prefix string) string
+	find = func(n *yaml.Node, prefix string) string {
+		switch n.Kind {
+		case yaml.DocumentNode:
+			for _, c := range n.Content {
+				if key := find(c, prefix); key != "" {
+					return key
+				}
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k, v := n.Content[i], n.Content[i+1]
+				path := joinKey(prefix, k.Value)
+				if k.Line == line {
+					return path
+				}
+				if key := find(v, path); key != "" {
+					return key
+				}
+			}
+		case yaml.SequenceNode:
+			for i, c := range n.Content {
+				path := fmt.Sprintf("%s[%d]", prefix, i)
+				if c.Line == line && c.Kind == yaml.ScalarNode {
+					return path
+				}
+				if key := find(c, path); key != "" {
+					return key
+				}
+			}
+		}
+		return ""
+	}
+	return find(root, "")
+}
+
+// lineOfKey returns the line of a dotted key path such as "tcp.target" or
+// "classifier.gateway_ports[1]", or 0 when the file does not set it.
+func lineOfKey(root *yaml.Node, key string) int {
+	n := root
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+
+	line := 0
+	for _, part := range strings.Split(key, ".") {
+		name, index := part, -1
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			name = part[:i]
+			index, _ = strconv.Atoi(strings.TrimSuffix(part[i+1:], "]"))
+		}
+
+		if n == nil || n.Kind != yaml.MappingNode {
+			return line
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == name {
+				line, next = n.Content[i].Line, n.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return line
+		}
+		n = next
+
+		if index >= 0 {
+			if n.Kind != yaml.SequenceNode || index >= len(n.Content) {
+				return line
+			}
+			n = n.Content[index]
+			line = n.Line
+		}
+	}
+	return line
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// validate checks every value and returns one error per problem, keyed by
+// its dotted path in the config file.
+func (c *Config) validate() []*ConfigError {
+	var problems []*ConfigError
+	fail := func(key, format string, args ...any) {
+		problems = append(problems, &ConfigError{Key: key, Msg: fmt.Sprintf(format, args...)})
+	}
+	positive := func(key string, d time.Duration) {
+		if d <= 0 {
+			fail(key, "must be a positive duration such as 30s, got %v", d)
+		}
+	}
+	hostPort := func(key, value string) {
+		if err := validateHostPort(value); err != nil {
+			fail(key, "%v", err)
+		}
+	}
+
+	if c.System.ResolvConf == "" {
+		fail("system.resolv_conf", "must not be empty")
+	}
+	positive("system.dns_poll_interval", c.System.DNSPollInterval)
+	positive("system.interface_poll_interval", c.System.InterfacePollInterval)
+	positive("system.route_poll_interval", c.System.RoutePollInterval)
+	positive("system.address_poll_interval", c.System.AddressPollInterval)
+
+	if len(c.TCP.Targets) == 0 {
+		fail("tcp.targets", "must list at least one host:port")
+	}
+	targets := func(key string, list []string, family string) {
+		seen := make(map[string]bool, len(list))
+		for i, target := range list {
+			key := fmt.Sprintf("%s[%d]", key, i)
+			hostPort(key, target)
+			if seen[target] {
+				fail(key, "duplicate target %q", target)
+			}
+			seen[target] = true
+			if host, _, err := net.SplitHostPort(target); err == nil {
+				if ip := net.ParseIP(host); ip != nil && (ip.To4() != nil) != (family == "IPv4") {
+					fail(key, "%s is not an %s address", host, family)
+				}
+			}
+		}
+	}
+	quorum := func(key string, quorum, targets int) {
+		if quorum < 0 || quorum > targets {
+			fail(key, "must be between 0 (majority) and the number of targets (%d), got %d", targets, quorum)
+		}
+	}
+	targets("tcp.targets", c.TCP.Targets, "IPv4")
+	quorum("tcp.quorum", c.TCP.Quorum, len(c.TCP.Targets))
+	targets("tcp.targets6", c.TCP.Targets6, "IPv6")
+	quorum("tcp.quorum6", c.TCP.Quorum6, len(c.TCP.Targets6))
+	if c.TCP.Mode != TCPModeKeepalive && c.TCP.Mode != TCPModeHeartbeat {
+		fail("tcp.mode", "must be %q or %q, got %q", TCPModeKeepalive, TCPModeHeartbeat, c.TCP.Mode)
+	}
+	positive("tcp.dial_timeout", c.TCP.DialTimeout)
+	positive("tcp.keepalive_idle", c.TCP.KeepaliveIdle)
+	positive("tcp.keepalive_interval", c.TCP.KeepaliveInterval)
+	if c.TCP.KeepaliveCount < 1 {
+		fail("tcp.keepalive_count", "must be at least 1, got %d", c.TCP.KeepaliveCount)
+	}
+	if c.TCP.UserTimeout < 0 {
+		fail("tcp.user_timeout", "must not be negative (0 keeps the kernel default), got %v", c.TCP.UserTimeout)
+	}
+	positive("tcp.check_interval", c.TCP.CheckInterval)
+	positive("tcp.read_timeout", c.TCP.ReadTimeout)
+	positive("tcp.heartbeat_interval", c.TCP.HeartbeatInterval)
+	positive("tcp.heartbeat_timeout", c.TCP.HeartbeatTimeout)
+	positive("tcp.reconnect_delay", c.TCP.ReconnectDelay)
+	if c.TCP.ReconnectMaxDelay < c.TCP.ReconnectDelay {
+		fail("tcp.reconnect_max_delay", "must not be shorter than tcp.reconnect_delay (%v), got %v",
+			c.TCP.ReconnectDelay, c.TCP.ReconnectMaxDelay)
+	}
+	positive("tcp.info_interval", c.TCP.InfoInterval)
+	if c.TCP.RetransmitWarn < 1 {
+		fail("tcp.retransmit_warn", "must be at least 1, got %d", c.TCP.RetransmitWarn)
+	}
+
+	positive("watchdog.interval", c.Watchdog.Interval)
+	hostPort("watchdog.route_probe", c.Watchdog.RouteProbe)
+	if c.Watchdog.IPv6 {
+		hostPort("watchdog.route_probe6", c.Watchdog.RouteProbe6)
+	}
+	if c.Watchdog.DNSDomain == "" {
+		fail("watchdog.dns_domain", "must not be empty")
+	}
+	positive("watchdog.dns_timeout", c.Watchdog.DNSTimeout)
+	if u, err := url.Parse(c.Watchdog.HTTPURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fail("watchdog.http_url", "must be an absolute http or https URL, got %q", c.Watchdog.HTTPURL)
+	}
+	positive("watchdog.http_timeout", c.Watchdog.HTTPTimeout)
+	for i, ep := range c.Watchdog.PortalEndpoints {
+		if err := validatePortalEndpoint(ep); err != nil {
+			fail(fmt.Sprintf("watchdog.portal_endpoints[%d]", i), "%v", err)
+		}
+	}
+	if err := validatePortalTLSURL(c.Watchdog.PortalTLSURL); err != nil {
+		fail("watchdog.portal_tls_url", "%v", err)
+	}
+	checks := make(map[string]bool, len(c.Watchdog.Checks))
+	for i, check := range c.Watchdog.Checks {
+		key := fmt.Sprintf("watchdog.checks[%d]", i)
+		switch {
+		case check.Name == "":
+			fail(key+".name", "must not be empty")
+		case checks[check.Name]:
+			fail(key+".name", "duplicate check %q", check.Name)
+		case check.Type == "" && !isBuiltinCheck(check.Name):
+			fail(key+".type", "must be set for a check that is not built in (%s)", strings.Join(CheckTypes(), ", "))
+		case check.Type != "" && isBuiltinCheck(check.Name):
+			fail(key+".name", "%q is a built-in check; leave out type to adjust it", check.Name)
+		case check.Type == "" && len(check.Params) > 0:
+			fail(key, "built-in checks only take interval, timeout and disabled")
+		case check.Type != "":
+			if _, err := NewCheck(check, CheckEnv{Watchdog: c.Watchdog, ResolvConf: c.System.ResolvConf}); err != nil {
+				fail(key, "%v", err)
+			}
+		}
+		checks[check.Name] = true
+		if check.Interval < 0 {
+			fail(key+".interval", "must not be negative (0 uses watchdog.interval), got %v", check.Interval)
+		}
+		if check.Timeout < 0 {
+			fail(key+".timeout", "must not be negative (0 uses the default), got %v", check.Timeout)
+		}
+	}
+
+	positive("classifier.probe_timeout", c.Classifier.ProbeTimeout)
+	if len(c.Classifier.GatewayPorts) == 0 {
+		fail("classifier.gateway_ports", "must list at least one port")
+	}
+	for i, port := range c.Classifier.GatewayPorts {
+		if port < 1 || port > 65535 {
+			fail(fmt.Sprintf("classifier.gateway_ports[%d]", i), "port %d out of range 1-65535", port)
+		}
+	}
+	if len(c.Classifier.UpstreamTargets) == 0 {
+		fail("classifier.upstream_targets", "must list at least one host:port")
+	}
+	for i, target := range c.Classifier.UpstreamTargets {
+		hostPort(fmt.Sprintf("classifier.upstream_targets[%d]", i), target)
+	}
+
+	names := make(map[string]bool, len(c.Uplinks))
+	for i, u := range c.Uplinks {
+		key := fmt.Sprintf("uplinks[%d]", i)
+		switch {
+		case u.Name == "":
+			fail(key+".name", "must not be empty")
+		case names[u.Name]:
+			fail(key+".name", "duplicate uplink %q", u.Name)
+		}
+		names[u.Name] = true
+		if u.Interface == "" && u.Source == "" {
+			fail(key, "must set interface, source or both")
+		}
+		if u.Interface != "" && !bindToDeviceSupported {
+			fail(key+".interface", "binding to an interface is only supported on Linux; use source")
+		}
+		if u.Source != "" && net.ParseIP(u.Source) == nil {
+			fail(key+".source", "must be an IP address, got %q", u.Source)
+		}
+	}
+
+	return problems
+}
+
+func validateHostPort(value string) error {
+	host, port, err := net.SplitHostPort(value)
+	if err != nil {
+		return fmt.Errorf("expected host:port, got %q", value)
+	}
+	if host == "" {
+		return fmt.Errorf("missing host in %q", value)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port in %q", value)
+	}
+	return nil
+}
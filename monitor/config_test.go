@@ -0,0 +1,254 @@
+package monitor
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseConfigDefaults(t *testing.T) {
+	cfg, err := ParseConfig("config.yaml", []byte(`
+tcp:
+  targets: ["1.1.1.1:443"]
+  dial_timeout: 3s
+watchdog:
+  checks:
+    - name: dns
+      interval: 1m
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	def := DefaultConfig()
+	if !slices.Equal(cfg.TCP.Targets, []string{"1.1.1.1:443"}) || cfg.TCP.DialTimeout != 3*time.Second {
+		t.Errorf("tcp = %v, %v, want the values from the file", cfg.TCP.Targets, cfg.TCP.DialTimeout)
+	}
+	// Keys the file leaves out keep their defaults, also within a section it sets
+	if !slices.Equal(cfg.TCP.Targets6, def.TCP.Targets6) || cfg.TCP.KeepaliveIdle != def.TCP.KeepaliveIdle {
+		t.Errorf("tcp = %v, %v, want the defaults", cfg.TCP.Targets6, cfg.TCP.KeepaliveIdle)
+	}
+	if cfg.System != def.System || cfg.Watchdog.HTTPURL != def.Watchdog.HTTPURL ||
+		cfg.Classifier.ProbeTimeout != def.Classifier.ProbeTimeout {
+		t.Errorf("unset sections differ from the defaults: %+v %+v", cfg.System, cfg.Classifier)
+	}
+	if len(cfg.Watchdog.Checks) != 1 || cfg.Watchdog.Checks[0].Interval != time.Minute {
+		t.Errorf("watchdog.checks = %+v, want the dns check every 1m", cfg.Watchdog.Checks)
+	}
+	if cfg.Path != "config.yaml" {
+		t.Errorf("Path = %q, want config.yaml", cfg.Path)
+	}
+}
+
+func TestParseConfigEmpty(t *testing.T) {
+	cfg, err := ParseConfig("config.yaml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	def := DefaultConfig()
+	if cfg.TCP.Mode != def.TCP.Mode || cfg.Watchdog.Interval != def.Watchdog.Interval ||
+		len(cfg.Watchdog.PortalEndpoints) != len(def.Watchdog.PortalEndpoints) {
+		t.Errorf("ParseConfig(empty) = %+v, want the defaults", cfg)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	type problem struct {
+		line int
+		key  string
+		msg  string // a substring of the message
+	}
+	for _, tc := range []struct {
+		name string
+		data string
+		want []problem
+	}{
+		{
+			name: "unknown key",
+			data: "tcp:\n  targets: [\"1.1.1.1:443\"]\n  dial_timout: 3s\n",
+			want: []problem{{3, "tcp.dial_timout", "unknown key"}},
+		},
+		{
+			name: "unknown section",
+			data: "tcp:\n  mode: keepalive\nwatchdgo:\n  interval: 1m\n",
+			want: []problem{{3, "watchdgo", "unknown key"}},
+		},
+		{
+			// A bare number has no unit and is rejected, not taken as nanoseconds
+			name: "bad duration",
+			data: "watchdog:\n  interval: 30\n  dns_timeout: soon\n",
+			want: []problem{{2, "watchdog.interval", "cannot unmarshal"}, {3, "watchdog.dns_timeout", "cannot unmarshal"}},
+		},
+		{
+			name: "negative duration",
+			data: "system:\n  dns_poll_interval: -2s\n",
+			want: []problem{{2, "system.dns_poll_interval", "positive duration"}},
+		},
+		{
+			name: "wrong type",
+			data: "tcp:\n  keepalive_count: three\n",
+			want: []problem{{2, "tcp.keepalive_count", "cannot unmarshal"}},
+		},
+		{
+			name: "syntax error",
+			data: "tcp:\n  targets: [1.1.1.1:443\n",
+			want: []problem{{1, "", "did not find expected"}},
+		},
+		{
+			name: "out of range",
+			data: `tcp:
+  targets: ["1.1.1.1:443", "8.8.8.8:443"]
+  quorum: 3
+  keepalive_count: 0
+classifier:
+  gateway_ports:
+    - 80
+    - 70000
+`,
+			want: []problem{
+				{3, "tcp.quorum", "between 0 (majority) and the number of targets (2), got 3"},
+				{4, "tcp.keepalive_count", "at least 1, got 0"},
+				{8, "classifier.gateway_ports[1]", "port 70000 out of range"},
+			},
+		},
+		{
+			name: "list items",
+			data: `tcp:
+  targets:
+    - 1.1.1.1:443
+    - "[2606:4700:4700::1111]:443"
+    - 1.1.1.1:443
+    - 8.8.8.8
+`,
+			want: []problem{
+				{4, "tcp.targets[1]", "is not an IPv4 address"},
+				{5, "tcp.targets[2]", "duplicate target"},
+				{6, "tcp.targets[3]", "expected host:port"},
+			},
+		},
+		{
+			name: "relation between keys",
+			data: "tcp:\n  reconnect_delay: 1m\n  reconnect_max_delay: 30s\n",
+			want: []problem{{3, "tcp.reconnect_max_delay", "must not be shorter than tcp.reconnect_delay"}},
+		},
+		{
+			name: "value the file does not set",
+			data: "watchdog:\n  ipv6: true\ntcp:\n  targets: []\n",
+			want: []problem{{4, "tcp.targets", "at least one"}},
+		},
+		{
+			name: "checks",
+			data: `watchdog:
+  checks:
+    - name: dns
+      type: dns
+    - name: extra
+    - name: extra
+      type: dns
+      timeout: -1s
+`,
+			want: []problem{
+				{3, "watchdog.checks[0].name", "built-in check"},
+				{5, "watchdog.checks[1].type", "must be set"},
+				{6, "watchdog.checks[2].name", "duplicate check"},
+				{8, "watchdog.checks[2].timeout", "must not be negative"},
+			},
+		},
+		{
+			name: "check parameters",
+			data: "watchdog:\n  checks:\n    - name: tls\n      type: dot\n      endpoints: [\"1.1.1.1:99999\"]\n",
+			want: []problem{{3, "watchdog.checks[0]", "invalid port"}},
+		},
+		{
+			name: "portal endpoint",
+			data: "watchdog:\n  portal_endpoints:\n    - url: https://example.com/\n      status: 204\n",
+			want: []problem{{3, "watchdog.portal_endpoints[0]", "absolute http URL"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := ParseConfig("config.yaml", []byte(tc.data))
+			if err == nil {
+				t.Fatalf("ParseConfig() = %+v, want errors", cfg)
+			}
+			got := configErrors(err)
+			if len(got) != len(tc.want) {
+				t.Fatalf("ParseConfig() errors:\n%v\nwant %d", err, len(tc.want))
+			}
+			for i, want := range tc.want {
+				ce := got[i]
+				if ce.File != "config.yaml" || ce.Line != want.line || ce.Key != want.key || !strings.Contains(ce.Msg, want.msg) {
+					t.Errorf("error %d = %q (line %d, key %q), want line %d, key %q, message containing %q",
+						i, ce, ce.Line, ce.Key, want.line, want.key, want.msg)
+				}
+			}
+		})
+	}
+}
+
+// configErrors returns the ConfigErrors joined in err.
+func configErrors(err error) []*ConfigError {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	var problems []*ConfigError
+	for _, err := range errs {
+		var ce *ConfigError
+		if errors.As(err, &ce) {
+			problems = append(problems, ce)
+		}
+	}
+	return problems
+}
+
+func TestLineOfKey(t *testing.T) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(`tcp:
+  targets:
+    - 1.1.1.1:443
+    - 8.8.8.8:443
+watchdog:
+  checks:
+    - name: dns
+      interval: 1m
+`), &root); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		key  string
+		want int
+	}{
+		{"tcp", 1},
+		{"tcp.targets", 2},
+		{"tcp.targets[1]", 4},
+		{"watchdog.checks[0].interval", 8},
+		// Keys the file does not set fall back to their closest parent
+		{"tcp.quorum", 1},
+		{"tcp.targets[5]", 2},
+		{"watchdog.checks[0].timeout", 7},
+		{"system.resolv_conf", 0},
+	} {
+		if got := lineOfKey(&root, tc.key); got != tc.want {
+			t.Errorf("lineOfKey(%q) = %d, want %d", tc.key, got, tc.want)
+		}
+	}
+}
+
+func TestConfigErrorString(t *testing.T) {
+	for _, tc := range []struct {
+		err  ConfigError
+		want string
+	}{
+		{ConfigError{File: "config.yaml", Line: 3, Key: "tcp.mode", Msg: "bad"}, "config.yaml:3: tcp.mode: bad"},
+		{ConfigError{File: "config.yaml", Key: "tcp.mode", Msg: "bad"}, "config.yaml: tcp.mode: bad"},
+		{ConfigError{Msg: "bad"}, "bad"},
+	} {
+		if got := tc.err.Error(); got != tc.want {
+			t.Errorf("Error() = %q, want %q", got, tc.want)
+		}
+	}
+}
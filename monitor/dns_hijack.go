@@ -0,0 +1,358 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func init() {
+	RegisterCheck("dns_hijack", newDNSHijackCheck)
+}
+
+// sinkholeWait bounds the query to the sinkhole. Interceptors answer as
+// fast as a resolver would, so waiting longer only delays the round.
+const sinkholeWait = time.Second
+
+// Reasons of the dns_hijack check, in the order they are reported when
+// several apply.
+const (
+	reasonDNSIntercepted      = "dns_intercepted"
+	reasonNXDOMAINRewritten   = "dns_nxdomain_rewritten"
+	reasonDNSCanaryMismatched = "dns_canary_mismatch"
+)
+
+// dnsCanary is a name whose answer is known: either the addresses listed
+// in the config or, without them, what the trusted resolver answers.
+type dnsCanary struct {
+	name  string
+	addrs []netip.Addr
+}
+
+// dnsHijackCheck looks for resolvers that lie: ones that answer names that
+// do not exist (NXDOMAIN rewriting, typically to ad or search pages), that
+// answer canaries with other addresses than a trusted resolver, and
+// networks that intercept port 53, detected by querying a sinkhole address
+// that runs no DNS server.
+type dnsHijackCheck struct {
+	name       string
+	resolvConf string
+	servers    []string
+	trusted    string
+	sinkhole   string
+	canaries   []dnsCanary
+	probes     int
+	suffix     string
+}
+
+func newDNSHijackCheck(cfg CheckConfig, env CheckEnv) (Check, error) {
+	type canary struct {
+		Name      string   `yaml:"name"`
+		Addresses []string `yaml:"addresses"`
+	}
+	p := struct {
+		ResolvConf string   `yaml:"resolv_conf"`
+		Servers    []string `yaml:"servers"`
+		Trusted    string   `yaml:"trusted"`
+		Sinkhole   string   `yaml:"sinkhole"`
+		Canaries   []canary `yaml:"canaries"`
+		Probes     int      `yaml:"probes"`
+		Suffix     string   `yaml:"suffix"`
+	}{
+		ResolvConf: env.resolvConf(),
+		Trusted:    "1.1.1.1",
+		Sinkhole:   "198.51.100.53",
+		Probes:     2,
+		Suffix:     "com",
+	}
+	if err := cfg.DecodeParams(&p); err != nil {
+		return nil, err
+	}
+
+	for _, server := range p.Servers {
+		if _, err := netip.ParseAddr(server); err != nil {
+			return nil, fmt.Errorf("servers: %q is not an IP address", server)
+		}
+	}
+	if _, err := netip.ParseAddr(p.Trusted); err != nil {
+		return nil, fmt.Errorf("trusted: %q is not an IP address", p.Trusted)
+	}
+	if _, err := netip.ParseAddr(p.Sinkhole); err != nil {
+		return nil, fmt.Errorf("sinkhole: %q is not an IP address", p.Sinkhole)
+	}
+	if p.Probes < 0 {
+		return nil, fmt.Errorf("probes must not be negative, got %d", p.Probes)
+	}
+	if _, err := dnsmessage.NewName(fqdn(randomDNSName(p.Suffix))); err != nil {
+		return nil, fmt.Errorf("suffix: %w", err)
+	}
+	if len(p.Canaries) == 0 {
+		p.Canaries = []canary{{Name: env.Watchdog.DNSDomain}}
+	}
+
+	c := &dnsHijackCheck{
+		name:       cfg.Name,
+		resolvConf: p.ResolvConf,
+		servers:    p.Servers,
+		trusted:    p.Trusted,
+		sinkhole:   p.Sinkhole,
+		probes:     p.Probes,
+		suffix:     p.Suffix,
+	}
+	for i, cn := range p.Canaries {
+		if _, err := dnsmessage.NewName(fqdn(cn.Name)); err != nil || cn.Name == "" {
+			return nil, fmt.Errorf("canaries[%d]: invalid name %q", i, cn.Name)
+		}
+		canary := dnsCanary{name: cn.Name}
+		for _, a := range cn.Addresses {
+			addr, err := netip.ParseAddr(a)
+			if err != nil {
+				return nil, fmt.Errorf("canaries[%d]: %q is not an IP address", i, a)
+			}
+			canary.addrs = append(canary.addrs, addr)
+		}
+		c.canaries = append(c.canaries, canary)
+	}
+	return c, nil
+}
+
+func (c *dnsHijackCheck) Name() string { return c.name }
+
+// randomDNSName returns a name under suffix that does not exist.
+func randomDNSName(suffix string) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+	label := make([]byte, 16)
+	for i := range label {
+		label[i] = letters[rand.IntN(len(letters))]
+	}
+	return "nm-" + string(label) + "." + strings.TrimSuffix(suffix, ".")
+}
+
+// dnsLookup is the answer of one server for one name.
+type dnsLookup struct {
+	server  string
+	name    string
+	random  bool // name was generated and should not exist
+	trusted bool // server is the trusted resolver
+	resp    dnsResponse
+	err     error
+}
+
+func (l dnsLookup) details() map[string]any {
+	d := map[string]any{"server": l.server, "name": l.name, "random": l.random, "trusted": l.trusted}
+	if l.err != nil {
+		d["error"] = l.err.Error()
+		return d
+	}
+	d["rcode"] = rcodeName(l.resp.rcode)
+	if len(l.resp.addrs) > 0 {
+		d["addresses"] = addrStrings(l.resp.addrs)
+	}
+	return d
+}
+
+// dnsFinding is one sign of hijacking.
+type dnsFinding struct {
+	reason string
+	detail string
+}
+
+func (c *dnsHijackCheck) Run(ctx context.Context) Result {
+	servers, err := checkNameservers(c.servers, c.resolvConf)
+	if err != nil {
+		return Result{Err: err, Message: fmt.Sprintf("✗ DNS hijack check: %v", err)}
+	}
+
+	randoms := make([]string, c.probes)
+	for i := range randoms {
+		randoms[i] = randomDNSName(c.suffix)
+	}
+	var lookups []dnsLookup
+	for i, server := range append(slices.Clone(servers), c.trusted) {
+		trusted := i == len(servers)
+		for _, name := range randoms {
+			lookups = append(lookups, dnsLookup{server: server, name: name, random: true, trusted: trusted})
+		}
+		for _, canary := range c.canaries {
+			lookups = append(lookups, dnsLookup{server: server, name: canary.name, trusted: trusted})
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := range lookups {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l := &lookups[i]
+			l.resp, _, l.err = queryNameserver(ctx, "udp", l.server, l.name)
+		}()
+	}
+	var sinkholeErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sinkholeCtx, cancel := context.WithTimeout(ctx, sinkholeWait)
+		defer cancel()
+		_, _, sinkholeErr = queryNameserver(sinkholeCtx, "udp", c.sinkhole, c.canaries[0].name)
+	}()
+	wg.Wait()
+
+	return c.result(servers, lookups, sinkholeErr)
+}
+
+// result compares the answers of every resolver with what is expected. A
+// resolver that cannot be reached is no sign of hijacking; the dns and
+// nameservers checks report it. The check warns when it finds a sign of
+// hijacking, since resolution works but cannot be trusted.
+func (c *dnsHijackCheck) result(servers []string, lookups []dnsLookup, sinkholeErr error) Result {
+	trusted := make(map[string]dnsLookup)
+	for _, l := range lookups {
+		if l.trusted {
+			trusted[l.name] = l
+		}
+	}
+	trustedErr := errors.New("no answer")
+	for _, l := range trusted {
+		if l.err == nil {
+			trustedErr = nil
+			break
+		}
+		trustedErr = l.err
+	}
+
+	var findings []dnsFinding
+	if sinkholeErr == nil {
+		findings = append(findings, dnsFinding{reasonDNSIntercepted,
+			fmt.Sprintf("%s answered although it runs no DNS server, so port 53 is intercepted", c.sinkhole)})
+	}
+
+	answered := make(map[string]bool)
+	details := make([]map[string]any, len(lookups))
+	for i, l := range lookups {
+		details[i] = l.details()
+		if l.err != nil || l.trusted {
+			continue
+		}
+		answered[l.server] = true
+		if l.random {
+			if f, ok := checkRandomAnswer(l, trusted[l.name]); ok {
+				findings = append(findings, f)
+			}
+			continue
+		}
+		canary := c.canaries[slices.IndexFunc(c.canaries, func(cn dnsCanary) bool { return cn.name == l.name })]
+		if f, ok := checkCanaryAnswer(l, canary, trusted[l.name]); ok {
+			findings = append(findings, f)
+		}
+	}
+
+	r := Result{
+		Status:  StatusOK,
+		Target:  strings.Join(servers, ", "),
+		Details: map[string]any{"trusted": c.trusted, "lookups": details},
+	}
+	if trustedErr != nil {
+		r.Details["trusted_error"] = trustedErr.Error()
+	}
+	if len(answered) == 0 && len(findings) == 0 {
+		r.Status = ""
+		r.Message = fmt.Sprintf("✗ DNS hijack check: no resolver answered (%s)", strings.Join(servers, ", "))
+		return r
+	}
+
+	if len(findings) == 0 {
+		r.Message = fmt.Sprintf("✓ No DNS hijacking: %d of %d resolvers answered, %d random names did not exist, %d canaries as expected",
+			len(answered), len(servers), c.probes, len(c.canaries))
+		if trustedErr != nil {
+			r.Message += fmt.Sprintf(" (trusted resolver %s: %v)", c.trusted, trustedErr)
+		}
+		return r
+	}
+
+	order := []string{reasonDNSIntercepted, reasonNXDOMAINRewritten, reasonDNSCanaryMismatched}
+	slices.SortStableFunc(findings, func(a, b dnsFinding) int {
+		return slices.Index(order, a.reason) - slices.Index(order, b.reason)
+	})
+	parts := make([]string, len(findings))
+	reasons := make([]string, len(findings))
+	for i, f := range findings {
+		parts[i], reasons[i] = f.detail, f.reason
+	}
+	r.Status, r.Reason = StatusWarn, findings[0].reason
+	r.Details["findings"] = parts
+	r.Details["reasons"] = slices.Compact(reasons)
+	r.Message = "⚠ DNS HIJACKING suspected: " + strings.Join(parts, "; ")
+	return r
+}
+
+// checkRandomAnswer flags a resolver that returns addresses for a name that
+// does not exist. A trusted resolver answering as well means the suffix has
+// a wildcard record, which is no sign of hijacking.
+func checkRandomAnswer(l, trusted dnsLookup) (dnsFinding, bool) {
+	if l.resp.rcode != dnsmessage.RCodeSuccess || len(l.resp.addrs) == 0 {
+		return dnsFinding{}, false
+	}
+	if trusted.err == nil && trusted.resp.rcode == dnsmessage.RCodeSuccess && len(trusted.resp.addrs) > 0 {
+		return dnsFinding{}, false
+	}
+	return dnsFinding{reasonNXDOMAINRewritten, fmt.Sprintf("%s answered non-existent %s with %s",
+		l.server, l.name, strings.Join(addrStrings(l.resp.addrs), ", "))}, true
+}
+
+// checkCanaryAnswer flags a resolver that denies a canary exists or answers
+// it with other addresses than expected. Without configured addresses the
+// answer of the trusted resolver is expected; CDNs answer differently
+// depending on who asks, so only private and reserved addresses the trusted
+// resolver did not return are flagged then.
+func checkCanaryAnswer(l dnsLookup, canary dnsCanary, trusted dnsLookup) (dnsFinding, bool) {
+	expected := canary.addrs
+	if len(expected) == 0 && trusted.err == nil {
+		expected = trusted.resp.addrs
+	}
+	if len(expected) > 0 && l.resp.rcode == dnsmessage.RCodeNameError {
+		return dnsFinding{reasonDNSCanaryMismatched, fmt.Sprintf("%s claims canary %s does not exist", l.server, l.name)}, true
+	}
+
+	var unexpected []string
+	for _, addr := range l.resp.addrs {
+		switch {
+		case slices.Contains(expected, addr):
+		case len(canary.addrs) > 0, !isPublicAddr(addr):
+			unexpected = append(unexpected, addr.String())
+		}
+	}
+	if len(unexpected) == 0 {
+		return dnsFinding{}, false
+	}
+	want := "addresses of the trusted resolver"
+	if len(canary.addrs) > 0 {
+		want = strings.Join(addrStrings(canary.addrs), ", ")
+	}
+	return dnsFinding{reasonDNSCanaryMismatched, fmt.Sprintf("%s answered canary %s with %s instead of %s",
+		l.server, l.name, strings.Join(unexpected, ", "), want)}, true
+}
+
+// cgnatPrefix is the shared address space of carrier-grade NAT (RFC 6598).
+var cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
+
+// isPublicAddr reports whether addr can be the address of a public host.
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !cgnatPrefix.Contains(addr)
+}
+
+func addrStrings(addrs []netip.Addr) []string {
+	s := make([]string, len(addrs))
+	for i, a := range addrs {
+		s[i] = a.String()
+	}
+	return s
+}
@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestNewDNSHijackCheckResolvConf(t *testing.T) {
+	env := CheckEnv{Watchdog: DefaultConfig().Watchdog, ResolvConf: "/run/systemd/resolve/resolv.conf"}
+	c, err := NewCheck(CheckConfig{Name: "hijack", Type: "dns_hijack"}, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.(*dnsHijackCheck).resolvConf; got != env.ResolvConf {
+		t.Errorf("resolvConf = %q, want %q", got, env.ResolvConf)
+	}
+}
+
+// hijackLookup returns the answer of server for name with rcode and addrs.
+func hijackLookup(server, name string, rcode dnsmessage.RCode, addrs ...string) dnsLookup {
+	l := dnsLookup{server: server, name: name, resp: dnsResponse{rcode: rcode}}
+	for _, a := range addrs {
+		l.resp.addrs = append(l.resp.addrs, netip.MustParseAddr(a))
+	}
+	return l
+}
+
+func TestCheckRandomAnswer(t *testing.T) {
+	const name = "nm-0123456789abcdef.com"
+	nxdomain := hijackLookup("1.1.1.1", name, dnsmessage.RCodeNameError)
+	for _, tc := range []struct {
+		name    string
+		lookup  dnsLookup
+		trusted dnsLookup
+		want    bool
+	}{
+		{"nxdomain", hijackLookup("192.168.1.1", name, dnsmessage.RCodeNameError), nxdomain, false},
+		{"rewritten", hijackLookup("192.168.1.1", name, dnsmessage.RCodeSuccess, "198.51.100.7"), nxdomain, true},
+		{"no addresses", hijackLookup("192.168.1.1", name, dnsmessage.RCodeSuccess), nxdomain, false},
+		{"server failure", hijackLookup("192.168.1.1", name, dnsmessage.RCodeServerFailure, "198.51.100.7"), nxdomain, false},
+		{"wildcard", hijackLookup("192.168.1.1", name, dnsmessage.RCodeSuccess, "198.51.100.7"),
+			hijackLookup("1.1.1.1", name, dnsmessage.RCodeSuccess, "198.51.100.7"), false},
+		{"trusted failed", hijackLookup("192.168.1.1", name, dnsmessage.RCodeSuccess, "198.51.100.7"),
+			dnsLookup{server: "1.1.1.1", name: name, err: errors.New("i/o timeout")}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, found := checkRandomAnswer(tc.lookup, tc.trusted)
+			if found != tc.want {
+				t.Fatalf("checkRandomAnswer() = %+v, %v, want %v", f, found, tc.want)
+			}
+			if found && (f.reason != reasonNXDOMAINRewritten || !strings.Contains(f.detail, "198.51.100.7")) {
+				t.Errorf("finding = %+v, want %s naming the address", f, reasonNXDOMAINRewritten)
+			}
+		})
+	}
+}
+
+func TestCheckCanaryAnswer(t *testing.T) {
+	const name = "www.example.com"
+	pinned := dnsCanary{name: name, addrs: []netip.Addr{netip.MustParseAddr("93.184.215.14")}}
+	unpinned := dnsCanary{name: name}
+	trusted := hijackLookup("1.1.1.1", name, dnsmessage.RCodeSuccess, "93.184.215.14")
+	trustedFailed := dnsLookup{server: "1.1.1.1", name: name, err: errors.New("i/o timeout")}
+
+	for _, tc := range []struct {
+		name    string
+		lookup  dnsLookup
+		canary  dnsCanary
+		trusted dnsLookup
+		want    string // a substring of the finding; empty for none
+	}{
+		{name: "pinned match", lookup: hijackLookup("192.168.1.1", name, dnsmessage.RCodeSuccess, "93.184.215.14"),
+			canary: pinned, trusted: trustedFailed},
+		{name: "pinned mismatch", lookup: hijackLookup("192.168.1.1", name, dnsmessage.RCodeSuccess, "203.0.113.9"),
+			canary: pinned, trusted: trusted, want: "with 203.0.113.9 instead of 93.184.215.14"},
+		{name: "pinned denied", lookup: hijackLookup("192.168.1.1", name, dnsmessage.RCodeNameError),
+			canary: pinned, trusted: trustedFailed, want: "does not exist"},
+		{name: "unpinned match", lookup: hijackLookup("192.168.1.1", name, dnsmessage.RCodeSuccess, "93.184.215.14"),
+			canary: unpinned, trusted: trusted},
+		// CDNs answer differently depending on who asks
+		{name: "unpinned other public address", lookup: hijackLookup("192.168.1.1", name, dnsmessage.RCodeSuccess, "203.0.113.9"),
+			canary: unpinned, trusted: trusted},
+		{name: "unpinned private address", lookup: hijackLookup("192.168.1.1", name, dnsmessage.RCodeSuccess, "192.168.1.1"),
+			canary: unpinned, trusted: trusted, want: "with 192.168.1.1 instead of addresses of the trusted resolver"},
+		{name: "unpinned cgnat address", lookup: hijackLookup("192.168.1.1", name, dnsmessage.RCodeSuccess, "100.64.0.1"),
+			canary: unpinned, trusted: trusted, want: "with 100.64.0.1"},
+		{name: "unpinned denied", lookup: hijackLookup("192.168.1.1", name, dnsmessage.RCodeNameError),
+			canary: unpinned, trusted: trusted, want: "does not exist"},
+		{name: "unpinned denied without trusted answer", lookup: hijackLookup("192.168.1.1", name, dnsmessage.RCodeNameError),
+			canary: unpinned, trusted: trustedFailed},
+		{name: "unpinned private address without trusted answer",
+			lookup: hijackLookup("192.168.1.1", name, dnsmessage.RCodeSuccess, "10.0.0.1"),
+			canary: unpinned, trusted: trustedFailed, want: "with 10.0.0.1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, found := checkCanaryAnswer(tc.lookup, tc.canary, tc.trusted)
+			if found != (tc.want != "") {
+				t.Fatalf("checkCanaryAnswer() = %+v, %v, want a finding %v", f, found, tc.want != "")
+			}
+			if found && (f.reason != reasonDNSCanaryMismatched || !strings.Contains(f.detail, tc.want)) {
+				t.Errorf("finding = %+v, want %s containing %q", f, reasonDNSCanaryMismatched, tc.want)
+			}
+		})
+	}
+}
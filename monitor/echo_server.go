@@ -0,0 +1,132 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// EchoServer answers the heartbeats of TCP monitors running in heartbeat
+// mode. It is meant to run on a host under our control, such as a VPS, so
+// the monitor measures the path to it end to end.
+type EchoServer struct {
+	logger      *Logger
+	ctx         context.Context
+	addr        string
+	idleTimeout time.Duration
+
+	wg sync.WaitGroup
+}
+
+// NewEchoServer constructs an echo server listening on addr. Connections that
+// send no heartbeat for idleTimeout are closed.
+func NewEchoServer(ctx context.Context, logger *Logger, addr string, idleTimeout time.Duration) *EchoServer {
+	return &EchoServer{
+		logger:      logger,
+		ctx:         ctx,
+		addr:        addr,
+		idleTimeout: idleTimeout,
+	}
+}
+
+// Run accepts connections until the context is canceled and then waits for
+// open connections to close.
+func (s *EchoServer) Run() error {
+	var lc net.ListenConfig
+	ln, err := lc.Listen(s.ctx, "tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.addr, err)
+	}
+	stop := context.AfterFunc(s.ctx, func() { _ = ln.Close() })
+	defer stop()
+
+	s.emit(Event{
+		Address: ln.Addr().String(),
+		Message: fmt.Sprintf("Echo server listening on %s (idle timeout %v)", ln.Addr(), s.idleTimeout),
+	})
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				break
+			}
+			s.emit(Event{Severity: SeverityError, Err: err, Message: fmt.Sprintf("Accept failed: %v", err)})
+			time.Sleep(time.Second)
+			continue
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serve(conn)
+		}()
+	}
+
+	s.wg.Wait()
+	s.emit(Event{Message: "Echo server stopped"})
+	return nil
+}
+
+// serve echoes the heartbeats of one client until it disconnects, goes idle
+// or sends something that is not a heartbeat.
+func (s *EchoServer) serve(conn net.Conn) {
+	remote := conn.RemoteAddr().String()
+	start := time.Now()
+	stop := context.AfterFunc(s.ctx, func() { _ = conn.Close() })
+	defer stop()
+	defer func() { _ = conn.Close() }()
+
+	s.emit(Event{Target: remote, Message: fmt.Sprintf("Client %s connected", remote)})
+
+	var count int
+	frame := make([]byte, heartbeatFrameSize)
+	err := func() error {
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(s.idleTimeout))
+			if _, err := io.ReadFull(conn, frame); err != nil {
+				return err
+			}
+			if _, _, err := decodeHeartbeat(frame); err != nil {
+				return err
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(s.idleTimeout))
+			if _, err := conn.Write(frame); err != nil {
+				return err
+			}
+			count++
+		}
+	}()
+
+	e := Event{
+		Target:   remote,
+		Duration: time.Since(start),
+		Fields:   map[string]any{"heartbeats": count},
+	}
+	var netErr net.Error
+	switch {
+	case s.ctx.Err() != nil, errors.Is(err, io.EOF):
+		e.Message = fmt.Sprintf("Client %s disconnected after %d heartbeats (%v)",
+			remote, count, HumanDuration(e.Duration))
+	case errors.As(err, &netErr) && netErr.Timeout():
+		e.Severity = SeverityWarn
+		e.Message = fmt.Sprintf("Client %s idle for %v after %d heartbeats, closing",
+			remote, s.idleTimeout, count)
+	default:
+		e.Severity = SeverityWarn
+		e.Err = err
+		e.Message = fmt.Sprintf("Client %s dropped after %d heartbeats: %v", remote, count, err)
+	}
+	s.emit(e)
+}
+
+// emit stamps the event with the echo category and source and forwards it to the logger.
+func (s *EchoServer) emit(e Event) {
+	e.Category = CategoryEcho
+	e.Source = SourceEcho
+	s.logger.Emit(e)
+}
@@ -0,0 +1,351 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func init() {
+	RegisterCheck("doh", newDoHCheck)
+	RegisterCheck("dot", newDoTCheck)
+}
+
+// dnsMessageType is the media type of DNS messages over HTTPS (RFC 8484).
+const dnsMessageType = "application/dns-message"
+
+// Failure classes of encrypted DNS queries: the stage the query failed in.
+// A resolver that cannot be connected to while plain DNS works usually
+// means port 853 or the DoH endpoint is blocked; a failing handshake means
+// TLS is intercepted or filtered by SNI.
+const (
+	dnsFailResolve = "resolve" // the name of the endpoint did not resolve
+	dnsFailConnect = "connect"
+	dnsFailTLS     = "tls"
+	dnsFailHTTP    = "http"  // the DoH endpoint answered with an error status
+	dnsFailQuery   = "query" // no or no valid DNS response
+	dnsFailRCode   = "rcode" // the resolver answered with an error
+)
+
+// encryptedDNSCheck resolves a domain over DNS-over-HTTPS or DNS-over-TLS
+// with each endpoint, on a new connection every run so that the handshake
+// is measured, and reports the connect, handshake and query times.
+type encryptedDNSCheck struct {
+	name      string
+	domain    string
+	endpoints []string
+	label     string // DoH or DoT
+	prefix    string // prefixes the reasons
+	uplink    *UplinkConfig
+	client    *http.Client // DoH only
+	tlsConfig *tls.Config  // DoT only; nil verifies with the system roots
+}
+
+func newDoHCheck(cfg CheckConfig, env CheckEnv) (Check, error) {
+	c, err := newEncryptedDNSCheck(cfg, env, "https://1.1.1.1/dns-query")
+	if err != nil {
+		return nil, err
+	}
+	for _, endpoint := range c.endpoints {
+		if u, err := url.Parse(endpoint); err != nil || u.Scheme != "https" || u.Host == "" {
+			return nil, fmt.Errorf("endpoints: %q is not an absolute https URL", endpoint)
+		}
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = newDialer(env.Uplink, "tcp", 0).DialContext
+	t.DisableKeepAlives = true
+	c.client = &http.Client{
+		Transport: t,
+		CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	c.label, c.prefix = "DoH", "doh"
+	return c, nil
+}
+
+func newDoTCheck(cfg CheckConfig, env CheckEnv) (Check, error) {
+	c, err := newEncryptedDNSCheck(cfg, env, "1.1.1.1:853")
+	if err != nil {
+		return nil, err
+	}
+	for i, endpoint := range c.endpoints {
+		if _, _, err := net.SplitHostPort(endpoint); err != nil {
+			endpoint = net.JoinHostPort(endpoint, "853")
+		}
+		if err := validateHostPort(endpoint); err != nil {
+			return nil, fmt.Errorf("endpoints: %w", err)
+		}
+		c.endpoints[i] = endpoint
+	}
+	c.label, c.prefix = "DoT", "dot"
+	return c, nil
+}
+
+// newEncryptedDNSCheck decodes the parameters shared by the DoH and DoT
+// checks.
+func newEncryptedDNSCheck(cfg CheckConfig, env CheckEnv, endpoint string) (*encryptedDNSCheck, error) {
+	var p struct {
+		Domain    string   `yaml:"domain"`
+		Endpoints []string `yaml:"endpoints"`
+	}
+	if err := cfg.DecodeParams(&p); err != nil {
+		return nil, err
+	}
+	if p.Domain == "" {
+		p.Domain = env.Watchdog.DNSDomain
+	}
+	if _, err := dnsmessage.NewName(fqdn(p.Domain)); err != nil {
+		return nil, fmt.Errorf("domain: %w", err)
+	}
+	if len(p.Endpoints) == 0 {
+		p.Endpoints = []string{endpoint}
+	}
+	return &encryptedDNSCheck{name: cfg.Name, domain: p.Domain, endpoints: p.Endpoints, uplink: env.Uplink}, nil
+}
+
+func (c *encryptedDNSCheck) Name() string { return c.name }
+
+// encryptedDNSQuery is the outcome of one query to one endpoint.
+type encryptedDNSQuery struct {
+	endpoint  string
+	connect   time.Duration
+	handshake time.Duration
+	query     time.Duration
+	rcode     dnsmessage.RCode
+	class     string // the failure class, when err is set
+	err       error
+}
+
+func (q encryptedDNSQuery) String() string {
+	if q.err != nil {
+		return fmt.Sprintf("%s %s failure: %v", q.endpoint, q.class, q.err)
+	}
+	return fmt.Sprintf("%s connect %.1fms, handshake %.1fms, query %.1fms %s", q.endpoint,
+		durationMillis(q.connect), durationMillis(q.handshake), durationMillis(q.query), rcodeName(q.rcode))
+}
+
+func (q encryptedDNSQuery) details() map[string]any {
+	d := map[string]any{"endpoint": q.endpoint}
+	if q.connect > 0 {
+		d["connect_ms"] = durationMillis(q.connect)
+	}
+	if q.handshake > 0 {
+		d["handshake_ms"] = durationMillis(q.handshake)
+	}
+	if q.err != nil {
+		d["failure"] = q.class
+		d["error"] = q.err.Error()
+		return d
+	}
+	d["query_ms"] = durationMillis(q.query)
+	d["rcode"] = rcodeName(q.rcode)
+	return d
+}
+
+func (c *encryptedDNSCheck) Run(ctx context.Context) Result {
+	queries := make([]encryptedDNSQuery, len(c.endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range c.endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if c.client != nil {
+				queries[i] = c.queryDoH(ctx, endpoint)
+			} else {
+				queries[i] = c.queryDoT(ctx, endpoint)
+			}
+			q := &queries[i]
+			// NXDOMAIN counts: the resolver works even if it does not know the domain
+			if q.err == nil && q.rcode != dnsmessage.RCodeSuccess && q.rcode != dnsmessage.RCodeNameError {
+				q.class, q.err = dnsFailRCode, fmt.Errorf("answered %s", rcodeName(q.rcode))
+			}
+		}()
+	}
+	wg.Wait()
+
+	return c.result(queries)
+}
+
+// result fails the check when no endpoint answered and degrades it when
+// some did not.
+func (c *encryptedDNSCheck) result(queries []encryptedDNSQuery) Result {
+	parts := make([]string, len(queries))
+	details := make([]map[string]any, len(queries))
+	var failed, classes []string
+	var total time.Duration
+	for i, q := range queries {
+		parts[i] = q.String()
+		details[i] = q.details()
+		if q.err != nil {
+			failed = append(failed, q.endpoint)
+			classes = append(classes, q.class)
+			continue
+		}
+		total += q.connect + q.handshake + q.query
+	}
+
+	r := Result{
+		Status:  StatusOK,
+		Target:  c.domain,
+		Details: map[string]any{"queries": details},
+	}
+	if answered := len(queries) - len(failed); answered > 0 {
+		r.Latency = total / time.Duration(answered)
+	}
+	summary := strings.Join(parts, "; ")
+	switch {
+	case len(failed) == len(queries):
+		r.Status, r.Reason = StatusFail, c.prefix+"_failed"
+		r.Details["failure"] = classes[0]
+		r.Message = fmt.Sprintf("✗ %s FAILED: %s", c.label, summary)
+	case len(failed) > 0:
+		r.Status, r.Reason = StatusWarn, c.prefix+"_endpoint_failed"
+		r.Details["failure"] = classes[0]
+		r.Message = fmt.Sprintf("⚠ %s endpoint %s not answering: %s", c.label, strings.Join(failed, ", "), summary)
+	default:
+		r.Message = fmt.Sprintf("✓ %s working: %s", c.label, summary)
+	}
+	return r
+}
+
+// queryDoT sends the query over TLS, framed as over TCP (RFC 7858).
+func (c *encryptedDNSCheck) queryDoT(ctx context.Context, endpoint string) encryptedDNSQuery {
+	q := encryptedDNSQuery{endpoint: endpoint}
+	id := uint16(rand.UintN(1 << 16))
+	packed, err := packDNSQuery(c.domain, id)
+	if err != nil {
+		q.class, q.err = dnsFailQuery, err
+		return q
+	}
+
+	start := time.Now()
+	raw, err := newDialer(c.uplink, "tcp", 0).DialContext(ctx, "tcp", endpoint)
+	if err != nil {
+		q.class, q.err = dialFailureClass(err), shortNetError(err)
+		return q
+	}
+	q.connect = time.Since(start)
+	defer func() { _ = raw.Close() }()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = raw.SetDeadline(deadline)
+	}
+
+	host, _, _ := net.SplitHostPort(endpoint)
+	tlsConfig := &tls.Config{}
+	if c.tlsConfig != nil {
+		tlsConfig = c.tlsConfig.Clone()
+	}
+	tlsConfig.ServerName = host
+	conn := tls.Client(raw, tlsConfig)
+	start = time.Now()
+	if err := conn.HandshakeContext(ctx); err != nil {
+		q.class, q.err = dnsFailTLS, shortNetError(err)
+		return q
+	}
+	q.handshake = time.Since(start)
+
+	start = time.Now()
+	resp, err := exchangeTCP(conn, packed, id)
+	q.query = time.Since(start)
+	if err != nil {
+		q.class, q.err = dnsFailQuery, err
+		return q
+	}
+	q.rcode = resp.rcode
+	return q
+}
+
+// queryDoH posts the query to the endpoint (RFC 8484). The ID is 0, as the
+// RFC recommends, since HTTP already matches the response to the request.
+func (c *encryptedDNSCheck) queryDoH(ctx context.Context, endpoint string) encryptedDNSQuery {
+	q := encryptedDNSQuery{endpoint: endpoint}
+	packed, err := packDNSQuery(c.domain, 0)
+	if err != nil {
+		q.class, q.err = dnsFailQuery, err
+		return q
+	}
+
+	trace, traceCtx := newHTTPTrace(ctx)
+	req, err := http.NewRequestWithContext(traceCtx, "POST", endpoint, bytes.NewReader(packed))
+	if err != nil {
+		q.class, q.err = dnsFailQuery, err
+		return q
+	}
+	req.Header.Set("Content-Type", dnsMessageType)
+	req.Header.Set("Accept", dnsMessageType)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		t := trace.timing()
+		q.connect, q.handshake = t.connect, t.handshake
+		q.class, q.err = httpFailureClass(t.stage, err), shortNetError(unwrapURLError(err))
+		return q
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	t := trace.timing()
+	q.connect, q.handshake = t.connect, t.handshake
+	q.query = time.Since(t.gotConn)
+
+	switch {
+	case err != nil:
+		q.class, q.err = dnsFailQuery, shortNetError(err)
+	case resp.StatusCode != http.StatusOK:
+		q.class, q.err = dnsFailHTTP, fmt.Errorf("HTTP %s", resp.Status)
+	case !strings.HasPrefix(resp.Header.Get("Content-Type"), dnsMessageType):
+		q.class, q.err = dnsFailQuery, fmt.Errorf("response is %q, not a DNS message", resp.Header.Get("Content-Type"))
+	default:
+		if r, ok := parseDNSResponse(body, 0); ok {
+			q.rcode = r.rcode
+		} else {
+			q.class, q.err = dnsFailQuery, errors.New("malformed response")
+		}
+	}
+	return q
+}
+
+// dialFailureClass tells a name that does not resolve from an address that
+// cannot be connected to.
+func dialFailureClass(err error) string {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsFailResolve
+	}
+	return dnsFailConnect
+}
+
+// httpFailureClass returns the failure class of a request that failed with
+// err in stage.
+func httpFailureClass(stage string, err error) string {
+	switch stage {
+	case stageResolve, stageConnect:
+		return dialFailureClass(err)
+	case stageTLS:
+		return dnsFailTLS
+	}
+	return dnsFailQuery
+}
+
+// unwrapURLError strips the method and URL from an error of http.Client,
+// which the message already names.
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,238 @@
+package monitor
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// selfSignedCert returns a certificate for 127.0.0.1 named commonName and a
+// pool that trusts it.
+func selfSignedCert(t *testing.T, commonName string) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+// newTestEncryptedDNSCheck builds a check of typ ("doh" or "dot") that
+// resolves example.com with endpoint.
+func newTestEncryptedDNSCheck(t *testing.T, typ, endpoint string) *encryptedDNSCheck {
+	t.Helper()
+	c, err := NewCheck(CheckConfig{Name: typ, Type: typ, Params: map[string]any{
+		"domain":    "example.com",
+		"endpoints": []string{endpoint},
+	}}, CheckEnv{Watchdog: DefaultConfig().Watchdog})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.(*encryptedDNSCheck)
+}
+
+func runEncryptedDNSCheck(t *testing.T, c *encryptedDNSCheck) (Result, map[string]any) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	r := c.Run(ctx)
+	queries, ok := r.Details["queries"].([]map[string]any)
+	if !ok || len(queries) != 1 {
+		t.Fatalf("queries = %v, want one", r.Details["queries"])
+	}
+	return r, queries[0]
+}
+
+// checkEncryptedDNSResult checks r against the failure class want, or
+// success and the timing of every stage when want is empty.
+func checkEncryptedDNSResult(t *testing.T, r Result, query map[string]any, prefix, want string) {
+	t.Helper()
+	if want == "" {
+		if r.Status != StatusOK {
+			t.Fatalf("Status = %s (%s), want ok", r.Status, r.Message)
+		}
+		for _, key := range []string{"connect_ms", "handshake_ms", "query_ms"} {
+			if ms, _ := query[key].(float64); ms <= 0 {
+				t.Errorf("%s = %v, want a duration", key, query[key])
+			}
+		}
+		if r.Latency <= 0 {
+			t.Errorf("Latency = %v, want a duration", r.Latency)
+		}
+		return
+	}
+	if r.Status != StatusFail || r.Reason != prefix+"_failed" || r.Details["failure"] != want || query["failure"] != want {
+		t.Fatalf("result = %s %s failure %v (%s), want fail %s_failed failure %s",
+			r.Status, r.Reason, r.Details["failure"], r.Message, prefix, want)
+	}
+	if !strings.Contains(r.Message, want+" failure") {
+		t.Errorf("Message = %q, want the failure class", r.Message)
+	}
+}
+
+func TestDoTCheck(t *testing.T) {
+	cert, pool := selfSignedCert(t, "resolver.test")
+	response := packDNSResponse(t, 0, dnsmessage.RCodeSuccess, dnsAnswer(t, &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}}))
+	// answer returns the response to query, framed for TCP and cut to n bytes
+	answer := func(query []byte, n int) []byte {
+		resp := append([]byte(nil), response...)
+		copy(resp, query[:2]) // the ID
+		framed := binary.BigEndian.AppendUint16(nil, uint16(len(resp)))
+		framed = append(framed, resp...)
+		return framed[:min(n, len(framed))]
+	}
+
+	for _, tc := range []struct {
+		name    string
+		trust   bool
+		respond func(query []byte) []byte
+		want    string
+	}{
+		{name: "success", trust: true, respond: func(q []byte) []byte { return answer(q, 1<<16) }},
+		{name: "untrusted certificate", respond: func(q []byte) []byte { return answer(q, 1<<16) }, want: dnsFailTLS},
+		{name: "truncated response", trust: true, respond: func(q []byte) []byte { return answer(q, 20) }, want: dnsFailQuery},
+		{name: "malformed response", trust: true, want: dnsFailQuery, respond: func([]byte) []byte {
+			return append(binary.BigEndian.AppendUint16(nil, 5), "hello"...)
+		}},
+		{name: "no response", trust: true, respond: func([]byte) []byte { return nil }, want: dnsFailQuery},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = ln.Close() }()
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer func() { _ = conn.Close() }()
+				var length [2]byte
+				if _, err := io.ReadFull(conn, length[:]); err != nil {
+					return
+				}
+				query := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, query); err != nil {
+					return
+				}
+				_, _ = conn.Write(tc.respond(query))
+			}()
+
+			c := newTestEncryptedDNSCheck(t, "dot", ln.Addr().String())
+			if tc.trust {
+				c.tlsConfig = &tls.Config{RootCAs: pool}
+			}
+			r, query := runEncryptedDNSCheck(t, c)
+			checkEncryptedDNSResult(t, r, query, "dot", tc.want)
+		})
+	}
+}
+
+func TestDoTCheckConnectFailure(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	r, query := runEncryptedDNSCheck(t, newTestEncryptedDNSCheck(t, "dot", addr))
+	checkEncryptedDNSResult(t, r, query, "dot", dnsFailConnect)
+	if _, ok := query["handshake_ms"]; ok {
+		t.Errorf("handshake_ms = %v without a connection", query["handshake_ms"])
+	}
+}
+
+func TestDoHCheck(t *testing.T) {
+	answer := dnsAnswer(t, &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}})
+	dnsReply := func(msg []byte) http.HandlerFunc {
+		return func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", dnsMessageType)
+			_, _ = w.Write(msg)
+		}
+	}
+
+	for _, tc := range []struct {
+		name    string
+		untrust bool
+		handler http.HandlerFunc
+		want    string
+	}{
+		{name: "success", handler: dnsReply(packDNSResponse(t, 0, dnsmessage.RCodeSuccess, answer))},
+		{name: "nxdomain", handler: dnsReply(packDNSResponse(t, 0, dnsmessage.RCodeNameError))},
+		{name: "untrusted certificate", untrust: true, handler: dnsReply(packDNSResponse(t, 0, dnsmessage.RCodeSuccess, answer)),
+			want: dnsFailTLS},
+		{name: "error status", want: dnsFailHTTP, handler: func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+		}},
+		{name: "not a dns message", want: dnsFailQuery, handler: func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = io.WriteString(w, "<html>blocked</html>")
+		}},
+		{name: "malformed response", handler: dnsReply([]byte("hello")), want: dnsFailQuery},
+		{name: "truncated response", handler: dnsReply(packDNSResponse(t, 0, dnsmessage.RCodeSuccess, answer)[:8]),
+			want: dnsFailQuery},
+		{name: "server failure", handler: dnsReply(packDNSResponse(t, 0, dnsmessage.RCodeServerFailure)), want: dnsFailRCode},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if r.Method != http.MethodPost || r.Header.Get("Content-Type") != dnsMessageType ||
+					len(body) < 2 || binary.BigEndian.Uint16(body) != 0 {
+					http.Error(w, "bad query", http.StatusBadRequest)
+					return
+				}
+				tc.handler(w, r)
+			}))
+			srv.Config.ErrorLog = log.New(io.Discard, "", 0) // failed handshakes
+			srv.StartTLS()
+			defer srv.Close()
+
+			c := newTestEncryptedDNSCheck(t, "doh", srv.URL+"/dns-query")
+			if !tc.untrust {
+				pool := x509.NewCertPool()
+				pool.AddCert(srv.Certificate())
+				c.client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: pool}
+			}
+			r, query := runEncryptedDNSCheck(t, c)
+			checkEncryptedDNSResult(t, r, query, "doh", tc.want)
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Event categories as they appear in the log.
+const (
+	CategorySystem   = "SYSTEM"
+	CategoryLink     = "LINK"
+	CategoryAddress  = "ADDRESS"
+	CategoryRoute    = "ROUTE"
+	CategoryDNS      = "DNS"
+	CategoryTCP      = "TCP"
+	CategoryWatchdog = "WATCHDOG"
+	CategoryMonitor  = "MONITOR"
+	CategoryState    = "STATE"
+	CategoryOutage   = "OUTAGE"
+	CategoryEcho     = "ECHO"
+	CategoryUplink   = "UPLINK"
+	CategoryIP       = "IP"
+)
+
+// Event sources identify the monitor that emitted an event.
+const (
+	SourceMonitor  = "monitor"
+	SourceSystem   = "system"
+	SourceTCP      = "tcp"
+	SourceWatchdog = "watchdog"
+	SourceEcho     = "echo"
+)
+
+// Severity ranks how important an event is.
+type Severity int
+
+// Supported severities, from least to most important. SeverityInfo is the
+// zero value so events that do not set a severity are informational.
+const (
+	SeverityDebug Severity = iota - 1
+	SeverityInfo
+	SeverityWarn
+	SeverityError
+	SeverityCritical
+)
+
+// String returns the upper-case name of the severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityDebug:
+		return "DEBUG"
+	case SeverityInfo:
+		return "INFO"
+	case SeverityWarn:
+		return "WARN"
+	case SeverityError:
+		return "ERROR"
+	case SeverityCritical:
+		return "CRITICAL"
+	default:
+		return fmt.Sprintf("SEVERITY(%d)", int(s))
+	}
+}
+
+// ParseSeverity converts a severity name such as "WARN" back into a Severity.
+func ParseSeverity(name string) (Severity, error) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "DEBUG":
+		return SeverityDebug, nil
+	case "INFO":
+		return SeverityInfo, nil
+	case "WARN", "WARNING":
+		return SeverityWarn, nil
+	case "ERROR":
+		return SeverityError, nil
+	case "CRITICAL":
+		return SeverityCritical, nil
+	default:
+		return SeverityInfo, fmt.Errorf("unknown severity %q", name)
+	}
+}
+
+// Signal names the aspect of connectivity an event reports on.
+type Signal string
+
+// Connectivity signals consumed by the StateTracker.
+const (
+	SignalLink  Signal = "link"
+	SignalRoute Signal = "route"
+	SignalTCP   Signal = "tcp"
+	// SignalTCPHealth warns while a keepalive connection is retransmitting.
+	SignalTCPHealth Signal = "tcp_health"
+	SignalDNS       Signal = "dns"
+	SignalHTTP      Signal = "http"
+	// SignalNameservers warns while some of the configured nameservers do
+	// not answer, even though the resolver still gets answers from others.
+	SignalNameservers Signal = "nameservers"
+	// SignalCaptivePortal fails while a captive portal intercepts HTTP or
+	// TLS.
+	SignalCaptivePortal Signal = "captive_portal"
+
+	// The IPv6 counterparts of the route, TCP and HTTP signals.
+	SignalRoute6     Signal = "route6"
+	SignalTCP6       Signal = "tcp6"
+	SignalTCPHealth6 Signal = "tcp_health6"
+	SignalHTTP6      Signal = "http6"
+)
+
+// Status is the outcome an event reports for its signal.
+type Status string
+
+// Signal outcomes. StatusWarn marks a signal that works but not as expected.
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Event is a single structured observation emitted by one of the monitors.
+// Message is the human-readable summary; the remaining fields carry the same
+// information in a form that can be consumed without parsing text. Events
+// that report on connectivity set Signal and Status, and Reason with a short
+// machine-readable code (e.g. "tcp_read_error") when Status is not ok.
+type Event struct {
+	Time      time.Time
+	Category  string
+	Severity  Severity
+	Source    string
+	Message   string
+	Signal    Signal
+	Status    Status
+	Reason    string
+	Interface string
+	Address   string
+	Gateway   string
+	Target    string
+	// Uplink names the uplink the probe that emitted the event is pinned to.
+	Uplink   string
+	Duration time.Duration
+	Err      error
+	Fields   map[string]any
+}
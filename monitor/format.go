@@ -0,0 +1,216 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Supported log formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Formatter renders an event as a single log line.
+type Formatter interface {
+	Format(e Event) string
+}
+
+// NewFormatter returns the formatter for the named log format.
+func NewFormatter(format string) (Formatter, error) {
+	switch format {
+	case "", FormatText:
+		return TextFormatter{}, nil
+	case FormatJSON:
+		return JSONFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (expected %q or %q)", format, FormatText, FormatJSON)
+	}
+}
+
+const timestampLayout = "2006-01-02 15:04:05.000"
+
+// TextFormatter renders events as "[timestamp] [CATEGORY] [SEVERITY] message",
+// with "[uplink NAME] " before the message of events from pinned probes.
+type TextFormatter struct{}
+
+// Format implements Formatter.
+func (TextFormatter) Format(e Event) string {
+	msg := e.Message
+	if e.Uplink != "" {
+		msg = fmt.Sprintf("[uplink %s] %s", e.Uplink, msg)
+	}
+	return fmt.Sprintf("[%s] [%s] [%s] %s", e.Time.Format(timestampLayout), e.Category, e.Severity, msg)
+}
+
+// JSONFormatter renders events as one JSON object per line.
+type JSONFormatter struct{}
+
+// reservedJSONKeys are the top-level keys owned by Event fields; free-form
+// fields with the same name are dropped rather than overwriting them.
+var reservedJSONKeys = map[string]bool{
+	"ts": true, "category": true, "severity": true, "source": true, "message": true,
+	"iface": true, "address": true, "gateway": true, "target": true, "duration_ms": true, "error": true,
+	"signal": true, "status": true, "reason": true, "uplink": true,
+}
+
+// Format implements Formatter.
+func (JSONFormatter) Format(e Event) string {
+	obj := make(map[string]any, len(e.Fields)+8)
+	for k, v := range e.Fields {
+		if !reservedJSONKeys[k] {
+			obj[k] = v
+		}
+	}
+
+	obj["ts"] = e.Time.Format(time.RFC3339Nano)
+	obj["category"] = e.Category
+	obj["severity"] = e.Severity.String()
+	obj["message"] = plainMessage(e.Message)
+	setIfNotEmpty(obj, "source", e.Source)
+	setIfNotEmpty(obj, "signal", string(e.Signal))
+	setIfNotEmpty(obj, "status", string(e.Status))
+	setIfNotEmpty(obj, "reason", e.Reason)
+	setIfNotEmpty(obj, "iface", e.Interface)
+	setIfNotEmpty(obj, "address", e.Address)
+	setIfNotEmpty(obj, "gateway", e.Gateway)
+	setIfNotEmpty(obj, "target", e.Target)
+	setIfNotEmpty(obj, "uplink", e.Uplink)
+	if e.Duration > 0 {
+		obj["duration_ms"] = durationMillis(e.Duration)
+	}
+	if e.Err != nil {
+		obj["error"] = e.Err.Error()
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		// Fields hold arbitrary values; fall back to a line that is still valid JSON.
+		data, _ = json.Marshal(map[string]any{
+			"ts":       obj["ts"],
+			"category": e.Category,
+			"severity": e.Severity.String(),
+			"message":  obj["message"],
+			"error":    fmt.Sprintf("failed to encode event: %v", err),
+		})
+	}
+	return string(data)
+}
+
+func setIfNotEmpty(obj map[string]any, key, value string) {
+	if value != "" {
+		obj[key] = value
+	}
+}
+
+// durationMillis converts d to fractional milliseconds rounded to microseconds.
+func durationMillis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// plainMessage strips the leading status glyphs used by the text format.
+func plainMessage(msg string) string {
+	for _, glyph := range []string{"✓ ", "✗ ", "⚠ "} {
+		if strings.HasPrefix(msg, glyph) {
+			return strings.TrimPrefix(msg, glyph)
+		}
+	}
+	return msg
+}
+
+// textLinePattern matches text lines; the severity tag is optional because
+// logs written before severities existed do not have one.
+var textLinePattern = regexp.MustCompile(
+	`^\[([^\]]+)\] \[([A-Z_]+)\] (?:\[(DEBUG|INFO|WARN|ERROR|CRITICAL)\] )?(.*)$`)
+
+// ParseLine decodes a log line written in either the text or the JSON format.
+// It reports false when the line is in neither format.
+func ParseLine(line string) (Event, bool) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "{") {
+		return parseJSONLine(line)
+	}
+	return parseTextLine(line)
+}
+
+func parseTextLine(line string) (Event, bool) {
+	m := textLinePattern.FindStringSubmatch(line)
+	if m == nil {
+		return Event{}, false
+	}
+
+	ts, err := time.ParseInLocation(timestampLayout, m[1], time.Local)
+	if err != nil {
+		return Event{}, false
+	}
+
+	e := Event{
+		Time:     ts,
+		Category: m[2],
+		Message:  m[4],
+	}
+	if sev, err := ParseSeverity(m[3]); err == nil {
+		e.Severity = sev
+	}
+	return e, true
+}
+
+func parseJSONLine(line string) (Event, bool) {
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(line), &obj); err != nil {
+		return Event{}, false
+	}
+
+	ts, err := time.Parse(time.RFC3339Nano, stringField(obj, "ts"))
+	if err != nil {
+		return Event{}, false
+	}
+
+	e := Event{
+		Time:      ts,
+		Category:  stringField(obj, "category"),
+		Source:    stringField(obj, "source"),
+		Message:   stringField(obj, "message"),
+		Interface: stringField(obj, "iface"),
+		Address:   stringField(obj, "address"),
+		Gateway:   stringField(obj, "gateway"),
+		Target:    stringField(obj, "target"),
+		Signal:    Signal(stringField(obj, "signal")),
+		Status:    Status(stringField(obj, "status")),
+		Reason:    stringField(obj, "reason"),
+		Uplink:    stringField(obj, "uplink"),
+	}
+	if e.Category == "" {
+		return Event{}, false
+	}
+	if sev, err := ParseSeverity(stringField(obj, "severity")); err == nil {
+		e.Severity = sev
+	}
+	if ms, ok := obj["duration_ms"].(float64); ok {
+		e.Duration = time.Duration(ms * float64(time.Millisecond))
+	}
+	if msg := stringField(obj, "error"); msg != "" {
+		e.Err = errors.New(msg)
+	}
+
+	for k, v := range obj {
+		if reservedJSONKeys[k] {
+			continue
+		}
+		if e.Fields == nil {
+			e.Fields = make(map[string]any)
+		}
+		e.Fields[k] = v
+	}
+
+	return e, true
+}
+
+func stringField(obj map[string]any, key string) string {
+	s, _ := obj[key].(string)
+	return s
+}
@@ -0,0 +1,69 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Heartbeat frames are 16 bytes: the magic "NSLH", a big-endian sequence
+// number and the sender's clock in Unix nanoseconds. The echo server sends
+// every frame back unchanged.
+const heartbeatFrameSize = 16
+
+// heartbeatMagic starts every frame so a target that is not an echo server,
+// or a middlebox answering in its place, is detected rather than trusted.
+var heartbeatMagic = []byte("NSLH")
+
+// encodeHeartbeat builds the frame for heartbeat seq sent at sent.
+func encodeHeartbeat(seq uint32, sent time.Time) []byte {
+	frame := make([]byte, heartbeatFrameSize)
+	copy(frame, heartbeatMagic)
+	binary.BigEndian.PutUint32(frame[4:8], seq)
+	binary.BigEndian.PutUint64(frame[8:16], uint64(sent.UnixNano()))
+	return frame
+}
+
+// decodeHeartbeat parses a frame built by encodeHeartbeat.
+func decodeHeartbeat(frame []byte) (seq uint32, sent time.Time, err error) {
+	if len(frame) != heartbeatFrameSize || !bytes.Equal(frame[:4], heartbeatMagic) {
+		return 0, time.Time{}, fmt.Errorf("not a heartbeat frame: %x", frame)
+	}
+	seq = binary.BigEndian.Uint32(frame[4:8])
+	sent = time.Unix(0, int64(binary.BigEndian.Uint64(frame[8:16])))
+	return seq, sent, nil
+}
+
+// exchangeHeartbeat sends heartbeat seq over conn and waits up to timeout
+// for its echo. It returns the round-trip time.
+func exchangeHeartbeat(conn net.Conn, seq uint32, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	_ = conn.SetDeadline(start.Add(timeout))
+	defer func() { _ = conn.SetDeadline(time.Time{}) }()
+
+	if _, err := conn.Write(encodeHeartbeat(seq, start)); err != nil {
+		return 0, fmt.Errorf("failed to send heartbeat %d: %w", seq, err)
+	}
+
+	reply := make([]byte, heartbeatFrameSize)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return 0, fmt.Errorf("heartbeat %d not echoed within %v", seq, timeout)
+		}
+		return 0, fmt.Errorf("failed to read heartbeat %d echo: %w", seq, err)
+	}
+	// RTT uses the monotonic clock; the echoed timestamp only identifies the frame
+	rtt := time.Since(start)
+
+	gotSeq, sent, err := decodeHeartbeat(reply)
+	if err != nil {
+		return 0, err
+	}
+	if gotSeq != seq || !sent.Equal(time.Unix(0, start.UnixNano())) {
+		return 0, fmt.Errorf("heartbeat %d echoed as %d", seq, gotSeq)
+	}
+	return rtt, nil
+}
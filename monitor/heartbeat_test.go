@@ -0,0 +1,144 @@
+package monitor
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatFrame(t *testing.T) {
+	sent := time.Date(2025, 12, 12, 10, 0, 0, 123456789, time.UTC)
+	frame := encodeHeartbeat(0xdeadbeef, sent)
+	if len(frame) != heartbeatFrameSize || string(frame[:4]) != "NSLH" {
+		t.Fatalf("encodeHeartbeat() = %x, want 16 bytes starting with NSLH", frame)
+	}
+	seq, got, err := decodeHeartbeat(frame)
+	if err != nil || seq != 0xdeadbeef || !got.Equal(sent) {
+		t.Fatalf("decodeHeartbeat() = %d, %v, %v; want %d, %v", seq, got, err, uint32(0xdeadbeef), sent)
+	}
+
+	badMagic := append([]byte(nil), frame...)
+	copy(badMagic, "HTTP")
+	for name, frame := range map[string][]byte{
+		"bad magic": badMagic,
+		"short":     frame[:15],
+		"long":      append(append([]byte(nil), frame...), 0),
+		"empty":     nil,
+	} {
+		if _, _, err := decodeHeartbeat(frame); err == nil || !strings.Contains(err.Error(), "not a heartbeat frame") {
+			t.Errorf("decodeHeartbeat(%s) error = %v, want not a heartbeat frame", name, err)
+		}
+	}
+}
+
+// startEchoServer runs an echo server on a loopback port until the test
+// ends and returns its address.
+func startEchoServer(t *testing.T) string {
+	t.Helper()
+	logger, err := NewLogger(filepath.Join(t.TempDir(), "echo.log"), LoggerOptions{MinLevel: SeverityCritical + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listening := make(chan string, 1)
+	logger.AddHook(func(e Event) {
+		if e.Address != "" {
+			listening <- e.Address
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- NewEchoServer(ctx, logger, "127.0.0.1:0", 5*time.Second).Run() }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run() = %v", err)
+		}
+		_ = logger.Close()
+	})
+
+	select {
+	case addr := <-listening:
+		return addr
+	case err := <-done:
+		t.Fatalf("Run() = %v before listening", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("echo server did not start listening")
+	}
+	return ""
+}
+
+func TestExchangeHeartbeatEchoServer(t *testing.T) {
+	addr := startEchoServer(t)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	for seq := uint32(1); seq <= 3; seq++ {
+		rtt, err := exchangeHeartbeat(conn, seq, 5*time.Second)
+		if err != nil {
+			t.Fatalf("exchangeHeartbeat(%d) = %v", seq, err)
+		}
+		if rtt <= 0 || rtt > 5*time.Second {
+			t.Errorf("exchangeHeartbeat(%d) RTT = %v", seq, rtt)
+		}
+	}
+
+	// The server hangs up on anything that is not a heartbeat
+	if _, err := conn.Write([]byte("GET / HTTP/1.1\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if n, err := conn.Read(make([]byte, heartbeatFrameSize)); err != io.EOF {
+		t.Errorf("Read() after a bad frame = %d, %v; want EOF", n, err)
+	}
+}
+
+func TestExchangeHeartbeatBadEcho(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		reply   func(frame []byte) []byte // nil for no reply
+		wantErr string
+	}{
+		{name: "other sequence number", wantErr: "heartbeat 7 echoed as 8", reply: func(frame []byte) []byte {
+			binary.BigEndian.PutUint32(frame[4:8], 8)
+			return frame
+		}},
+		{name: "other timestamp", wantErr: "heartbeat 7 echoed as 7", reply: func(frame []byte) []byte {
+			binary.BigEndian.PutUint64(frame[8:16], 1)
+			return frame
+		}},
+		{name: "bad magic", wantErr: "not a heartbeat frame", reply: func(frame []byte) []byte {
+			copy(frame, "HTTP")
+			return frame
+		}},
+		{name: "no reply", wantErr: "heartbeat 7 not echoed within 100ms"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer func() { _ = client.Close() }()
+			go func() {
+				defer func() { _ = server.Close() }()
+				frame := make([]byte, heartbeatFrameSize)
+				if _, err := io.ReadFull(server, frame); err != nil || tc.reply == nil {
+					// Hold the connection open until the client gives up
+					_, _ = io.Copy(io.Discard, server)
+					return
+				}
+				_, _ = server.Write(tc.reply(frame))
+			}()
+
+			_, err := exchangeHeartbeat(client, 7, 100*time.Millisecond)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("exchangeHeartbeat() error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
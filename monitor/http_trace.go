@@ -0,0 +1,174 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stages of an HTTP request, in the order a request on a new connection
+// goes through them.
+const (
+	stageResolve = "resolve"
+	stageConnect = "connect"
+	stageTLS     = "tls"
+	stageRequest = "request" // connected, waiting for the response
+)
+
+// httpTrace records the stages of one HTTP request, so that a failure can
+// be attributed to the stage it happened in and a success broken down into
+// the time each stage took. The transport calls back from its dialing
+// goroutines, which may outlive a failed request, hence the mutex.
+type httpTrace struct {
+	mu           sync.Mutex
+	stage        string
+	dnsStart     time.Time
+	dns          time.Duration
+	connectStart time.Time
+	connect      time.Duration
+	tlsStart     time.Time
+	handshake    time.Duration
+	gotConn      time.Time
+	reused       bool
+	wroteRequest time.Time
+	ttfb         time.Duration
+}
+
+// newHTTPTrace returns a trace and a context that makes the requests made
+// with it report to the trace.
+func newHTTPTrace(ctx context.Context) (*httpTrace, context.Context) {
+	t := &httpTrace{stage: stageResolve}
+	return t, httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if info.Err == nil {
+				t.dns = time.Since(t.dnsStart)
+			}
+		},
+		ConnectStart: func(_, _ string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			// Dual-stack dialing starts a second connect for the fallback
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+				t.stage = stageConnect
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil && t.connect == 0 {
+				t.connect = time.Since(t.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsStart = time.Now()
+			t.stage = stageTLS
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil {
+				t.handshake = time.Since(t.tlsStart)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.gotConn = time.Now()
+			t.reused = info.Reused
+			t.stage = stageRequest
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if !t.wroteRequest.IsZero() {
+				t.ttfb = time.Since(t.wroteRequest)
+			}
+		},
+	})
+}
+
+// httpTiming is what a trace recorded, copied out of it. A stage that did
+// not happen, such as DNS for an address or everything up to the request
+// on a reused connection, has no duration.
+type httpTiming struct {
+	stage     string // the last stage the request reached
+	dns       time.Duration
+	connect   time.Duration
+	handshake time.Duration
+	gotConn   time.Time
+	reused    bool
+	ttfb      time.Duration // from writing the request to the first byte of the response
+}
+
+// timing returns what the trace has recorded so far.
+func (t *httpTrace) timing() httpTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return httpTiming{
+		stage:     t.stage,
+		dns:       t.dns,
+		connect:   t.connect,
+		handshake: t.handshake,
+		gotConn:   t.gotConn,
+		reused:    t.reused,
+		ttfb:      t.ttfb,
+	}
+}
+
+// httpStageTime is how long one stage of a request took.
+type httpStageTime struct {
+	name string
+	d    time.Duration
+}
+
+// parts returns the durations of the stages that happened.
+func (t httpTiming) parts() []httpStageTime {
+	all := []httpStageTime{{"dns", t.dns}, {"connect", t.connect}, {"tls", t.handshake}, {"ttfb", t.ttfb}}
+	parts := all[:0]
+	for _, p := range all {
+		if p.d > 0 {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+// String returns the breakdown, e.g. "dns 12.0ms, connect 20.1ms, tls
+// 45.2ms, ttfb 80.3ms".
+func (t httpTiming) String() string {
+	var parts []string
+	for _, p := range t.parts() {
+		parts = append(parts, fmt.Sprintf("%s %.1fms", p.name, durationMillis(p.d)))
+	}
+	if t.reused {
+		parts = append(parts, "reused connection")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// addDetails adds the breakdown to the details of a result.
+func (t httpTiming) addDetails(details map[string]any) {
+	for _, p := range t.parts() {
+		details[p.name+"_ms"] = durationMillis(p.d)
+	}
+	details["conn_reused"] = t.reused
+}
@@ -0,0 +1,146 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPTiming(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		timing     httpTiming
+		want       string
+		wantSuffix string
+		wantKeys   []string
+	}{
+		{name: "nothing timed", want: "", wantSuffix: ""},
+		{
+			name: "new connection",
+			timing: httpTiming{dns: 12 * time.Millisecond, connect: 20100 * time.Microsecond,
+				handshake: 45200 * time.Microsecond, ttfb: 80300 * time.Microsecond},
+			want:       "dns 12.0ms, connect 20.1ms, tls 45.2ms, ttfb 80.3ms",
+			wantSuffix: ", dns 12.0ms, connect 20.1ms, tls 45.2ms, ttfb 80.3ms",
+			wantKeys:   []string{"dns_ms", "connect_ms", "tls_ms", "ttfb_ms"},
+		},
+		{
+			name:       "address over plain HTTP",
+			timing:     httpTiming{connect: time.Millisecond, ttfb: 2 * time.Millisecond},
+			want:       "connect 1.0ms, ttfb 2.0ms",
+			wantSuffix: ", connect 1.0ms, ttfb 2.0ms",
+			wantKeys:   []string{"connect_ms", "ttfb_ms"},
+		},
+		{
+			name:       "reused connection",
+			timing:     httpTiming{reused: true, ttfb: 5 * time.Millisecond},
+			want:       "ttfb 5.0ms, reused connection",
+			wantSuffix: ", ttfb 5.0ms, reused connection",
+			wantKeys:   []string{"ttfb_ms"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.timing.String(); got != tc.want {
+				t.Errorf("String() = %q, want %q", got, tc.want)
+			}
+			if got := timingSuffix(tc.timing); got != tc.wantSuffix {
+				t.Errorf("timingSuffix() = %q, want %q", got, tc.wantSuffix)
+			}
+			details := map[string]any{}
+			tc.timing.addDetails(details)
+			if len(details) != len(tc.wantKeys)+1 || details["conn_reused"] != tc.timing.reused {
+				t.Errorf("addDetails() = %v, want %v and conn_reused", details, tc.wantKeys)
+			}
+			for _, key := range tc.wantKeys {
+				if ms, _ := details[key].(float64); ms <= 0 {
+					t.Errorf("%s = %v, want a duration", key, details[key])
+				}
+			}
+		})
+	}
+}
+
+// newTimingTestCheck returns an http check of srv by the name localhost, so
+// the request goes through every stage, trusting the server certificate
+// unless untrusted.
+func newTimingTestCheck(t *testing.T, srv *httptest.Server, untrusted bool) Check {
+	t.Helper()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewCheck(CheckConfig{Name: "http", Type: "http", Timeout: 5 * time.Second, Params: map[string]any{
+		"url": "https://localhost:" + u.Port() + "/",
+	}}, CheckEnv{Watchdog: DefaultConfig().Watchdog})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !untrusted {
+		pool := x509.NewCertPool()
+		pool.AddCert(srv.Certificate())
+		// The test certificate names example.com and the loopback addresses
+		c.(*httpCheck).client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{
+			RootCAs:    pool,
+			ServerName: "example.com",
+		}
+	}
+	return c
+}
+
+func TestHTTPCheckTiming(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(delay)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0) // failed handshakes
+	srv.StartTLS()
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	took := regexp.MustCompile(`, dns [\d.]+ms, connect [\d.]+ms, tls [\d.]+ms, ttfb [\d.]+ms \(took [^)]+\)$`)
+
+	t.Run("success", func(t *testing.T) {
+		r := newTimingTestCheck(t, srv, false).Run(ctx)
+		if r.Status != StatusOK {
+			t.Fatalf("Status = %q (%s), want ok", r.Status, r.Message)
+		}
+		// The report reads the total from "(took ...)" at the end
+		if !took.MatchString(r.Message) {
+			t.Errorf("Message = %q, want every stage followed by (took ...)", r.Message)
+		}
+		if sample, ok := latencySample(Event{Message: r.Message}); !ok || sample.Latency <= 0 {
+			t.Errorf("latencySample() of %q = %+v, %v; want the total", r.Message, sample, ok)
+		}
+		for _, key := range []string{"dns_ms", "connect_ms", "tls_ms", "ttfb_ms"} {
+			if ms, _ := r.Details[key].(float64); ms <= 0 {
+				t.Errorf("%s = %v, want a duration", key, r.Details[key])
+			}
+		}
+		if ttfb, _ := r.Details["ttfb_ms"].(float64); ttfb < durationMillis(delay) {
+			t.Errorf("ttfb_ms = %v, want at least the %v the server took", ttfb, delay)
+		}
+	})
+
+	t.Run("tls failure", func(t *testing.T) {
+		r := newTimingTestCheck(t, srv, true).Run(ctx)
+		if r.Status != StatusFail || r.Details["stage"] != stageTLS {
+			t.Fatalf("result = %q in stage %v (%s), want fail in %s", r.Status, r.Details["stage"], r.Message, stageTLS)
+		}
+		if !strings.Contains(r.Message, "FAILED in tls after dns ") || !tookMessagePattern.MatchString(r.Message) {
+			t.Errorf("Message = %q, want the stage, the time spent before it and (took ...)", r.Message)
+		}
+		if _, ok := r.Details["ttfb_ms"]; ok {
+			t.Errorf("ttfb_ms = %v without a response", r.Details["ttfb_ms"])
+		}
+	})
+}
@@ -10,16 +10,38 @@ import (
 	"time"
 )
 
-// Logger writes categorized log messages to a file and stdout.
+// Logger writes structured events to a file and stdout.
 type Logger struct {
-	mu      sync.Mutex
-	file    *os.File
-	logger  *log.Logger
-	logPath string
+	mu        sync.Mutex
+	file      *rotatingFile
+	logger    *log.Logger
+	logPath   string
+	formatter Formatter
+	console   Formatter
+	minLevel  Severity
+	levels    map[string]Severity
+	hooks     []func(Event)
+}
+
+// LoggerOptions controls how a Logger renders events.
+type LoggerOptions struct {
+	// Format is the on-disk log format: FormatText (default) or FormatJSON.
+	Format string
+	// Rotation configures size- and time-based rotation of the log file.
+	Rotation RotationOptions
+	// MinLevel drops events below this severity (SeverityInfo by default).
+	MinLevel Severity
+	// CategoryLevels overrides MinLevel for individual categories.
+	CategoryLevels map[string]Severity
 }
 
 // NewLogger creates a new Logger writing to the provided log path.
-func NewLogger(logPath string) (*Logger, error) {
+func NewLogger(logPath string, opts LoggerOptions) (*Logger, error) {
+	formatter, err := NewFormatter(opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
 	logPath = filepath.Clean(logPath)
 	// Create logs directory if it doesn't exist
 	dir := filepath.Dir(logPath)
@@ -27,28 +49,59 @@ func NewLogger(logPath string) (*Logger, error) {
 		return nil, fmt.Errorf("failed to create log directory: %w", err)
 	}
 
-	file, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	file, err := openRotatingFile(logPath, opts.Rotation)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open log file: %w", err)
+		return nil, err
 	}
 
 	return &Logger{
-		file:    file,
-		logger:  log.New(file, "", 0),
-		logPath: logPath,
+		file:      file,
+		logger:    log.New(file, "", 0),
+		logPath:   logPath,
+		formatter: formatter,
+		console:   TextFormatter{},
+		minLevel:  opts.MinLevel,
+		levels:    opts.CategoryLevels,
 	}, nil
 }
 
-// Log writes a single line with timestamp and category.
-func (l *Logger) Log(category, message string) {
+// AddHook registers a function that receives every emitted event, including
+// events below the minimum level. Hooks run on the emitting goroutine after
+// the event is written and may emit events themselves.
+func (l *Logger) AddHook(hook func(Event)) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05.000")
-	logLine := fmt.Sprintf("[%s] [%s] %s", timestamp, category, message)
+	l.hooks = append(l.hooks, hook)
+}
+
+// Emit renders the event with the configured formatter and writes it,
+// unless its severity is below the minimum level for its category.
+// The console copy always uses the text format. A zero Time is replaced
+// with the current time.
+func (l *Logger) Emit(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
 
-	l.logger.Println(logLine)
-	fmt.Println(logLine) // Also print to console
+	l.mu.Lock()
+	if e.Severity >= l.minLevelFor(e.Category) {
+		l.logger.Println(l.formatter.Format(e))
+		fmt.Println(l.console.Format(e)) // Also print to console
+	}
+	hooks := l.hooks
+	l.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook(e)
+	}
+}
+
+func (l *Logger) minLevelFor(category string) Severity {
+	if level, ok := l.levels[category]; ok {
+		return level
+	}
+	return l.minLevel
 }
 
 // Close flushes and closes the underlying file handle.
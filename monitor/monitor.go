@@ -6,6 +6,17 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Messages marking the start and end of a monitoring run; the report
+// relies on them to tell monitored time from downtime of the monitor itself.
+const (
+	monitorStartMessage = "=== Network Stability Monitor Starting ==="
+	monitorStopMessage  = "=== Network Stability Monitor Stopping ==="
 )
 
 // NetworkMonitor coordinates system, TCP, and watchdog monitors.
@@ -15,31 +26,81 @@ type NetworkMonitor struct {
 	cancel     context.CancelFunc
 	sysEvents  *SystemEventsMonitor
 	tcpMonitor *TCPKeepaliveMonitor
+	tcp6       *TCPKeepaliveMonitor
 	watchdog   *WatchdogMonitor
+	state      *StateTracker
+	started    time.Time
+	cfgMu      sync.Mutex
+	cfg        *Config
+	uplinks    []*UplinkMonitor
 }
 
-// NewNetworkMonitor constructs a monitor with the given log path.
-func NewNetworkMonitor(logPath string) (*NetworkMonitor, error) {
-	logger, err := NewLogger(logPath)
+// NewNetworkMonitor constructs a monitor with the given log path, logger
+// options and monitor configuration.
+func NewNetworkMonitor(logPath string, logOpts LoggerOptions, cfg *Config) (*NetworkMonitor, error) {
+	logger, err := NewLogger(logPath, logOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create logger: %w", err)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &NetworkMonitor{
+	// The state tracker consumes the signals every monitor emits
+	state := NewStateTracker(ctx, logger, NewOutageClassifier(cfg))
+	logger.AddHook(state.HandleEvent)
+
+	// Down TCP targets are retried as soon as the network changes
+	tcpMonitor := NewTCPKeepaliveMonitor(ctx, logger, cfg.TCP)
+	logger.AddHook(tcpMonitor.HandleEvent)
+	tcp6 := newIPv6TCPKeepaliveMonitor(ctx, logger, cfg.TCP.IPv6())
+	logger.AddHook(tcp6.HandleEvent)
+
+	nm := &NetworkMonitor{
 		logger:     logger,
 		ctx:        ctx,
 		cancel:     cancel,
-		sysEvents:  NewSystemEventsMonitor(ctx, logger),
-		tcpMonitor: NewTCPKeepaliveMonitor(ctx, logger),
-		watchdog:   NewWatchdogMonitor(ctx, logger),
-	}, nil
+		sysEvents:  NewSystemEventsMonitor(ctx, logger, cfg.System),
+		tcpMonitor: tcpMonitor,
+		tcp6:       tcp6,
+		watchdog:   NewWatchdogMonitor(ctx, logger, cfg.Watchdog, cfg.System.ResolvConf),
+		cfg:        cfg,
+		state:      state,
+	}
+	nm.uplinks = nm.newUplinkMonitors(cfg)
+	logger.AddHook(nm.handleUplinkEvent)
+	return nm, nil
+}
+
+// newUplinkMonitors constructs the pinned probes of every uplink in cfg.
+func (nm *NetworkMonitor) newUplinkMonitors(cfg *Config) []*UplinkMonitor {
+	uplinks := make([]*UplinkMonitor, 0, len(cfg.Uplinks))
+	for _, u := range cfg.Uplinks {
+		uplinks = append(uplinks, NewUplinkMonitor(nm.ctx, nm.logger, u, cfg))
+	}
+	return uplinks
+}
+
+// handleUplinkEvent passes events to the current uplink monitors, which
+// are replaced when a reload changes the uplinks.
+func (nm *NetworkMonitor) handleUplinkEvent(e Event) {
+	nm.cfgMu.Lock()
+	uplinks := nm.uplinks
+	nm.cfgMu.Unlock()
+
+	for _, u := range uplinks {
+		u.HandleEvent(e)
+	}
 }
 
 // Start begins all monitoring routines.
 func (nm *NetworkMonitor) Start() error {
-	nm.logger.Log("MONITOR", "=== Network Stability Monitor Starting ===")
+	nm.started = time.Now()
+	nm.emit(monitorStartMessage)
+	if nm.cfg.Path != "" {
+		nm.emit(fmt.Sprintf("Loaded configuration from %s", nm.cfg.Path))
+	} else {
+		nm.emit("Using built-in default configuration")
+	}
 
 	// Start all three goroutines
 	if err := nm.sysEvents.Start(); err != nil {
@@ -50,18 +111,28 @@ func (nm *NetworkMonitor) Start() error {
 		return fmt.Errorf("failed to start TCP keepalive monitor: %w", err)
 	}
 
+	if err := nm.tcp6.Start(); err != nil {
+		return fmt.Errorf("failed to start IPv6 TCP keepalive monitor: %w", err)
+	}
+
 	if err := nm.watchdog.Start(); err != nil {
 		return fmt.Errorf("failed to start watchdog monitor: %w", err)
 	}
 
-	nm.logger.Log("MONITOR", "All monitors started successfully")
+	for _, u := range nm.uplinks {
+		if err := u.Start(); err != nil {
+			return fmt.Errorf("failed to start probes of uplink %s: %w", u.cfg.Name, err)
+		}
+	}
+
+	nm.emit("All monitors started successfully")
 
 	return nil
 }
 
 // Stop signals monitors to terminate and closes the logger.
 func (nm *NetworkMonitor) Stop() error {
-	nm.logger.Log("MONITOR", "=== Network Stability Monitor Stopping ===")
+	nm.emit(monitorStopMessage)
 
 	nm.cancel()
 
@@ -72,6 +143,150 @@ func (nm *NetworkMonitor) Stop() error {
 	return nil
 }
 
+// Reload loads a new configuration with load and applies it to the running
+// monitors. Connectivity state and open outages are kept. If load fails the
+// error is logged and returned, and the current configuration stays active.
+func (nm *NetworkMonitor) Reload(load func() (*Config, error)) error {
+	cfg, err := load()
+	if err != nil {
+		nm.logger.Emit(Event{
+			Category: CategoryMonitor,
+			Severity: SeverityError,
+			Source:   SourceMonitor,
+			Err:      err,
+			Message:  fmt.Sprintf("Configuration reload rejected, keeping current configuration: %v", err),
+		})
+		return err
+	}
+
+	nm.cfgMu.Lock()
+	prev := nm.cfg
+	nm.cfg = cfg
+	nm.cfgMu.Unlock()
+
+	nm.sysEvents.Reconfigure(cfg.System)
+	nm.tcpMonitor.Reconfigure(cfg.TCP)
+	nm.tcp6.Reconfigure(cfg.TCP.IPv6())
+	nm.watchdog.Reconfigure(cfg.Watchdog, cfg.System.ResolvConf)
+	nm.state.SetClassifier(NewOutageClassifier(cfg))
+	nm.reconfigureUplinks(prev, cfg)
+
+	source := cfg.Path
+	if source == "" {
+		source = "built-in defaults"
+	}
+	changed := "none"
+	if sections := changedSections(prev, cfg); len(sections) > 0 {
+		changed = strings.Join(sections, ", ")
+	}
+	nm.emit(fmt.Sprintf("Configuration reloaded from %s (changed: %s)", source, changed))
+	return nil
+}
+
+// reconfigureUplinks applies cfg to the uplink probes. A changed list of
+// uplinks replaces all of them; otherwise they keep running with new settings.
+func (nm *NetworkMonitor) reconfigureUplinks(prev, cfg *Config) {
+	if reflect.DeepEqual(prev.Uplinks, cfg.Uplinks) {
+		nm.cfgMu.Lock()
+		uplinks := nm.uplinks
+		nm.cfgMu.Unlock()
+		for _, u := range uplinks {
+			u.Reconfigure(cfg)
+		}
+		return
+	}
+
+	next := nm.newUplinkMonitors(cfg)
+	nm.cfgMu.Lock()
+	old := nm.uplinks
+	nm.uplinks = next
+	nm.cfgMu.Unlock()
+
+	for _, u := range old {
+		u.Stop()
+	}
+	for _, u := range next {
+		if err := u.Start(); err != nil {
+			nm.emit(fmt.Sprintf("Failed to start probes of uplink %s: %v", u.cfg.Name, err))
+		}
+	}
+}
+
+// changedSections lists the top-level config sections that differ.
+func changedSections(prev, next *Config) []string {
+	var sections []string
+	if prev.System != next.System {
+		sections = append(sections, "system")
+	}
+	if !reflect.DeepEqual(prev.TCP, next.TCP) {
+		sections = append(sections, "tcp")
+	}
+	if !reflect.DeepEqual(prev.Watchdog, next.Watchdog) {
+		sections = append(sections, "watchdog")
+	}
+	if !reflect.DeepEqual(prev.Classifier, next.Classifier) {
+		sections = append(sections, "classifier")
+	}
+	if !reflect.DeepEqual(prev.Uplinks, next.Uplinks) {
+		sections = append(sections, "uplinks")
+	}
+	return sections
+}
+
+func (nm *NetworkMonitor) emit(message string) {
+	nm.logger.Emit(Event{
+		Category: CategoryMonitor,
+		Source:   SourceMonitor,
+		Message:  message,
+	})
+}
+
+// State returns the current overall connectivity state.
+func (nm *NetworkMonitor) State() StateSnapshot {
+	return nm.state.Snapshot()
+}
+
+// Status returns the connectivity state and the state of every TCP
+// keepalive target.
+func (nm *NetworkMonitor) Status() MonitorStatus {
+	nm.cfgMu.Lock()
+	cfg := nm.cfg
+	uplinks := nm.uplinks
+	nm.cfgMu.Unlock()
+
+	snapshot := nm.state.Snapshot()
+	s := MonitorStatus{
+		PID:        os.Getpid(),
+		Started:    nm.started,
+		Updated:    time.Now(),
+		ConfigPath: cfg.Path,
+		State:      snapshot.State,
+		Since:      snapshot.Since,
+		Reasons:    snapshot.Reasons,
+		Families:   snapshot.Families,
+		TCPQuorum:  cfg.TCP.EffectiveQuorum(),
+		TCPTargets: nm.tcpMonitor.Targets(),
+	}
+	if len(cfg.TCP.Targets6) > 0 {
+		s.TCP6Quorum = cfg.TCP.IPv6().EffectiveQuorum()
+		s.TCP6Targets = nm.tcp6.Targets()
+	}
+	for _, u := range uplinks {
+		s.Uplinks = append(s.Uplinks, u.Status())
+	}
+	if o := snapshot.Outage; o != nil {
+		s.Outage = &OutageStatus{
+			ID:      o.ID,
+			Start:   o.Start,
+			Worst:   o.Worst,
+			Cause:   o.Cause,
+			Detail:  o.Detail,
+			Reasons: o.Reasons,
+		}
+	}
+	return s
+}
+
 // Wait blocks until the monitor context is canceled.
 func (nm *NetworkMonitor) Wait() {
 	<-nm.ctx.Done()
@@ -0,0 +1,360 @@
+package monitor
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func init() {
+	RegisterCheck("nameservers", newNameserversCheck)
+}
+
+// nameserverTransports are the transports every nameserver is queried over.
+// The system resolver uses UDP and retries over TCP when an answer is
+// truncated.
+var nameserverTransports = []string{"udp", "tcp"}
+
+// nameserversCheck queries every nameserver directly instead of going
+// through the system resolver, which falls back from one server to the next
+// and so hides which of them is dead.
+type nameserversCheck struct {
+	name       string
+	domain     string
+	resolvConf string
+	servers    []string // used instead of the nameservers of resolvConf
+}
+
+func newNameserversCheck(cfg CheckConfig, env CheckEnv) (Check, error) {
+	var p struct {
+		Domain     string   `yaml:"domain"`
+		ResolvConf string   `yaml:"resolv_conf"`
+		Servers    []string `yaml:"servers"`
+	}
+	if err := cfg.DecodeParams(&p); err != nil {
+		return nil, err
+	}
+	if p.Domain == "" {
+		p.Domain = env.Watchdog.DNSDomain
+	}
+	if _, err := dnsmessage.NewName(fqdn(p.Domain)); err != nil {
+		return nil, fmt.Errorf("domain: %w", err)
+	}
+	if p.ResolvConf == "" {
+		p.ResolvConf = env.resolvConf()
+	}
+	for _, server := range p.Servers {
+		if _, err := netip.ParseAddr(server); err != nil {
+			return nil, fmt.Errorf("servers: %q is not an IP address", server)
+		}
+	}
+	return &nameserversCheck{name: cfg.Name, domain: p.Domain, resolvConf: p.ResolvConf, servers: p.Servers}, nil
+}
+
+func (c *nameserversCheck) Name() string { return c.name }
+
+// checkNameservers returns servers, or the nameservers of the resolv.conf
+// at path when servers is empty. Reading the file on every run picks up
+// changes without a reload.
+func checkNameservers(servers []string, path string) ([]string, error) {
+	if len(servers) > 0 {
+		return servers, nil
+	}
+	conf, err := readResolvConf(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(conf.Nameservers) == 0 {
+		return nil, fmt.Errorf("no nameservers in %s", path)
+	}
+	return conf.Nameservers, nil
+}
+
+// nameserverQuery is the outcome of one query to one nameserver.
+type nameserverQuery struct {
+	server    string
+	transport string
+	rcode     dnsmessage.RCode
+	latency   time.Duration
+	err       error
+}
+
+// answered reports whether the server gave a real answer. NXDOMAIN counts:
+// the server works even if it does not know the domain.
+func (q nameserverQuery) answered() bool {
+	return q.err == nil && (q.rcode == dnsmessage.RCodeSuccess || q.rcode == dnsmessage.RCodeNameError)
+}
+
+func (q nameserverQuery) String() string {
+	if q.err != nil {
+		return fmt.Sprintf("%s %v", q.transport, q.err)
+	}
+	return fmt.Sprintf("%s %s %.1fms", q.transport, rcodeName(q.rcode), durationMillis(q.latency))
+}
+
+func (q nameserverQuery) details() map[string]any {
+	d := map[string]any{"server": q.server, "transport": q.transport}
+	if q.err != nil {
+		d["error"] = q.err.Error()
+	} else {
+		d["rcode"] = rcodeName(q.rcode)
+		d["latency_ms"] = durationMillis(q.latency)
+	}
+	return d
+}
+
+func (c *nameserversCheck) Run(ctx context.Context) Result {
+	servers, err := checkNameservers(c.servers, c.resolvConf)
+	if err != nil {
+		return Result{Err: err, Message: fmt.Sprintf("✗ Nameservers: %v", err)}
+	}
+
+	queries := make([]nameserverQuery, 0, len(servers)*len(nameserverTransports))
+	for _, server := range servers {
+		for _, transport := range nameserverTransports {
+			queries = append(queries, nameserverQuery{server: server, transport: transport})
+		}
+	}
+	var wg sync.WaitGroup
+	for i := range queries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q := &queries[i]
+			var resp dnsResponse
+			resp, q.latency, q.err = queryNameserver(ctx, q.transport, q.server, c.domain)
+			q.rcode = resp.rcode
+		}()
+	}
+	wg.Wait()
+
+	return c.result(servers, queries)
+}
+
+// result judges the queries, which are grouped by server in the order of
+// servers. A server is dead when it does not answer over UDP, which is what
+// the system resolver uses; TCP failures are reported but many home routers
+// do not serve DNS over TCP at all. The check fails when every server is
+// dead and is degraded when some are.
+func (c *nameserversCheck) result(servers []string, queries []nameserverQuery) Result {
+	var parts, dead []string
+	var total time.Duration
+	var answered int
+	details := make([]map[string]any, len(queries))
+	for i, server := range servers {
+		group := queries[i*len(nameserverTransports) : (i+1)*len(nameserverTransports)]
+		outcomes := make([]string, len(group))
+		for j, q := range group {
+			outcomes[j] = q.String()
+			details[i*len(nameserverTransports)+j] = q.details()
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", server, strings.Join(outcomes, ", ")))
+
+		if udp := group[0]; udp.answered() {
+			answered++
+			total += udp.latency
+		} else {
+			dead = append(dead, server)
+		}
+	}
+
+	r := Result{
+		Status:  StatusOK,
+		Target:  c.domain,
+		Details: map[string]any{"queries": details},
+	}
+	if answered > 0 {
+		r.Latency = total / time.Duration(answered)
+	}
+	summary := strings.Join(parts, "; ")
+	switch {
+	case answered == 0:
+		r.Status, r.Reason = StatusFail, "nameservers_failed"
+		r.Message = fmt.Sprintf("✗ Nameservers FAILED: %s", summary)
+	case len(dead) > 0:
+		r.Status, r.Reason = StatusWarn, "nameserver_failed"
+		r.Message = fmt.Sprintf("⚠ Nameserver %s not answering: %s", strings.Join(dead, ", "), summary)
+	default:
+		r.Message = fmt.Sprintf("✓ Nameservers: %s", summary)
+	}
+	return r
+}
+
+// dnsResponse is what a nameserver answered to a query.
+type dnsResponse struct {
+	rcode dnsmessage.RCode
+	addrs []netip.Addr // the A and AAAA records of the answer section
+}
+
+// queryNameserver sends a recursive query for the A records of domain to
+// server over network (udp or tcp) and returns the response and the time
+// until it arrived, including the TCP handshake.
+func queryNameserver(ctx context.Context, network, server, domain string) (dnsResponse, time.Duration, error) {
+	id := uint16(rand.UintN(1 << 16))
+	packed, err := packDNSQuery(domain, id)
+	if err != nil {
+		return dnsResponse{}, 0, err
+	}
+
+	start := time.Now()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, net.JoinHostPort(server, "53"))
+	if err != nil {
+		return dnsResponse{}, time.Since(start), shortNetError(err)
+	}
+	defer func() { _ = conn.Close() }()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	var resp dnsResponse
+	if network == "tcp" {
+		resp, err = exchangeTCP(conn, packed, id)
+	} else {
+		resp, err = exchangeUDP(conn, packed, id)
+	}
+	return resp, time.Since(start), err
+}
+
+// packDNSQuery returns a recursive query with id for the A records of
+// domain in wire format.
+func packDNSQuery(domain string, id uint16) ([]byte, error) {
+	name, err := dnsmessage.NewName(fqdn(domain))
+	if err != nil {
+		return nil, err
+	}
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack query: %w", err)
+	}
+	return packed, nil
+}
+
+// exchangeUDP sends the query and reads datagrams until the response to it
+// arrives; stray or late datagrams are skipped.
+func exchangeUDP(conn net.Conn, query []byte, id uint16) (dnsResponse, error) {
+	if _, err := conn.Write(query); err != nil {
+		return dnsResponse{}, shortNetError(err)
+	}
+	buf := make([]byte, 1232)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return dnsResponse{}, shortNetError(err)
+		}
+		if resp, ok := parseDNSResponse(buf[:n], id); ok {
+			return resp, nil
+		}
+	}
+}
+
+// exchangeTCP sends the query and reads the response, both prefixed with
+// their length as RFC 1035 requires for TCP.
+func exchangeTCP(conn net.Conn, query []byte, id uint16) (dnsResponse, error) {
+	msg := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+	if _, err := conn.Write(append(msg, query...)); err != nil {
+		return dnsResponse{}, shortNetError(err)
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return dnsResponse{}, shortNetError(err)
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return dnsResponse{}, shortNetError(err)
+	}
+	resp, ok := parseDNSResponse(buf, id)
+	if !ok {
+		return dnsResponse{}, errors.New("malformed response")
+	}
+	return resp, nil
+}
+
+// parseDNSResponse parses msg if it is the response to the query with id.
+// Records after one that cannot be parsed are ignored.
+func parseDNSResponse(msg []byte, id uint16) (dnsResponse, bool) {
+	var p dnsmessage.Parser
+	h, err := p.Start(msg)
+	if err != nil || !h.Response || h.ID != id {
+		return dnsResponse{}, false
+	}
+	resp := dnsResponse{rcode: h.RCode}
+	if err := p.SkipAllQuestions(); err != nil {
+		return resp, true
+	}
+	for {
+		rh, err := p.AnswerHeader()
+		if err != nil {
+			return resp, true
+		}
+		switch rh.Type {
+		case dnsmessage.TypeA:
+			r, err := p.AResource()
+			if err != nil {
+				return resp, true
+			}
+			resp.addrs = append(resp.addrs, netip.AddrFrom4(r.A))
+		case dnsmessage.TypeAAAA:
+			r, err := p.AAAAResource()
+			if err != nil {
+				return resp, true
+			}
+			resp.addrs = append(resp.addrs, netip.AddrFrom16(r.AAAA))
+		default:
+			if err := p.SkipAnswer(); err != nil {
+				return resp, true
+			}
+		}
+	}
+}
+
+// shortNetError strips the operation and addresses from a network error,
+// which the message already names, e.g. "i/o timeout".
+func shortNetError(err error) error {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return opErr.Err
+	}
+	return err
+}
+
+// fqdn returns domain with the trailing dot a DNS name needs.
+func fqdn(domain string) string {
+	if strings.HasSuffix(domain, ".") {
+		return domain
+	}
+	return domain + "."
+}
+
+// rcodeName returns the conventional name of an rcode, e.g. NXDOMAIN.
+func rcodeName(rcode dnsmessage.RCode) string {
+	switch rcode {
+	case dnsmessage.RCodeSuccess:
+		return "NOERROR"
+	case dnsmessage.RCodeFormatError:
+		return "FORMERR"
+	case dnsmessage.RCodeServerFailure:
+		return "SERVFAIL"
+	case dnsmessage.RCodeNameError:
+		return "NXDOMAIN"
+	case dnsmessage.RCodeNotImplemented:
+		return "NOTIMP"
+	case dnsmessage.RCodeRefused:
+		return "REFUSED"
+	}
+	return fmt.Sprintf("RCODE%d", rcode)
+}
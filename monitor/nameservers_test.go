@@ -0,0 +1,188 @@
+package monitor
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"slices"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestNewNameserversCheckResolvConf(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		params map[string]any
+		env    CheckEnv
+		want   string
+	}{
+		{name: "system resolv.conf", env: CheckEnv{ResolvConf: "/run/systemd/resolve/resolv.conf"},
+			want: "/run/systemd/resolve/resolv.conf"},
+		{name: "parameter", params: map[string]any{"resolv_conf": "/tmp/resolv.conf"},
+			env: CheckEnv{ResolvConf: "/run/systemd/resolve/resolv.conf"}, want: "/tmp/resolv.conf"},
+		{name: "unset", want: defaultResolvConf},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.env.Watchdog = DefaultConfig().Watchdog
+			c, err := NewCheck(CheckConfig{Name: "ns", Type: "nameservers", Params: tc.params}, tc.env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.(*nameserversCheck).resolvConf; got != tc.want {
+				t.Errorf("resolvConf = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// dnsAnswer returns an answer record of the test domain.
+func dnsAnswer(t *testing.T, body dnsmessage.ResourceBody) dnsmessage.Resource {
+	t.Helper()
+	var typ dnsmessage.Type
+	switch body.(type) {
+	case *dnsmessage.AResource:
+		typ = dnsmessage.TypeA
+	case *dnsmessage.AAAAResource:
+		typ = dnsmessage.TypeAAAA
+	case *dnsmessage.CNAMEResource:
+		typ = dnsmessage.TypeCNAME
+	default:
+		t.Fatalf("unexpected record %T", body)
+	}
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("example.com."), Type: typ, Class: dnsmessage.ClassINET, TTL: 60},
+		Body:   body,
+	}
+}
+
+// packDNSResponse returns a response to the query with id for example.com.
+func packDNSResponse(t *testing.T, id uint16, rcode dnsmessage.RCode, answers ...dnsmessage.Resource) []byte {
+	t.Helper()
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: id, Response: true, RecursionAvailable: true, RCode: rcode},
+		Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName("example.com."),
+			Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+		Answers: answers,
+	}
+	packed, err := msg.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return packed
+}
+
+func TestParseDNSResponse(t *testing.T) {
+	a := dnsAnswer(t, &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}})
+	aaaa := dnsAnswer(t, &dnsmessage.AAAAResource{AAAA: netip.MustParseAddr("2001:db8::1").As16()})
+	cname := dnsAnswer(t, &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("cdn.example.net.")})
+
+	complete := packDNSResponse(t, 7, dnsmessage.RCodeSuccess, a, aaaa)
+	query, err := packDNSQuery("example.com", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		msg       []byte
+		ok        bool
+		wantRCode dnsmessage.RCode
+		wantAddrs []string
+	}{
+		{name: "answers", msg: complete, ok: true, wantAddrs: []string{"192.0.2.1", "2001:db8::1"}},
+		{name: "cname skipped", msg: packDNSResponse(t, 7, dnsmessage.RCodeSuccess, cname, a), ok: true,
+			wantAddrs: []string{"192.0.2.1"}},
+		{name: "nxdomain", msg: packDNSResponse(t, 7, dnsmessage.RCodeNameError), ok: true,
+			wantRCode: dnsmessage.RCodeNameError},
+		{name: "truncated answer keeps earlier records", msg: complete[:len(complete)-4], ok: true,
+			wantAddrs: []string{"192.0.2.1"}},
+		{name: "other id", msg: packDNSResponse(t, 8, dnsmessage.RCodeSuccess, a)},
+		{name: "query", msg: query},
+		{name: "truncated header", msg: complete[:5]},
+		{name: "empty"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, ok := parseDNSResponse(tc.msg, 7)
+			if ok != tc.ok {
+				t.Fatalf("parseDNSResponse() ok = %v, want %v", ok, tc.ok)
+			}
+			var addrs []string
+			for _, addr := range resp.addrs {
+				addrs = append(addrs, addr.String())
+			}
+			if resp.rcode != tc.wantRCode || !slices.Equal(addrs, tc.wantAddrs) {
+				t.Errorf("parseDNSResponse() = %s %v, want %s %v", rcodeName(resp.rcode), addrs,
+					rcodeName(tc.wantRCode), tc.wantAddrs)
+			}
+		})
+	}
+}
+
+func TestExchangeTCP(t *testing.T) {
+	response := packDNSResponse(t, 7, dnsmessage.RCodeSuccess, dnsAnswer(t, &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}}))
+	prefixed := func(msg []byte) []byte {
+		return append(binary.BigEndian.AppendUint16(nil, uint16(len(msg))), msg...)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		writes  [][]byte // what the server writes after reading the query
+		wantErr string
+	}{
+		{name: "response", writes: [][]byte{prefixed(response)}},
+		{name: "response in pieces", writes: [][]byte{prefixed(response)[:1], prefixed(response)[1:10], prefixed(response)[10:]}},
+		{name: "malformed response", writes: [][]byte{prefixed([]byte("not a dns message"))}, wantErr: "malformed response"},
+		{name: "closed within the length", writes: [][]byte{{0}}, wantErr: io.ErrUnexpectedEOF.Error()},
+		{name: "closed within the message", writes: [][]byte{prefixed(response)[:20]}, wantErr: io.ErrUnexpectedEOF.Error()},
+		{name: "closed before answering", wantErr: io.EOF.Error()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := packDNSQuery("example.com", 7)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client, server := net.Pipe()
+			defer func() { _ = client.Close() }()
+
+			received := make(chan []byte, 1)
+			go func() {
+				defer func() { _ = server.Close() }()
+				var length [2]byte
+				if _, err := io.ReadFull(server, length[:]); err != nil {
+					received <- nil
+					return
+				}
+				msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(server, msg); err != nil {
+					received <- nil
+					return
+				}
+				received <- msg
+				for _, w := range tc.writes {
+					if _, err := server.Write(w); err != nil {
+						return
+					}
+				}
+			}()
+
+			resp, err := exchangeTCP(client, query, 7)
+			if got := <-received; !slices.Equal(got, query) {
+				t.Fatalf("server received %x, want the query %x", got, query)
+			}
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("exchangeTCP() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.addrs) != 1 || resp.addrs[0] != netip.MustParseAddr("192.0.2.1") {
+				t.Errorf("exchangeTCP() addrs = %v, want 192.0.2.1", resp.addrs)
+			}
+		})
+	}
+}
@@ -0,0 +1,403 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+func init() {
+	RegisterCheck("ping", newPingCheck)
+}
+
+// Roles of ping targets. Comparing them tells problems on the local network
+// (e.g. WiFi) from problems upstream (e.g. the ISP).
+const (
+	pingRoleGateway  = "gateway"
+	pingRoleExternal = "external"
+)
+
+// pingCheck sends a burst of ICMP echo requests to the default gateway and
+// to external hosts and reports loss, round-trip times and jitter for each.
+type pingCheck struct {
+	name     string
+	hosts    []string
+	gateway  bool
+	count    int
+	spacing  time.Duration
+	lossWarn float64
+}
+
+func newPingCheck(cfg CheckConfig, _ CheckEnv) (Check, error) {
+	p := struct {
+		Hosts    []string      `yaml:"hosts"`
+		Gateway  bool          `yaml:"gateway"`
+		Count    int           `yaml:"count"`
+		Spacing  time.Duration `yaml:"spacing"`
+		LossWarn float64       `yaml:"loss_warn"`
+	}{
+		Gateway:  true,
+		Count:    5,
+		Spacing:  200 * time.Millisecond,
+		LossWarn: 20,
+	}
+	if err := cfg.DecodeParams(&p); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(p.Hosts) == 0 && !p.Gateway:
+		return nil, errors.New("hosts must list at least one host when gateway is false")
+	case p.Count < 1:
+		return nil, fmt.Errorf("count must be at least 1, got %d", p.Count)
+	case p.Spacing <= 0:
+		return nil, fmt.Errorf("spacing must be a positive duration, got %v", p.Spacing)
+	case p.LossWarn <= 0 || p.LossWarn > 100:
+		return nil, fmt.Errorf("loss_warn must be a percentage above 0, got %v", p.LossWarn)
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+	if burst := time.Duration(p.Count-1) * p.Spacing; burst >= timeout {
+		return nil, fmt.Errorf("a burst of %d pings %v apart takes %v, leaving no time for replies within the timeout of %v",
+			p.Count, p.Spacing, burst, timeout)
+	}
+
+	return &pingCheck{
+		name:     cfg.Name,
+		hosts:    p.Hosts,
+		gateway:  p.Gateway,
+		count:    p.Count,
+		spacing:  p.Spacing,
+		lossWarn: p.LossWarn,
+	}, nil
+}
+
+func (c *pingCheck) Name() string { return c.name }
+
+// pingTarget is one host to ping.
+type pingTarget struct {
+	host string
+	role string
+}
+
+// pingStats summarizes the burst sent to one target.
+type pingStats struct {
+	pingTarget
+	sent     int
+	received int
+	min      time.Duration
+	avg      time.Duration
+	max      time.Duration
+	jitter   time.Duration // mean difference between consecutive round-trip times
+	err      error
+}
+
+func (s pingStats) loss() float64 {
+	if s.sent == 0 {
+		return 100
+	}
+	return 100 * float64(s.sent-s.received) / float64(s.sent)
+}
+
+func (s pingStats) String() string {
+	name := s.host
+	if s.role == pingRoleGateway {
+		name = "gateway " + s.host
+	}
+	switch {
+	case s.err != nil:
+		return fmt.Sprintf("%s: %v", name, s.err)
+	case s.received == 0:
+		return fmt.Sprintf("%s: 100%% loss", name)
+	}
+	return fmt.Sprintf("%s: %.0f%% loss, rtt min/avg/max %.2f/%.2f/%.2f ms, jitter %.2f ms", name, s.loss(),
+		durationMillis(s.min), durationMillis(s.avg), durationMillis(s.max), durationMillis(s.jitter))
+}
+
+func (s pingStats) details() map[string]any {
+	d := map[string]any{
+		"host":     s.host,
+		"role":     s.role,
+		"sent":     s.sent,
+		"received": s.received,
+		"loss_pct": s.loss(),
+	}
+	if s.received > 0 {
+		d["min_ms"] = durationMillis(s.min)
+		d["avg_ms"] = durationMillis(s.avg)
+		d["max_ms"] = durationMillis(s.max)
+		d["jitter_ms"] = durationMillis(s.jitter)
+	}
+	if s.err != nil {
+		d["error"] = s.err.Error()
+	}
+	return d
+}
+
+func (c *pingCheck) Run(ctx context.Context) Result {
+	var targets []pingTarget
+	if c.gateway {
+		// The gateway is only known where the routing table can be read
+		if found, gw, err := readDefaultRoute(); err == nil && found && gw != nil {
+			targets = append(targets, pingTarget{host: gw.String(), role: pingRoleGateway})
+		}
+	}
+	for _, host := range c.hosts {
+		targets = append(targets, pingTarget{host: host, role: pingRoleExternal})
+	}
+	if len(targets) == 0 {
+		return Result{Severity: SeverityWarn.Override(), Message: "Ping: no default gateway found and no hosts configured"}
+	}
+
+	stats := make([]pingStats, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stats[i] = c.ping(ctx, t)
+		}()
+	}
+	wg.Wait()
+
+	return c.result(stats)
+}
+
+// result judges the bursts: failed when no target answered, degraded when
+// a target lost at least lossWarn percent. The reason tells loss already
+// on the way to the gateway from loss beyond it.
+func (c *pingCheck) result(stats []pingStats) Result {
+	var parts []string
+	var gatewayLoss, externalLoss, answered int
+	var total time.Duration
+	var replies int
+	targets := make([]map[string]any, len(stats))
+	for i, s := range stats {
+		parts = append(parts, s.String())
+		targets[i] = s.details()
+		if s.received > 0 {
+			answered++
+			total += s.avg * time.Duration(s.received)
+			replies += s.received
+		}
+		if s.loss() >= c.lossWarn {
+			if s.role == pingRoleGateway {
+				gatewayLoss++
+			} else {
+				externalLoss++
+			}
+		}
+	}
+
+	r := Result{
+		Status:  StatusOK,
+		Details: map[string]any{"targets": targets},
+	}
+	if replies > 0 {
+		r.Latency = total / time.Duration(replies)
+	}
+
+	// An error on every target (typically no permission for ICMP sockets)
+	// says nothing about the network
+	var errs int
+	for _, s := range stats {
+		if s.err != nil {
+			errs++
+		}
+	}
+	switch {
+	case errs == len(stats):
+		r.Status = ""
+		r.Err = stats[0].err
+		r.Message = fmt.Sprintf("✗ Ping failed: %s", strings.Join(parts, "; "))
+		return r
+	case answered == 0:
+		r.Status, r.Reason = StatusFail, "ping_failed"
+	case gatewayLoss > 0:
+		r.Status, r.Reason = StatusWarn, "ping_gateway_loss"
+	case externalLoss > 0:
+		r.Status, r.Reason = StatusWarn, "ping_external_loss"
+	}
+
+	switch r.Status {
+	case StatusOK:
+		r.Message = "✓ Ping: " + strings.Join(parts, "; ")
+	case StatusWarn:
+		r.Message = "⚠ Ping loss: " + strings.Join(parts, "; ")
+	default:
+		r.Message = "✗ Ping FAILED: " + strings.Join(parts, "; ")
+	}
+	return r
+}
+
+// ping sends the burst to one target and collects the replies until all
+// have arrived or ctx expires.
+func (c *pingCheck) ping(ctx context.Context, t pingTarget) pingStats {
+	stats := pingStats{pingTarget: t}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, t.host)
+	if err != nil {
+		stats.err = fmt.Errorf("failed to resolve: %w", err)
+		return stats
+	}
+	ip := addrs[0].IP
+
+	conn, privileged, err := listenICMP(ip.To4() == nil)
+	if err != nil {
+		stats.err = err
+		return stats
+	}
+	defer func() { _ = conn.Close() }()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	var dst net.Addr = &net.UDPAddr{IP: ip}
+	var request icmp.Type = ipv4.ICMPTypeEcho
+	proto := 1 // ICMP
+	if privileged {
+		dst = &net.IPAddr{IP: ip}
+	}
+	if ip.To4() == nil {
+		request, proto = ipv6.ICMPTypeEchoRequest, 58 // ICMPv6
+	}
+
+	// Datagram sockets get the ID from the kernel; raw sockets see every
+	// reply on the host and must match it
+	id := rand.IntN(0xffff)
+	sentAt := make([]time.Time, c.count)
+	var mu sync.Mutex
+
+	sending := make(chan struct{})
+	go func() {
+		defer close(sending)
+		ticker := time.NewTicker(c.spacing)
+		defer ticker.Stop()
+		for seq := range c.count {
+			msg := icmp.Message{Type: request, Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("network-monitor")}}
+			data, err := msg.Marshal(nil)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			sentAt[seq] = time.Now()
+			stats.sent++
+			mu.Unlock()
+			if _, err := conn.WriteTo(data, dst); err != nil {
+				return
+			}
+			if seq < c.count-1 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}
+	}()
+
+	var rtts []time.Duration
+	received := make([]bool, c.count)
+	buf := make([]byte, 1500)
+	for len(rtts) < c.count {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		now := time.Now()
+		if privileged && !sameIP(from, ip) {
+			continue
+		}
+		msg, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil || (msg.Type != ipv4.ICMPTypeEchoReply && msg.Type != ipv6.ICMPTypeEchoReply) {
+			continue
+		}
+		echo, ok := msg.Body.(*icmp.Echo)
+		if !ok || echo.Seq < 0 || echo.Seq >= c.count || received[echo.Seq] || (privileged && echo.ID != id) {
+			continue
+		}
+		mu.Lock()
+		sent := sentAt[echo.Seq]
+		mu.Unlock()
+		if sent.IsZero() {
+			continue
+		}
+		received[echo.Seq] = true
+		rtts = append(rtts, now.Sub(sent))
+	}
+
+	// Replies still outstanding when the check ended count as lost
+	_ = conn.Close()
+	<-sending
+	stats.received = len(rtts)
+	summarizeRTTs(&stats, rtts)
+	return stats
+}
+
+// summarizeRTTs fills in min, avg, max and jitter from the round-trip times
+// in the order the replies arrived.
+func summarizeRTTs(s *pingStats, rtts []time.Duration) {
+	if len(rtts) == 0 {
+		return
+	}
+	s.min, s.max = rtts[0], rtts[0]
+	var sum, diffs time.Duration
+	for i, rtt := range rtts {
+		sum += rtt
+		s.min = min(s.min, rtt)
+		s.max = max(s.max, rtt)
+		if i > 0 {
+			d := rtt - rtts[i-1]
+			if d < 0 {
+				d = -d
+			}
+			diffs += d
+		}
+	}
+	s.avg = sum / time.Duration(len(rtts))
+	if len(rtts) > 1 {
+		s.jitter = diffs / time.Duration(len(rtts)-1)
+	}
+}
+
+// listenICMP opens an ICMP socket: an unprivileged datagram socket where
+// the system allows it (Linux with net.ipv4.ping_group_range, macOS), a raw
+// socket otherwise, which needs root or CAP_NET_RAW. privileged reports
+// which one was opened.
+func listenICMP(v6 bool) (conn *icmp.PacketConn, privileged bool, err error) {
+	dgram, raw, addr := "udp4", "ip4:icmp", "0.0.0.0"
+	if v6 {
+		dgram, raw, addr = "udp6", "ip6:ipv6-icmp", "::"
+	}
+	conn, dgramErr := icmp.ListenPacket(dgram, addr)
+	if dgramErr == nil {
+		return conn, false, nil
+	}
+	conn, err = icmp.ListenPacket(raw, addr)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open ICMP socket (%v; raw: %w); allow unprivileged ping with "+
+			"sysctl net.ipv4.ping_group_range or run as root", dgramErr, err)
+	}
+	return conn, true, nil
+}
+
+func sameIP(addr net.Addr, ip net.IP) bool {
+	switch a := addr.(type) {
+	case *net.IPAddr:
+		return a.IP.Equal(ip)
+	case *net.UDPAddr:
+		return a.IP.Equal(ip)
+	}
+	return false
+}
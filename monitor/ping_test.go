@@ -0,0 +1,144 @@
+package monitor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarizeRTTs(t *testing.T) {
+	ms := func(v ...int) []time.Duration {
+		rtts := make([]time.Duration, len(v))
+		for i, n := range v {
+			rtts[i] = time.Duration(n) * time.Millisecond
+		}
+		return rtts
+	}
+	for _, tc := range []struct {
+		name                string
+		rtts                []time.Duration
+		wantMin, wantAvg    time.Duration
+		wantMax, wantJitter time.Duration
+	}{
+		{name: "no replies"},
+		{name: "one reply", rtts: ms(12), wantMin: 12 * time.Millisecond, wantAvg: 12 * time.Millisecond,
+			wantMax: 12 * time.Millisecond},
+		{name: "steady", rtts: ms(10, 10, 10), wantMin: 10 * time.Millisecond, wantAvg: 10 * time.Millisecond,
+			wantMax: 10 * time.Millisecond},
+		// Differences 10, 20 and 30 in arrival order, not sorted order
+		{name: "jittery", rtts: ms(20, 10, 30, 0), wantMin: 0, wantAvg: 15 * time.Millisecond,
+			wantMax: 30 * time.Millisecond, wantJitter: 20 * time.Millisecond},
+		{name: "rising", rtts: ms(10, 20, 30, 40), wantMin: 10 * time.Millisecond, wantAvg: 25 * time.Millisecond,
+			wantMax: 40 * time.Millisecond, wantJitter: 10 * time.Millisecond},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var s pingStats
+			summarizeRTTs(&s, tc.rtts)
+			if s.min != tc.wantMin || s.avg != tc.wantAvg || s.max != tc.wantMax || s.jitter != tc.wantJitter {
+				t.Errorf("summarizeRTTs() = min %v avg %v max %v jitter %v, want %v %v %v %v",
+					s.min, s.avg, s.max, s.jitter, tc.wantMin, tc.wantAvg, tc.wantMax, tc.wantJitter)
+			}
+		})
+	}
+}
+
+func TestPingCheckResult(t *testing.T) {
+	// burst returns the stats of a target that got received of sent replies
+	// after avg milliseconds each
+	burst := func(role string, sent, received, avg int) pingStats {
+		s := pingStats{pingTarget: pingTarget{host: role + ".example", role: role}, sent: sent, received: received}
+		if received > 0 {
+			d := time.Duration(avg) * time.Millisecond
+			s.min, s.avg, s.max = d, d, d
+		}
+		return s
+	}
+	failed := func(role, err string) pingStats {
+		return pingStats{pingTarget: pingTarget{host: role + ".example", role: role}, err: errors.New(err)}
+	}
+
+	for _, tc := range []struct {
+		name        string
+		stats       []pingStats
+		wantStatus  Status
+		wantReason  string
+		wantLatency time.Duration // mean over every reply
+		wantMessage string        // prefix
+	}{
+		{
+			name:        "all answered",
+			stats:       []pingStats{burst(pingRoleGateway, 5, 5, 2), burst(pingRoleExternal, 5, 5, 20)},
+			wantStatus:  StatusOK,
+			wantLatency: 11 * time.Millisecond,
+			wantMessage: "✓ Ping: gateway gateway.example: 0% loss",
+		},
+		{
+			name:        "loss below the threshold",
+			stats:       []pingStats{burst(pingRoleExternal, 10, 9, 20)},
+			wantStatus:  StatusOK,
+			wantLatency: 20 * time.Millisecond,
+			wantMessage: "✓ Ping: external.example: 10% loss",
+		},
+		{
+			name:        "external loss",
+			stats:       []pingStats{burst(pingRoleGateway, 5, 5, 2), burst(pingRoleExternal, 5, 4, 20)},
+			wantStatus:  StatusWarn,
+			wantReason:  "ping_external_loss",
+			wantLatency: 10 * time.Millisecond,
+			wantMessage: "⚠ Ping loss: ",
+		},
+		{
+			name:        "gateway loss explains external loss",
+			stats:       []pingStats{burst(pingRoleGateway, 5, 2, 2), burst(pingRoleExternal, 5, 2, 20)},
+			wantStatus:  StatusWarn,
+			wantReason:  "ping_gateway_loss",
+			wantLatency: 11 * time.Millisecond,
+			wantMessage: "⚠ Ping loss: gateway gateway.example: 60% loss",
+		},
+		{
+			name:        "nothing answered",
+			stats:       []pingStats{burst(pingRoleGateway, 5, 0, 0), burst(pingRoleExternal, 5, 0, 0)},
+			wantStatus:  StatusFail,
+			wantReason:  "ping_failed",
+			wantMessage: "✗ Ping FAILED: gateway gateway.example: 100% loss; external.example: 100% loss",
+		},
+		{
+			name:        "one target errored",
+			stats:       []pingStats{burst(pingRoleGateway, 5, 5, 2), failed(pingRoleExternal, "failed to resolve")},
+			wantStatus:  StatusWarn,
+			wantReason:  "ping_external_loss",
+			wantLatency: 2 * time.Millisecond,
+			wantMessage: "⚠ Ping loss: gateway gateway.example: 0% loss",
+		},
+		{
+			name: "every target errored",
+			stats: []pingStats{
+				failed(pingRoleGateway, "operation not permitted"),
+				failed(pingRoleExternal, "operation not permitted"),
+			},
+			wantMessage: "✗ Ping failed: gateway gateway.example: operation not permitted; " +
+				"external.example: operation not permitted",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &pingCheck{name: "ping", lossWarn: 20}
+			r := c.result(tc.stats)
+			if r.Status != tc.wantStatus || r.Reason != tc.wantReason {
+				t.Fatalf("result() = %q %q (%s), want %q %q", r.Status, r.Reason, r.Message, tc.wantStatus, tc.wantReason)
+			}
+			if r.Latency != tc.wantLatency {
+				t.Errorf("Latency = %v, want %v", r.Latency, tc.wantLatency)
+			}
+			if !strings.HasPrefix(r.Message, tc.wantMessage) {
+				t.Errorf("Message = %q, want prefix %q", r.Message, tc.wantMessage)
+			}
+			if (r.Err != nil) != (tc.wantStatus == "") {
+				t.Errorf("Err = %v, want an error only when no target could be pinged", r.Err)
+			}
+			if targets, _ := r.Details["targets"].([]map[string]any); len(targets) != len(tc.stats) {
+				t.Errorf("got %d target details, want %d", len(targets), len(tc.stats))
+			}
+		})
+	}
+}
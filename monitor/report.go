@@ -0,0 +1,485 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OutageRecord is an outage reconstructed from the log.
+type OutageRecord struct {
+	Start   time.Time
+	End     time.Time
+	Worst   ConnState
+	Reasons []string
+	Cause   OutageCause
+	// Ongoing is set when the log ends mid-outage and Stopped when the
+	// monitor stopped mid-outage; End is then the last time the outage was
+	// observed.
+	Ongoing bool
+	Stopped bool
+}
+
+// Duration returns how long the outage lasted.
+func (o OutageRecord) Duration() time.Duration {
+	return o.End.Sub(o.Start)
+}
+
+// HumanDuration renders d rounded to seconds, with a day component for long spans.
+func HumanDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d < 24*time.Hour {
+		return d.String()
+	}
+	days := d / (24 * time.Hour)
+	if rest := d - days*24*time.Hour; rest > 0 {
+		return fmt.Sprintf("%dd%s", days, rest)
+	}
+	return fmt.Sprintf("%dd", days)
+}
+
+// CauseCount is the number of outages attributed to a cause.
+type CauseCount struct {
+	Cause OutageCause
+	Count int
+}
+
+// StatePeriod is a span of monitored time spent in one connectivity state.
+type StatePeriod struct {
+	Start time.Time
+	End   time.Time
+	State ConnState
+}
+
+// LatencySample is the duration of a successful TCP connect, DNS lookup or
+// HTTP request.
+type LatencySample struct {
+	Time    time.Time
+	Signal  Signal
+	Latency time.Duration
+}
+
+// Report summarizes connectivity over a time range. Outages are periods
+// that reached OFFLINE; periods that only reached DEGRADED are counted as
+// degraded episodes. Timeline covers the monitored time only, so gaps
+// between periods are times the monitor was not running.
+type Report struct {
+	From             time.Time
+	To               time.Time
+	Monitored        time.Duration
+	Offline          time.Duration
+	Degraded         time.Duration
+	Outages          []OutageRecord
+	DegradedEpisodes int
+	Timeline         []StatePeriod
+	Latency          []LatencySample
+}
+
+// UptimePercent is the share of monitored time that was not OFFLINE.
+func (r Report) UptimePercent() float64 {
+	if r.Monitored <= 0 {
+		return 0
+	}
+	return 100 * (1 - float64(r.Offline)/float64(r.Monitored))
+}
+
+// MTTR is the mean time to recovery over all outages.
+func (r Report) MTTR() time.Duration {
+	if len(r.Outages) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, o := range r.Outages {
+		total += o.Duration()
+	}
+	return total / time.Duration(len(r.Outages))
+}
+
+// LongestOutage returns the longest outage and false if there were none.
+func (r Report) LongestOutage() (OutageRecord, bool) {
+	if len(r.Outages) == 0 {
+		return OutageRecord{}, false
+	}
+	longest := r.Outages[0]
+	for _, o := range r.Outages[1:] {
+		if o.Duration() > longest.Duration() {
+			longest = o
+		}
+	}
+	return longest, true
+}
+
+// MedianOutage returns the median outage duration.
+func (r Report) MedianOutage() time.Duration {
+	n := len(r.Outages)
+	if n == 0 {
+		return 0
+	}
+	durations := make([]time.Duration, 0, n)
+	for _, o := range r.Outages {
+		durations = append(durations, o.Duration())
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	if n%2 == 1 {
+		return durations[n/2]
+	}
+	return (durations[n/2-1] + durations[n/2]) / 2
+}
+
+// OutagesPerDay is the number of outages per monitored day.
+func (r Report) OutagesPerDay() float64 {
+	days := r.Monitored.Hours() / 24
+	if days <= 0 {
+		return 0
+	}
+	return float64(len(r.Outages)) / days
+}
+
+// TopCauses returns up to n causes ordered by how many outages they caused.
+func (r Report) TopCauses(n int) []CauseCount {
+	counts := make(map[OutageCause]int)
+	for _, o := range r.Outages {
+		cause := o.Cause
+		if cause == "" {
+			cause = "unclassified"
+		}
+		counts[cause]++
+	}
+
+	causes := make([]CauseCount, 0, len(counts))
+	for cause, count := range counts {
+		causes = append(causes, CauseCount{Cause: cause, Count: count})
+	}
+	sort.Slice(causes, func(i, j int) bool {
+		if causes[i].Count != causes[j].Count {
+			return causes[i].Count > causes[j].Count
+		}
+		return causes[i].Cause < causes[j].Cause
+	})
+
+	if n > 0 && len(causes) > n {
+		causes = causes[:n]
+	}
+	return causes
+}
+
+// ReadEvents parses every segment of the log at logPath, oldest first, and
+// returns the events up to and including until. Earlier events are kept
+// because the state at the start of a report range depends on them.
+func ReadEvents(logPath string, until time.Time) ([]Event, error) {
+	segments, err := LogSegments(logPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	for _, segment := range segments {
+		file, err := OpenLogSegment(segment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log segment: %w", err)
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			e, ok := ParseLine(scanner.Text())
+			if ok && !e.Time.After(until) {
+				events = append(events, e)
+			}
+		}
+		err = scanner.Err()
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading log segment %s: %w", segment, err)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, nil
+}
+
+// BuildReport replays events in time order and summarizes the range
+// [from, to]. Monitoring runs are delimited by the monitor's start and stop
+// messages; time while the monitor was not running counts towards nothing.
+func BuildReport(events []Event, from, to time.Time) Report {
+	b := &reportBuilder{report: Report{From: from, To: to}, state: StateUnknown}
+	for _, e := range events {
+		b.handle(e)
+	}
+	if b.running {
+		b.stopRun(b.lastSeen, false)
+	}
+	return b.report
+}
+
+type reportBuilder struct {
+	report     Report
+	running    bool
+	runStart   time.Time
+	lastSeen   time.Time
+	state      ConnState
+	stateSince time.Time
+	open       *OutageRecord
+	lastClosed *OutageRecord
+}
+
+func (b *reportBuilder) handle(e Event) {
+	switch {
+	case e.Category == CategoryMonitor && e.Message == monitorStartMessage:
+		if b.running {
+			// Previous run ended without a stop message (crash or kill)
+			b.stopRun(b.lastSeen, true)
+		}
+		b.startRun(e.Time)
+	case !b.running:
+		// The log starts mid-run, e.g. after old segments were pruned
+		b.startRun(e.Time)
+	}
+	b.lastSeen = e.Time
+
+	if sample, ok := latencySample(e); ok && b.inRange(e.Time) {
+		b.report.Latency = append(b.report.Latency, sample)
+	}
+
+	switch e.Category {
+	case CategoryMonitor:
+		if e.Message == monitorStopMessage {
+			b.stopRun(e.Time, true)
+		}
+	case CategoryState:
+		if from, to, reasons, ok := parseStateEvent(e); ok {
+			b.transition(from, to, reasons, e.Time)
+		}
+	case CategoryOutage:
+		if cause, ok := parseOutageCause(e); ok {
+			b.recordCause(cause)
+		}
+	}
+}
+
+func (b *reportBuilder) startRun(at time.Time) {
+	b.running = true
+	b.runStart = at
+	b.state = StateUnknown
+	b.stateSince = at
+}
+
+// stopRun ends the current run at at. stopped tells a run the monitor
+// ended, with a stop message or by starting again, from one the log ends in.
+func (b *reportBuilder) stopRun(at time.Time, stopped bool) {
+	b.accumulateState(at)
+	b.report.Monitored += b.clip(b.runStart, at)
+	if b.open != nil {
+		b.open.Ongoing, b.open.Stopped = !stopped, stopped
+		b.closeOutage(at)
+	}
+	b.running = false
+}
+
+func (b *reportBuilder) transition(_ ConnState, to ConnState, reasons []string, at time.Time) {
+	b.accumulateState(at)
+	b.state = to
+	b.stateSince = at
+
+	switch {
+	case to == StateOnline && b.open != nil:
+		b.closeOutage(at)
+	case (to == StateDegraded || to == StateOffline) && b.open == nil:
+		b.open = &OutageRecord{Start: at, Worst: to, Reasons: reasons}
+	case to == StateOffline && b.open != nil:
+		b.open.Worst = StateOffline
+		b.open.Reasons = reasons
+	}
+}
+
+func (b *reportBuilder) closeOutage(at time.Time) {
+	o := b.open
+	b.open = nil
+	o.End = at
+	b.lastClosed = o
+
+	// Only count episodes overlapping the report range
+	if !o.End.After(b.report.From) || o.Start.After(b.report.To) {
+		return
+	}
+	if o.Worst == StateOffline {
+		b.report.Outages = append(b.report.Outages, *o)
+	} else {
+		b.report.DegradedEpisodes++
+	}
+}
+
+// recordCause attributes a classifier result to the open outage or, when
+// classification finished after recovery, to the outage that just closed.
+func (b *reportBuilder) recordCause(cause OutageCause) {
+	switch {
+	case b.open != nil:
+		b.open.Cause = cause
+	case b.lastClosed != nil && b.lastClosed.Cause == "":
+		b.lastClosed.Cause = cause
+		if n := len(b.report.Outages); n > 0 && b.report.Outages[n-1].Start.Equal(b.lastClosed.Start) {
+			b.report.Outages[n-1].Cause = cause
+		}
+	}
+}
+
+func (b *reportBuilder) accumulateState(until time.Time) {
+	held := b.clip(b.stateSince, until)
+	if held <= 0 {
+		return
+	}
+	switch b.state {
+	case StateOffline:
+		b.report.Offline += held
+	case StateDegraded:
+		b.report.Degraded += held
+	}
+
+	start := b.stateSince
+	if start.Before(b.report.From) {
+		start = b.report.From
+	}
+	b.report.Timeline = append(b.report.Timeline, StatePeriod{Start: start, End: start.Add(held), State: b.state})
+}
+
+func (b *reportBuilder) inRange(t time.Time) bool {
+	return !t.Before(b.report.From) && !t.After(b.report.To)
+}
+
+// clip returns the length of [start, end] that falls inside the report range.
+func (b *reportBuilder) clip(start, end time.Time) time.Duration {
+	if start.Before(b.report.From) {
+		start = b.report.From
+	}
+	if end.After(b.report.To) {
+		end = b.report.To
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+var (
+	stateMessagePattern  = regexp.MustCompile(`^STATE ([A-Z]+) -> ([A-Z]+)(?: reason=(\S+))?`)
+	outageMessagePattern = regexp.MustCompile(`^OUTAGE #\d+ (?:ended .*)?cause=(\S+)`)
+	tookMessagePattern   = regexp.MustCompile(`\(took ([^)]+)\)$`)
+)
+
+// latencyMessagePrefixes identify successful probes in text logs, which do
+// not carry the signal and duration fields of JSON logs.
+var latencyMessagePrefixes = map[string]Signal{
+	"SUCCESS: Connected to": SignalTCP,
+	"✓ DNS working":         SignalDNS,
+	"✓ HTTP working":        SignalHTTP,
+}
+
+// latencySample extracts the latency of a successful TCP, DNS or HTTP probe.
+func latencySample(e Event) (LatencySample, bool) {
+	if e.Uplink != "" {
+		// Pinned probes do not measure the default path
+		return LatencySample{}, false
+	}
+	switch e.Signal {
+	case SignalTCP, SignalDNS, SignalHTTP:
+		if e.Status == StatusOK && e.Duration > 0 {
+			return LatencySample{Time: e.Time, Signal: e.Signal, Latency: e.Duration}, true
+		}
+		return LatencySample{}, false
+	case "":
+	default:
+		return LatencySample{}, false
+	}
+
+	for prefix, signal := range latencyMessagePrefixes {
+		if !strings.HasPrefix(e.Message, prefix) {
+			continue
+		}
+		m := tookMessagePattern.FindStringSubmatch(e.Message)
+		if m == nil {
+			return LatencySample{}, false
+		}
+		d, err := time.ParseDuration(m[1])
+		if err != nil {
+			return LatencySample{}, false
+		}
+		return LatencySample{Time: e.Time, Signal: signal, Latency: d}, true
+	}
+	return LatencySample{}, false
+}
+
+// parseStateEvent extracts a transition from a STATE event, using the
+// structured fields of JSON logs and the message of text logs.
+func parseStateEvent(e Event) (from, to ConnState, reasons []string, ok bool) {
+	if f, t := stringField(e.Fields, "from"), stringField(e.Fields, "to"); f != "" && t != "" {
+		return ConnState(f), ConnState(t), splitReasons(e.Reason), true
+	}
+
+	m := stateMessagePattern.FindStringSubmatch(e.Message)
+	if m == nil {
+		return "", "", nil, false
+	}
+	return ConnState(m[1]), ConnState(m[2]), splitReasons(m[3]), true
+}
+
+// parseOutageCause extracts a classified cause from an OUTAGE event.
+func parseOutageCause(e Event) (OutageCause, bool) {
+	cause := stringField(e.Fields, "cause")
+	if cause == "" {
+		m := outageMessagePattern.FindStringSubmatch(e.Message)
+		if m == nil {
+			return "", false
+		}
+		cause = m[1]
+	}
+	if cause == "unclassified" {
+		return "", false
+	}
+	return OutageCause(cause), true
+}
+
+func splitReasons(reasons string) []string {
+	if reasons == "" {
+		return nil
+	}
+	return strings.Split(reasons, ",")
+}
+
+// ParseSince parses a report range boundary: a relative age such as "7d",
+// "12h" or "2w", a date ("2006-01-02") or an RFC 3339 timestamp.
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("empty time value")
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	unit := value[len(value)-1]
+	if unit == 'd' || unit == 'w' {
+		n, err := strconv.Atoi(value[:len(value)-1])
+		if err != nil || n < 0 {
+			return time.Time{}, fmt.Errorf("invalid age %q", value)
+		}
+		days := n
+		if unit == 'w' {
+			days = n * 7
+		}
+		return now.AddDate(0, 0, -days), nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid time %q (use e.g. 7d, 12h, 2006-01-02 or RFC 3339)", value)
+	}
+	return now.Add(-d), nil
+}
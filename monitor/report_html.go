@@ -0,0 +1,474 @@
+package monitor
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HostInfo describes the machine a report was generated on.
+type HostInfo struct {
+	Hostname   string
+	OS         string
+	Gateway    string
+	LogPath    string
+	Interfaces []InterfaceInfo
+	Generated  time.Time
+}
+
+// InterfaceInfo describes a network interface for the report.
+type InterfaceInfo struct {
+	Name      string
+	MAC       string
+	Up        bool
+	Addresses []string
+}
+
+// CollectHostInfo gathers the hostname, platform, default gateway and
+// non-loopback interfaces of the local machine. The gateway is only known
+// where the routing table can be read.
+func CollectHostInfo(logPath string) HostInfo {
+	info := HostInfo{
+		OS:        runtime.GOOS + "/" + runtime.GOARCH,
+		LogPath:   logPath,
+		Generated: time.Now(),
+	}
+	info.Hostname, _ = os.Hostname()
+
+	if found, gw, err := readDefaultRoute(); err == nil && found && gw != nil {
+		info.Gateway = gw.String()
+	}
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return info
+	}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		ii := InterfaceInfo{
+			Name: iface.Name,
+			MAC:  iface.HardwareAddr.String(),
+			Up:   linkStatus(iface.Flags) == StatusOK,
+		}
+		if addrs, err := iface.Addrs(); err == nil {
+			for _, addr := range addrs {
+				ii.Addresses = append(ii.Addresses, addr.String())
+			}
+		}
+		info.Interfaces = append(info.Interfaces, ii)
+	}
+	return info
+}
+
+// Chart geometry, in SVG user units.
+const (
+	chartWidth     = 960
+	chartMarginX   = 56
+	timelineHeight = 64
+	latencyHeight  = 180
+	chartMarginY   = 24
+)
+
+var stateColors = map[ConnState]string{
+	StateOnline:   "#2e9e44",
+	StateDegraded: "#e0a100",
+	StateOffline:  "#d0312d",
+	StateUnknown:  "#9aa0a6",
+}
+
+// latencyCharts lists the latency graphs in the order they are rendered.
+var latencyCharts = []struct {
+	Signal Signal
+	Title  string
+}{
+	{SignalTCP, "TCP keepalive connect time"},
+	{SignalDNS, "Watchdog DNS lookup time"},
+	{SignalHTTP, "Watchdog HTTP request time"},
+}
+
+type htmlOutage struct {
+	Index    int
+	Start    string
+	End      string
+	Duration string
+	Cause    string
+	Reasons  string
+}
+
+type htmlLatency struct {
+	Title   string
+	Samples int
+	Min     string
+	Median  string
+	P95     string
+	Max     string
+	Chart   template.HTML
+}
+
+type htmlReport struct {
+	Report   Report
+	Host     HostInfo
+	Uptime   string
+	PerDay   string
+	Longest  string
+	Median   string
+	MTTR     string
+	Durs     map[string]string
+	Causes   []CauseCount
+	Outages  []htmlOutage
+	Timeline template.HTML
+	Latency  []htmlLatency
+	Colors   map[string]string
+}
+
+// WriteHTMLReport renders r as a self-contained HTML document: styles and
+// SVG charts are inline, so the file can be sent as is and opened offline.
+func WriteHTMLReport(w io.Writer, r Report, host HostInfo) error {
+	data := htmlReport{
+		Report: r,
+		Host:   host,
+		Uptime: fmt.Sprintf("%.3f%%", r.UptimePercent()),
+		PerDay: fmt.Sprintf("%.2f", r.OutagesPerDay()),
+		Median: HumanDuration(r.MedianOutage()),
+		MTTR:   HumanDuration(r.MTTR()),
+		Durs: map[string]string{
+			"monitored": HumanDuration(r.Monitored),
+			"offline":   HumanDuration(r.Offline),
+			"degraded":  HumanDuration(r.Degraded),
+		},
+		Causes:   r.TopCauses(0),
+		Timeline: timelineSVG(r),
+		Colors:   make(map[string]string, len(stateColors)),
+	}
+	for state, color := range stateColors {
+		data.Colors[string(state)] = color
+	}
+	if longest, ok := r.LongestOutage(); ok {
+		data.Longest = HumanDuration(longest.Duration())
+	}
+
+	for i, o := range r.Outages {
+		ho := htmlOutage{
+			Index:    i + 1,
+			Start:    o.Start.Format(reportTimeLayout),
+			End:      o.End.Format(reportTimeLayout),
+			Duration: HumanDuration(o.Duration()),
+			Cause:    string(o.Cause),
+			Reasons:  strings.Join(o.Reasons, ", "),
+		}
+		if ho.Cause == "" {
+			ho.Cause = "unclassified"
+		}
+		switch {
+		case o.Stopped:
+			ho.End += " (monitor stopped)"
+		case o.Ongoing:
+			ho.End += " (ongoing)"
+		}
+		data.Outages = append(data.Outages, ho)
+	}
+
+	for _, c := range latencyCharts {
+		var samples []LatencySample
+		for _, s := range r.Latency {
+			if s.Signal == c.Signal {
+				samples = append(samples, s)
+			}
+		}
+		if len(samples) == 0 {
+			continue
+		}
+		data.Latency = append(data.Latency, latencySection(c.Title, samples, r.From, r.To))
+	}
+
+	if err := htmlReportTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return nil
+}
+
+const reportTimeLayout = "2006-01-02 15:04:05"
+
+// timeScale maps times in [from, to] onto the horizontal chart area.
+type timeScale struct {
+	from, to time.Time
+}
+
+func (s timeScale) x(t time.Time) float64 {
+	span := s.to.Sub(s.from)
+	if span <= 0 {
+		return chartMarginX
+	}
+	return chartMarginX + float64(t.Sub(s.from))/float64(span)*(chartWidth-2*chartMarginX)
+}
+
+// axis draws time ticks below a chart whose plot area ends at y.
+func (s timeScale) axis(b *strings.Builder, y float64) {
+	const ticks = 6
+	layout := "01-02 15:04"
+	if s.to.Sub(s.from) <= 24*time.Hour {
+		layout = "15:04"
+	}
+	step := s.to.Sub(s.from) / ticks
+	for i := 0; i <= ticks; i++ {
+		t := s.from.Add(time.Duration(i) * step)
+		x := s.x(t)
+		fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#888"/>`, x, y, x, y+4)
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f" text-anchor="middle" class="tick">%s</text>`,
+			x, y+16, html.EscapeString(t.Format(layout)))
+	}
+}
+
+// timelineSVG draws the connectivity state over the report range; time the
+// monitor was not running is left as a hatched background.
+func timelineSVG(r Report) template.HTML {
+	s := timeScale{from: r.From, to: r.To}
+	barTop, barHeight := 8.0, 32.0
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg viewBox="0 0 %d %d" width="100%%" role="img" aria-label="Connectivity timeline">`,
+		chartWidth, timelineHeight)
+	b.WriteString(`<defs><pattern id="gap" width="6" height="6" patternUnits="userSpaceOnUse" ` +
+		`patternTransform="rotate(45)"><rect width="6" height="6" fill="#f1f3f4"/>` +
+		`<line x1="0" y1="0" x2="0" y2="6" stroke="#dadce0" stroke-width="3"/></pattern></defs>`)
+	fmt.Fprintf(&b, `<rect x="%d" y="%.1f" width="%d" height="%.1f" fill="url(#gap)"/>`,
+		chartMarginX, barTop, chartWidth-2*chartMarginX, barHeight)
+
+	for _, p := range r.Timeline {
+		x1, x2 := s.x(p.Start), s.x(p.End)
+		width := x2 - x1
+		if p.State == StateOffline || p.State == StateDegraded {
+			// Keep short outages visible at multi-day scale
+			width = max(width, 1.5)
+		}
+		fmt.Fprintf(&b, `<rect x="%.2f" y="%.1f" width="%.2f" height="%.1f" fill="%s"><title>%s %s - %s</title></rect>`,
+			x1, barTop, width, barHeight, stateColors[p.State], p.State,
+			p.Start.Format(reportTimeLayout), p.End.Format(reportTimeLayout))
+	}
+
+	s.axis(&b, barTop+barHeight)
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String()) //nolint:gosec // built from numbers and escaped text only
+}
+
+// maxChartPoints bounds the points per latency graph; samples sharing a
+// bucket are reduced to their maximum so spikes stay visible.
+const maxChartPoints = 1000
+
+func latencySection(title string, samples []LatencySample, from, to time.Time) htmlLatency {
+	sorted := make([]time.Duration, len(samples))
+	for i, s := range samples {
+		sorted[i] = s.Latency
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	quantile := func(q float64) time.Duration { return sorted[int(q*float64(len(sorted)-1))] }
+
+	return htmlLatency{
+		Title:   title,
+		Samples: len(samples),
+		Min:     latencyString(sorted[0]),
+		Median:  latencyString(quantile(0.5)),
+		P95:     latencyString(quantile(0.95)),
+		Max:     latencyString(sorted[len(sorted)-1]),
+		Chart:   latencySVG(title, samples, timeScale{from: from, to: to}),
+	}
+}
+
+func latencySVG(title string, samples []LatencySample, s timeScale) template.HTML {
+	points := bucketSamples(samples, s)
+
+	peak := time.Millisecond
+	for _, p := range points {
+		peak = max(peak, p.Latency)
+	}
+	top := niceCeiling(peak)
+	plotTop, plotBottom := float64(chartMarginY)/2, float64(latencyHeight-chartMarginY)
+	y := func(d time.Duration) float64 {
+		return plotBottom - float64(d)/float64(top)*(plotBottom-plotTop)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg viewBox="0 0 %d %d" width="100%%" role="img" aria-label="%s">`,
+		chartWidth, latencyHeight, html.EscapeString(title))
+
+	for i := 0; i <= 4; i++ {
+		d := top * time.Duration(i) / 4
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#e8eaed"/>`,
+			chartMarginX, y(d), chartWidth-chartMarginX, y(d))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" class="tick">%s</text>`,
+			chartMarginX-6, y(d)+4, html.EscapeString(latencyString(d)))
+	}
+
+	b.WriteString(`<polyline fill="none" stroke="#1a73e8" stroke-width="1.2" points="`)
+	for _, p := range points {
+		fmt.Fprintf(&b, "%.1f,%.1f ", s.x(p.Time), y(p.Latency))
+	}
+	b.WriteString(`"/>`)
+	if len(points) == 1 {
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="2.5" fill="#1a73e8"/>`, s.x(points[0].Time), y(points[0].Latency))
+	}
+
+	s.axis(&b, plotBottom)
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String()) //nolint:gosec // built from numbers and escaped text only
+}
+
+// bucketSamples reduces samples to at most maxChartPoints, keeping the
+// slowest sample of each time bucket.
+func bucketSamples(samples []LatencySample, s timeScale) []LatencySample {
+	if len(samples) <= maxChartPoints {
+		return samples
+	}
+	bucket := s.to.Sub(s.from) / maxChartPoints
+	if bucket <= 0 {
+		return samples[:maxChartPoints]
+	}
+
+	var points []LatencySample
+	for _, sample := range samples {
+		n := len(points)
+		if n > 0 && sample.Time.Sub(s.from)/bucket == points[n-1].Time.Sub(s.from)/bucket {
+			if sample.Latency > points[n-1].Latency {
+				points[n-1] = sample
+			}
+			continue
+		}
+		points = append(points, sample)
+	}
+	return points
+}
+
+// niceCeiling rounds d up to 1, 2 or 5 times a power of ten milliseconds.
+func niceCeiling(d time.Duration) time.Duration {
+	step := time.Millisecond
+	for {
+		for _, m := range []time.Duration{1, 2, 5} {
+			if d <= m*step {
+				return m * step
+			}
+		}
+		step *= 10
+	}
+}
+
+func latencyString(d time.Duration) string {
+	if d >= time.Second {
+		return fmt.Sprintf("%.2fs", d.Seconds())
+	}
+	return fmt.Sprintf("%.1fms", durationMillis(d))
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Network outage report{{with .Host.Hostname}} - {{.}}{{end}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #202124; max-width: 1000px; margin: 2em auto; padding: 0 1em; }
+h1 { font-size: 1.6em; margin-bottom: 0.2em; }
+h2 { font-size: 1.2em; border-bottom: 1px solid #dadce0; padding-bottom: 0.2em; margin-top: 2em; }
+.sub { color: #5f6368; }
+table { border-collapse: collapse; width: 100%; font-size: 0.92em; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #e8eaed; vertical-align: top; }
+th { background: #f8f9fa; }
+table.kv th { width: 30%; background: none; font-weight: normal; color: #5f6368; }
+.num { text-align: right; font-variant-numeric: tabular-nums; }
+.tick { font-size: 11px; fill: #5f6368; }
+.legend span { display: inline-block; margin-right: 1.2em; font-size: 0.9em; }
+.legend i { display: inline-block; width: 12px; height: 12px; margin-right: 4px; vertical-align: -1px; }
+.state-OFFLINE { color: #d0312d; font-weight: bold; }
+footer { margin-top: 3em; font-size: 0.85em; color: #5f6368; }
+</style>
+</head>
+<body>
+<h1>Network outage report</h1>
+<p class="sub">{{.Report.From.Format "2006-01-02 15:04:05 MST"}} &ndash; {{.Report.To.Format "2006-01-02 15:04:05 MST"}}</p>
+
+<h2>Summary</h2>
+<table class="kv">
+<tr><th>Monitored time</th><td>{{index .Durs "monitored"}}</td></tr>
+<tr><th>Uptime</th><td>{{.Uptime}} (offline {{index .Durs "offline"}}, degraded {{index .Durs "degraded"}})</td></tr>
+<tr><th>Outages</th><td>{{len .Report.Outages}} ({{.PerDay}} per day)</td></tr>
+{{- if .Report.Outages}}
+<tr><th>Longest outage</th><td>{{.Longest}}</td></tr>
+<tr><th>Median outage</th><td>{{.Median}}</td></tr>
+<tr><th>Mean time to recovery</th><td>{{.MTTR}}</td></tr>
+{{- end}}
+<tr><th>Degraded episodes</th><td>{{.Report.DegradedEpisodes}}</td></tr>
+</table>
+{{- if .Causes}}
+<h3>Root causes</h3>
+<table>
+<tr><th>Cause</th><th class="num">Outages</th></tr>
+{{- range .Causes}}
+<tr><td>{{.Cause}}</td><td class="num">{{.Count}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+<h2>Connectivity timeline</h2>
+{{.Timeline}}
+<div class="legend">
+<span><i style="background: {{index .Colors "ONLINE"}}"></i>Online</span>
+<span><i style="background: {{index .Colors "DEGRADED"}}"></i>Degraded</span>
+<span><i style="background: {{index .Colors "OFFLINE"}}"></i>Offline</span>
+<span><i style="background: {{index .Colors "UNKNOWN"}}"></i>Unknown</span>
+<span><i style="background: #dadce0"></i>Not monitored</span>
+</div>
+
+<h2>Outages</h2>
+{{- if .Outages}}
+<table>
+<tr><th>#</th><th>Start</th><th>End</th><th class="num">Duration</th><th>Cause</th><th>Failing checks</th></tr>
+{{- range .Outages}}
+<tr><td>{{.Index}}</td><td>{{.Start}}</td><td>{{.End}}</td><td class="num">{{.Duration}}</td><td>{{.Cause}}</td><td>{{.Reasons}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No outages were recorded in this period.</p>
+{{- end}}
+
+<h2>Latency</h2>
+{{- range .Latency}}
+<h3>{{.Title}}</h3>
+<p class="sub">{{.Samples}} samples &middot; min {{.Min}} &middot; median {{.Median}} &middot; p95 {{.P95}} &middot; max {{.Max}}</p>
+{{.Chart}}
+{{- else}}
+<p>No latency samples were recorded in this period.</p>
+{{- end}}
+
+<h2>Host and network</h2>
+<table class="kv">
+<tr><th>Hostname</th><td>{{.Host.Hostname}}</td></tr>
+<tr><th>Platform</th><td>{{.Host.OS}}</td></tr>
+<tr><th>Default gateway</th><td>{{with .Host.Gateway}}{{.}}{{else}}unknown{{end}}</td></tr>
+<tr><th>Log file</th><td>{{.Host.LogPath}}</td></tr>
+</table>
+{{- if .Host.Interfaces}}
+<h3>Interfaces</h3>
+<table>
+<tr><th>Name</th><th>State</th><th>MAC</th><th>Addresses</th></tr>
+{{- range .Host.Interfaces}}
+<tr><td>{{.Name}}</td><td>{{if .Up}}up{{else}}down{{end}}</td><td>{{.MAC}}</td><td>{{range $i, $a := .Addresses}}{{if $i}}<br>{{end}}{{$a}}{{end}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+<footer>
+<p>An outage is a period in which the monitor's connectivity state was OFFLINE: no link or default route,
+or both the persistent TCP connection and HTTPS requests failing. Causes are assigned by probing the local link,
+default route, gateway, upstream hosts by IP address, DNS and HTTP in turn. Latency graphs show successful probes only.</p>
+<p>Generated {{.Host.Generated.Format "2006-01-02 15:04:05 MST"}} by network-monitor.</p>
+</footer>
+</body>
+</html>
+`))
@@ -0,0 +1,297 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// reportDay is the day the report fixtures were logged, in local time as
+// text logs are.
+var reportDay = time.Date(2025, 12, 12, 0, 0, 0, 0, time.Local)
+
+func at(hour, min, sec int) time.Time {
+	return reportDay.Add(time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute + time.Duration(sec)*time.Second)
+}
+
+// textReportLog is one monitoring run with three outages of 2m, 6m and 4m
+// and a degraded episode, in the text format.
+var textReportLog = []string{
+	"[2025-12-12 10:00:00.000] [MONITOR] [INFO] === Network Stability Monitor Starting ===",
+	"[2025-12-12 10:00:05.000] [STATE] [INFO] STATE UNKNOWN -> ONLINE after=5s",
+	"[2025-12-12 10:00:05.100] [TCP] [INFO] SUCCESS: Connected to 1.1.1.1:443 (took 25ms)",
+	"[2025-12-12 10:10:00.000] [STATE] [CRITICAL] STATE ONLINE -> OFFLINE reason=tcp_failed,http_failed after=9m55s",
+	"[2025-12-12 10:10:02.000] [OUTAGE] [WARN] OUTAGE #1 cause=upstream_unreachable (gateway answers but 1.1.1.1:443 are unreachable)",
+	"[2025-12-12 10:12:00.000] [STATE] [INFO] STATE OFFLINE -> ONLINE after=2m0s",
+	"[2025-12-12 10:20:00.000] [STATE] [WARN] STATE ONLINE -> DEGRADED reason=dns_failed after=8m0s",
+	"[2025-12-12 10:21:00.000] [STATE] [INFO] STATE DEGRADED -> ONLINE after=1m0s",
+	"[2025-12-12 10:30:00.000] [STATE] [CRITICAL] STATE ONLINE -> OFFLINE reason=link_down after=9m0s",
+	"[2025-12-12 10:36:00.000] [STATE] [INFO] STATE OFFLINE -> ONLINE after=6m0s",
+	"[2025-12-12 10:36:01.000] [OUTAGE] [INFO] OUTAGE #2 ended after=6m0s state=OFFLINE cause=local_link_down",
+	"[2025-12-12 10:40:00.000] [STATE] [CRITICAL] STATE ONLINE -> OFFLINE reason=tcp_failed after=4m0s",
+	"[2025-12-12 10:44:00.000] [STATE] [INFO] STATE OFFLINE -> ONLINE after=4m0s",
+	"[2025-12-12 11:00:00.000] [MONITOR] [INFO] === Network Stability Monitor Stopping ===",
+	"not a log line",
+}
+
+// jsonReportLog is the same run in the JSON format.
+var jsonReportLog = []string{
+	`{"ts":"` + at(10, 0, 0).Format(time.RFC3339Nano) + `","category":"MONITOR","severity":"INFO","message":"=== Network Stability Monitor Starting ==="}`,
+	`{"ts":"` + at(10, 0, 5).Format(time.RFC3339Nano) + `","category":"STATE","severity":"INFO","message":"STATE UNKNOWN -> ONLINE after=5s","from":"UNKNOWN","to":"ONLINE"}`,
+	`{"ts":"` + at(10, 0, 5).Add(100*time.Millisecond).Format(time.RFC3339Nano) + `","category":"TCP","severity":"INFO","message":"SUCCESS: Connected to 1.1.1.1:443","signal":"tcp","status":"ok","duration_ms":25}`,
+	`{"ts":"` + at(10, 10, 0).Format(time.RFC3339Nano) + `","category":"STATE","severity":"CRITICAL","message":"STATE ONLINE -> OFFLINE","reason":"tcp_failed,http_failed","from":"ONLINE","to":"OFFLINE"}`,
+	`{"ts":"` + at(10, 10, 2).Format(time.RFC3339Nano) + `","category":"OUTAGE","severity":"WARN","message":"OUTAGE #1 cause=upstream_unreachable","outage_id":1,"cause":"upstream_unreachable"}`,
+	`{"ts":"` + at(10, 12, 0).Format(time.RFC3339Nano) + `","category":"STATE","severity":"INFO","message":"STATE OFFLINE -> ONLINE","from":"OFFLINE","to":"ONLINE"}`,
+	`{"ts":"` + at(10, 20, 0).Format(time.RFC3339Nano) + `","category":"STATE","severity":"WARN","message":"STATE ONLINE -> DEGRADED","reason":"dns_failed","from":"ONLINE","to":"DEGRADED"}`,
+	`{"ts":"` + at(10, 21, 0).Format(time.RFC3339Nano) + `","category":"STATE","severity":"INFO","message":"STATE DEGRADED -> ONLINE","from":"DEGRADED","to":"ONLINE"}`,
+	`{"ts":"` + at(10, 30, 0).Format(time.RFC3339Nano) + `","category":"STATE","severity":"CRITICAL","message":"STATE ONLINE -> OFFLINE","reason":"link_down","from":"ONLINE","to":"OFFLINE"}`,
+	`{"ts":"` + at(10, 36, 0).Format(time.RFC3339Nano) + `","category":"STATE","severity":"INFO","message":"STATE OFFLINE -> ONLINE","from":"OFFLINE","to":"ONLINE"}`,
+	`{"ts":"` + at(10, 36, 1).Format(time.RFC3339Nano) + `","category":"OUTAGE","severity":"INFO","message":"OUTAGE #2 ended","outage_id":2,"state":"OFFLINE","cause":"local_link_down"}`,
+	`{"ts":"` + at(10, 40, 0).Format(time.RFC3339Nano) + `","category":"STATE","severity":"CRITICAL","message":"STATE ONLINE -> OFFLINE","reason":"tcp_failed","from":"ONLINE","to":"OFFLINE"}`,
+	`{"ts":"` + at(10, 44, 0).Format(time.RFC3339Nano) + `","category":"STATE","severity":"INFO","message":"STATE OFFLINE -> ONLINE","from":"OFFLINE","to":"ONLINE"}`,
+	`{"ts":"` + at(11, 0, 0).Format(time.RFC3339Nano) + `","category":"MONITOR","severity":"INFO","message":"=== Network Stability Monitor Stopping ==="}`,
+	`{"category":"STATE"}`,
+}
+
+func parseLines(t *testing.T, lines []string) []Event {
+	t.Helper()
+	var events []Event
+	for _, line := range lines {
+		if e, ok := ParseLine(line); ok {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+func TestParseLine(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		line string
+		ok   bool
+		want Event
+	}{
+		{
+			name: "text",
+			line: "[2025-12-12 10:10:00.000] [STATE] [CRITICAL] STATE ONLINE -> OFFLINE reason=tcp_failed",
+			ok:   true,
+			want: Event{Time: at(10, 10, 0), Category: CategoryState, Severity: SeverityCritical,
+				Message: "STATE ONLINE -> OFFLINE reason=tcp_failed"},
+		},
+		{
+			name: "text without severity",
+			line: "[2025-12-12 10:10:00.250] [TCP] SUCCESS: Connected to 1.1.1.1:443 (took 25ms)",
+			ok:   true,
+			want: Event{Time: at(10, 10, 0).Add(250 * time.Millisecond), Category: "TCP",
+				Message: "SUCCESS: Connected to 1.1.1.1:443 (took 25ms)"},
+		},
+		{
+			name: "json",
+			line: `{"ts":"2025-12-12T09:10:00Z","category":"WATCHDOG","severity":"WARN","message":"DNS slow","signal":"dns","status":"warn","reason":"dns_slow","duration_ms":1500.5}`,
+			ok:   true,
+			want: Event{Time: time.Date(2025, 12, 12, 9, 10, 0, 0, time.UTC), Category: CategoryWatchdog, Severity: SeverityWarn,
+				Message: "DNS slow", Signal: SignalDNS, Status: StatusWarn, Reason: "dns_slow",
+				Duration: 1500*time.Millisecond + 500*time.Microsecond},
+		},
+		{name: "bad timestamp", line: "[yesterday] [TCP] [INFO] hello"},
+		{name: "json without category", line: `{"ts":"2025-12-12T09:10:00Z","message":"hello"}`},
+		{name: "json without timestamp", line: `{"category":"TCP","message":"hello"}`},
+		{name: "garbage", line: "hello"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := ParseLine(tc.line)
+			if ok != tc.ok {
+				t.Fatalf("ParseLine() ok = %v, want %v", ok, tc.ok)
+			}
+			if !ok {
+				return
+			}
+			if !got.Time.Equal(tc.want.Time) || got.Category != tc.want.Category || got.Severity != tc.want.Severity ||
+				got.Message != tc.want.Message || got.Signal != tc.want.Signal || got.Status != tc.want.Status ||
+				got.Reason != tc.want.Reason || got.Duration != tc.want.Duration {
+				t.Errorf("ParseLine() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuildReport(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		lines []string
+	}{
+		{"text", textReportLog},
+		{"json", jsonReportLog},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := BuildReport(parseLines(t, tc.lines), at(0, 0, 0), at(23, 59, 59))
+
+			if len(r.Outages) != 3 {
+				t.Fatalf("got %d outages, want 3: %+v", len(r.Outages), r.Outages)
+			}
+			if r.DegradedEpisodes != 1 {
+				t.Errorf("DegradedEpisodes = %d, want 1", r.DegradedEpisodes)
+			}
+			if r.Monitored != time.Hour {
+				t.Errorf("Monitored = %v, want 1h", r.Monitored)
+			}
+			if r.Offline != 12*time.Minute {
+				t.Errorf("Offline = %v, want 12m", r.Offline)
+			}
+			if r.Degraded != time.Minute {
+				t.Errorf("Degraded = %v, want 1m", r.Degraded)
+			}
+			if got := r.MedianOutage(); got != 4*time.Minute {
+				t.Errorf("MedianOutage() = %v, want 4m", got)
+			}
+			if got := r.MTTR(); got != 4*time.Minute {
+				t.Errorf("MTTR() = %v, want 4m", got)
+			}
+			if got, _ := r.LongestOutage(); got.Duration() != 6*time.Minute || !got.Start.Equal(at(10, 30, 0)) {
+				t.Errorf("LongestOutage() = %+v, want the 6m outage at 10:30", got)
+			}
+			if got := r.UptimePercent(); got != 80 {
+				t.Errorf("UptimePercent() = %v, want 80", got)
+			}
+
+			causes := []OutageCause{CauseUpstream, CauseLocalLink, ""}
+			for i, o := range r.Outages {
+				if o.Cause != causes[i] {
+					t.Errorf("outage %d: cause %q, want %q", i, o.Cause, causes[i])
+				}
+				if o.Ongoing {
+					t.Errorf("outage %d: ongoing", i)
+				}
+			}
+			if len(r.Latency) != 1 || r.Latency[0].Latency != 25*time.Millisecond {
+				t.Errorf("Latency = %+v, want one 25ms TCP sample", r.Latency)
+			}
+		})
+	}
+}
+
+func TestBuildReportRange(t *testing.T) {
+	events := parseLines(t, textReportLog)
+
+	// From 10:35 the second outage is cut to its last minute
+	r := BuildReport(events, at(10, 35, 0), at(23, 59, 59))
+	if len(r.Outages) != 2 {
+		t.Fatalf("got %d outages, want 2", len(r.Outages))
+	}
+	if r.Monitored != 25*time.Minute {
+		t.Errorf("Monitored = %v, want 25m", r.Monitored)
+	}
+	if r.Offline != 5*time.Minute {
+		t.Errorf("Offline = %v, want 5m", r.Offline)
+	}
+
+	// A log that ends mid-outage leaves the outage ongoing
+	r = BuildReport(events[:len(events)-2], at(0, 0, 0), at(23, 59, 59))
+	if n := len(r.Outages); n != 3 || !r.Outages[n-1].Ongoing || r.Outages[n-1].Stopped {
+		t.Fatalf("outages = %+v, want the last of 3 ongoing", r.Outages)
+	}
+
+	// Stopping the monitor mid-outage ends it as stopped
+	stop := events[len(events)-1]
+	stop.Time = at(10, 43, 0)
+	r = BuildReport(append(events[:len(events)-2:len(events)-2], stop), at(0, 0, 0), at(23, 59, 59))
+	if n := len(r.Outages); n != 3 || r.Outages[n-1].Ongoing || !r.Outages[n-1].Stopped {
+		t.Fatalf("outages = %+v, want the last of 3 stopped", r.Outages)
+	}
+}
+
+func TestWriteHTMLReportOutageEnd(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		outage  OutageRecord
+		want    string
+		notWant string
+	}{
+		{name: "ended", outage: OutageRecord{}, notWant: "(", want: "10:05:00"},
+		{name: "ongoing", outage: OutageRecord{Ongoing: true}, want: "(ongoing)", notWant: "monitor stopped"},
+		{name: "stopped", outage: OutageRecord{Stopped: true}, want: "(monitor stopped)", notWant: "ongoing"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			o := tc.outage
+			o.Start, o.End, o.Worst = at(10, 0, 0), at(10, 5, 0), StateOffline
+			r := Report{From: at(9, 0, 0), To: at(11, 0, 0), Monitored: 2 * time.Hour, Offline: 5 * time.Minute,
+				Outages: []OutageRecord{o}}
+
+			var b strings.Builder
+			if err := WriteHTMLReport(&b, r, HostInfo{}); err != nil {
+				t.Fatal(err)
+			}
+			end := outageEndCell(b.String())
+			if !strings.Contains(end, tc.want) || strings.Contains(end, tc.notWant) {
+				t.Errorf("outage end %q, want it to contain %q and not %q", end, tc.want, tc.notWant)
+			}
+		})
+	}
+}
+
+// outageEndCell returns the rendered end of the first outage: the text of
+// the table cell that follows its start.
+func outageEndCell(page string) string {
+	start := at(10, 0, 0).Format(reportTimeLayout)
+	_, rest, _ := strings.Cut(page, start+"</td>")
+	_, rest, _ = strings.Cut(rest, ">")
+	end, _, _ := strings.Cut(rest, "</td>")
+	return end
+}
+
+func TestMedianOutage(t *testing.T) {
+	outage := func(d time.Duration) OutageRecord {
+		return OutageRecord{Start: at(10, 0, 0), End: at(10, 0, 0).Add(d)}
+	}
+	for _, tc := range []struct {
+		name      string
+		durations []time.Duration
+		want      time.Duration
+	}{
+		{"none", nil, 0},
+		{"odd", []time.Duration{5 * time.Minute, time.Minute, 3 * time.Minute}, 3 * time.Minute},
+		{"even", []time.Duration{4 * time.Minute, time.Minute, 2 * time.Minute, 10 * time.Minute}, 3 * time.Minute},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var r Report
+			for _, d := range tc.durations {
+				r.Outages = append(r.Outages, outage(d))
+			}
+			if got := r.MedianOutage(); got != tc.want {
+				t.Errorf("MedianOutage() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2025, 12, 12, 10, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "7d", want: now.AddDate(0, 0, -7)},
+		{value: "2w", want: now.AddDate(0, 0, -14)},
+		{value: "12h", want: now.Add(-12 * time.Hour)},
+		{value: "90m", want: now.Add(-90 * time.Minute)},
+		{value: " 0d ", want: now},
+		{value: "2025-12-01", want: time.Date(2025, 12, 1, 0, 0, 0, 0, time.Local)},
+		{value: "2025-12-01T08:30:00Z", want: time.Date(2025, 12, 1, 8, 30, 0, 0, time.UTC)},
+		{value: "", wantErr: true},
+		{value: "-1d", wantErr: true},
+		{value: "xd", wantErr: true},
+		{value: "-5h", wantErr: true},
+		{value: "yesterday", wantErr: true},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := ParseSince(tc.value, now)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseSince(%q) error = %v, want error %v", tc.value, err, tc.wantErr)
+			}
+			if !tc.wantErr && !got.Equal(tc.want) {
+				t.Errorf("ParseSince(%q) = %v, want %v", tc.value, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+)
+
+// defaultResolvConf is where the nameservers are read from when a check is
+// built without system.resolv_conf.
+const defaultResolvConf = "/etc/resolv.conf"
+
+// ResolvConf is the resolver configuration read from a resolv.conf file.
+type ResolvConf struct {
+	Nameservers []string
+	Search      []string
+	Options     []string
+}
+
+// parseResolvConf parses the nameserver, search, domain and options lines
+// of a resolv.conf file. Like the system resolver it ignores comments,
+// unknown keywords and nameservers that are not IP addresses, and the last
+// of domain and search wins.
+func parseResolvConf(r io.Reader) (ResolvConf, error) {
+	var conf ResolvConf
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		f := strings.Fields(line)
+		switch {
+		case f[0] == "nameserver" && len(f) > 1:
+			if _, err := netip.ParseAddr(f[1]); err == nil {
+				conf.Nameservers = append(conf.Nameservers, f[1])
+			}
+		case f[0] == "domain" && len(f) > 1:
+			conf.Search = []string{f[1]}
+		case f[0] == "search":
+			conf.Search = f[1:]
+		case f[0] == "options":
+			conf.Options = append(conf.Options, f[1:]...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ResolvConf{}, err
+	}
+	return conf, nil
+}
+
+// readResolvConf reads and parses the resolv.conf file at path.
+func readResolvConf(path string) (ResolvConf, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ResolvConf{}, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	conf, err := parseResolvConf(f)
+	if err != nil {
+		return ResolvConf{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return conf, nil
+}
+
+// String lists the nameservers, followed by the search domains and the
+// options when there are any.
+func (c ResolvConf) String() string {
+	s := "none"
+	if len(c.Nameservers) > 0 {
+		s = strings.Join(c.Nameservers, ", ")
+	}
+	var extra []string
+	if len(c.Search) > 0 {
+		extra = append(extra, "search: "+strings.Join(c.Search, " "))
+	}
+	if len(c.Options) > 0 {
+		extra = append(extra, "options: "+strings.Join(c.Options, " "))
+	}
+	if len(extra) > 0 {
+		s += " (" + strings.Join(extra, "; ") + ")"
+	}
+	return s
+}
+
+// fields returns the configuration as event fields.
+func (c ResolvConf) fields() map[string]any {
+	return map[string]any{
+		"nameservers": c.Nameservers,
+		"search":      c.Search,
+		"options":     c.Options,
+	}
+}
+
+// diffResolvConf describes what changed from prev to next, e.g.
+// "nameserver 1.1.1.1 added", "search lan -> corp.example". It returns
+// nothing when the configuration is the same.
+func diffResolvConf(prev, next ResolvConf) []string {
+	var changes []string
+	for _, ns := range next.Nameservers {
+		if !slices.Contains(prev.Nameservers, ns) {
+			changes = append(changes, fmt.Sprintf("nameserver %s added", ns))
+		}
+	}
+	for _, ns := range prev.Nameservers {
+		if !slices.Contains(next.Nameservers, ns) {
+			changes = append(changes, fmt.Sprintf("nameserver %s removed", ns))
+		}
+	}
+	// The resolver tries the nameservers in order, so a new order matters
+	if len(changes) == 0 && !slices.Equal(prev.Nameservers, next.Nameservers) {
+		changes = append(changes, fmt.Sprintf("nameservers reordered: %s", strings.Join(next.Nameservers, ", ")))
+	}
+
+	list := func(s []string) string {
+		if len(s) == 0 {
+			return "(none)"
+		}
+		return strings.Join(s, " ")
+	}
+	if !slices.Equal(prev.Search, next.Search) {
+		changes = append(changes, fmt.Sprintf("search %s -> %s", list(prev.Search), list(next.Search)))
+	}
+	if !slices.Equal(prev.Options, next.Options) {
+		changes = append(changes, fmt.Sprintf("options %s -> %s", list(prev.Options), list(next.Options)))
+	}
+	return changes
+}
@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseResolvConf(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data string
+		want ResolvConf
+	}{
+		{name: "empty"},
+		{
+			name: "typical",
+			data: "# Generated by NetworkManager\nsearch lan\nnameserver 192.168.1.1\nnameserver 2001:db8::1\n",
+			want: ResolvConf{Nameservers: []string{"192.168.1.1", "2001:db8::1"}, Search: []string{"lan"}},
+		},
+		{
+			name: "comments, blank lines and unknown keywords",
+			data: "; comment\n\n   \nsortlist 130.155.160.0/255.255.240.0\n  nameserver   1.1.1.1  \nlookup file bind\n",
+			want: ResolvConf{Nameservers: []string{"1.1.1.1"}},
+		},
+		{
+			name: "invalid nameservers",
+			data: "nameserver\nnameserver dns.example\nnameserver 1.1.1.1\nnameserver fe80::1%eth0\n",
+			want: ResolvConf{Nameservers: []string{"1.1.1.1", "fe80::1%eth0"}},
+		},
+		{
+			name: "last of domain and search wins",
+			data: "search a.example b.example\ndomain c.example\n",
+			want: ResolvConf{Search: []string{"c.example"}},
+		},
+		{
+			name: "search after domain",
+			data: "domain c.example\nsearch a.example b.example\n",
+			want: ResolvConf{Search: []string{"a.example", "b.example"}},
+		},
+		{
+			name: "options accumulate",
+			data: "options ndots:2\noptions timeout:1 rotate\n",
+			want: ResolvConf{Options: []string{"ndots:2", "timeout:1", "rotate"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseResolvConf(strings.NewReader(tc.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got.Nameservers, tc.want.Nameservers) || !slices.Equal(got.Search, tc.want.Search) ||
+				!slices.Equal(got.Options, tc.want.Options) {
+				t.Errorf("parseResolvConf() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadResolvConf(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	writeFile(t, path, "nameserver 9.9.9.9\n")
+	conf, err := readResolvConf(path)
+	if err != nil || !slices.Equal(conf.Nameservers, []string{"9.9.9.9"}) {
+		t.Fatalf("readResolvConf() = %+v, %v, want 9.9.9.9", conf, err)
+	}
+	if _, err := readResolvConf(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatal("readResolvConf() of a missing file returned no error")
+	}
+}
+
+func TestDiffResolvConf(t *testing.T) {
+	base := ResolvConf{Nameservers: []string{"1.1.1.1", "8.8.8.8"}, Search: []string{"lan"}, Options: []string{"ndots:1"}}
+	for _, tc := range []struct {
+		name string
+		next ResolvConf
+		want []string
+	}{
+		{name: "same", next: base},
+		{
+			name: "nameserver added and removed",
+			next: ResolvConf{Nameservers: []string{"1.1.1.1", "9.9.9.9"}, Search: []string{"lan"}, Options: []string{"ndots:1"}},
+			want: []string{"nameserver 9.9.9.9 added", "nameserver 8.8.8.8 removed"},
+		},
+		{
+			name: "reordered",
+			next: ResolvConf{Nameservers: []string{"8.8.8.8", "1.1.1.1"}, Search: []string{"lan"}, Options: []string{"ndots:1"}},
+			want: []string{"nameservers reordered: 8.8.8.8, 1.1.1.1"},
+		},
+		{
+			name: "search and options",
+			next: ResolvConf{Nameservers: []string{"1.1.1.1", "8.8.8.8"}, Search: []string{"corp.example", "lan"}},
+			want: []string{"search lan -> corp.example lan", "options ndots:1 -> (none)"},
+		},
+		{
+			name: "everything removed",
+			next: ResolvConf{},
+			want: []string{"nameserver 1.1.1.1 removed", "nameserver 8.8.8.8 removed", "search lan -> (none)",
+				"options ndots:1 -> (none)"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := diffResolvConf(base, tc.next); !slices.Equal(got, tc.want) {
+				t.Errorf("diffResolvConf() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package monitor
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotationOptions controls when the log file is rotated and how many rotated
+// segments are kept. The zero value disables rotation.
+type RotationOptions struct {
+	// MaxSize rotates the file before a write would grow it past this many bytes.
+	MaxSize int64
+	// Daily rotates the file at the first write after local midnight.
+	Daily bool
+	// MaxBackups is the number of rotated segments to keep (0 keeps all).
+	MaxBackups int
+	// Compress gzips rotated segments.
+	Compress bool
+}
+
+const (
+	backupTimeLayout = "2006-01-02T15-04-05.000"
+	dayLayout        = "2006-01-02"
+	compressSuffix   = ".gz"
+)
+
+// rotatingFile is an append-only log file that rotates itself according to
+// RotationOptions. Rotated segments are renamed to
+// "<name>-<timestamp><ext>" next to the active file and optionally gzipped.
+type rotatingFile struct {
+	path string
+	opts RotationOptions
+
+	file *os.File
+	size int64
+	day  string
+
+	// millMu serializes compression and pruning of rotated segments,
+	// which run in the background so rotation never blocks writers.
+	millMu sync.Mutex
+	millWg sync.WaitGroup
+}
+
+func openRotatingFile(path string, opts RotationOptions) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, opts: opts}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	r.file = file
+	r.size = info.Size()
+	r.day = info.ModTime().Format(dayLayout)
+	if r.size == 0 {
+		r.day = time.Now().Format(dayLayout)
+	}
+	return nil
+}
+
+// Write implements io.Writer, rotating first when a trigger fires. When
+// rotation fails, p is still written to the unrotated file and the
+// rotation error is returned.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	var rotateErr error
+	if r.shouldRotate(len(p)) {
+		rotateErr = r.rotate()
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	if err == nil {
+		err = rotateErr
+	}
+	return n, err
+}
+
+func (r *rotatingFile) shouldRotate(pending int) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.opts.MaxSize > 0 && r.size+int64(pending) > r.opts.MaxSize {
+		return true
+	}
+	return r.opts.Daily && time.Now().Format(dayLayout) != r.day
+}
+
+// rotate renames the active file to a backup and opens a new one. The
+// file is closed first, as Windows cannot rename open files; when the
+// rotation fails it is reopened, so logging goes on in the unrotated file.
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return r.reopen(fmt.Errorf("failed to close log file for rotation: %w", err))
+	}
+
+	backup := backupName(r.path, time.Now())
+	if err := os.Rename(r.path, backup); err != nil {
+		return r.reopen(fmt.Errorf("failed to rotate log file: %w", err))
+	}
+
+	if err := r.open(); err != nil {
+		return err
+	}
+
+	r.millWg.Add(1)
+	go func() {
+		defer r.millWg.Done()
+		r.mill(backup)
+	}()
+
+	return nil
+}
+
+// reopen opens the active file again after a failed rotation and returns
+// err, joined with the error of opening the file if that fails too.
+func (r *rotatingFile) reopen(err error) error {
+	if openErr := r.open(); openErr != nil {
+		return errors.Join(err, openErr)
+	}
+	return err
+}
+
+// mill compresses a freshly rotated segment and prunes old segments.
+// Errors are ignored: a segment left uncompressed or unpruned is harmless.
+func (r *rotatingFile) mill(backup string) {
+	r.millMu.Lock()
+	defer r.millMu.Unlock()
+
+	if r.opts.Compress {
+		_ = compressFile(backup)
+	}
+
+	if r.opts.MaxBackups <= 0 {
+		return
+	}
+	backups, err := rotatedSegments(r.path)
+	if err != nil || len(backups) <= r.opts.MaxBackups {
+		return
+	}
+	for _, old := range backups[:len(backups)-r.opts.MaxBackups] {
+		_ = os.Remove(old)
+	}
+}
+
+// Close waits for background compression and closes the active file.
+func (r *rotatingFile) Close() error {
+	r.millWg.Wait()
+	return r.file.Close()
+}
+
+// backupName returns an unused name for a segment rotated at t, bumping the
+// timestamp by a millisecond if several rotations happen within one.
+func backupName(path string, t time.Time) string {
+	dir := filepath.Dir(path)
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(filepath.Base(path), ext)
+	for {
+		name := filepath.Join(dir, fmt.Sprintf("%s-%s%s", base, t.Format(backupTimeLayout), ext))
+		_, errPlain := os.Stat(name)
+		_, errGzip := os.Stat(name + compressSuffix)
+		if os.IsNotExist(errPlain) && os.IsNotExist(errGzip) {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+// compressFile gzips src to src.gz and removes src. The archive is written
+// under a temporary name first so readers never see a partial segment.
+func compressFile(src string) error {
+	in, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	dst := src + compressSuffix
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(filepath.Clean(tmp), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		_ = out.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, dst); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Remove(src)
+}
+
+// rotatedSegments lists the rotated segments of the log at path, oldest first.
+// When both a plain and a compressed copy of a segment exist (compression in
+// progress), only the plain one is returned.
+func rotatedSegments(path string) ([]string, error) {
+	dir := filepath.Dir(path)
+	ext := filepath.Ext(path)
+	prefix := strings.TrimSuffix(filepath.Base(path), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	stamps := make(map[string]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stem := strings.TrimSuffix(name, compressSuffix)
+		if !strings.HasSuffix(stem, ext) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(stem, prefix), ext)
+		if _, err := time.Parse(backupTimeLayout, stamp); err != nil {
+			continue
+		}
+		if existing, ok := stamps[stamp]; ok && !strings.HasSuffix(existing, compressSuffix) {
+			continue
+		}
+		stamps[stamp] = filepath.Join(dir, name)
+	}
+
+	keys := make([]string, 0, len(stamps))
+	for stamp := range stamps {
+		keys = append(keys, stamp)
+	}
+	sort.Strings(keys)
+
+	segments := make([]string, 0, len(keys))
+	for _, stamp := range keys {
+		segments = append(segments, stamps[stamp])
+	}
+	return segments, nil
+}
+
+// LogSegments returns every segment of the log at path, oldest first: the
+// rotated segments followed by the active file if it exists. Compressed
+// segments end in ".gz"; OpenLogSegment decompresses them transparently.
+func LogSegments(path string) ([]string, error) {
+	path = filepath.Clean(path)
+	segments, err := rotatedSegments(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log segments: %w", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		segments = append(segments, path)
+	}
+	return segments, nil
+}
+
+// OpenLogSegment opens a segment returned by LogSegments for reading,
+// decompressing gzipped segments on the fly.
+func OpenLogSegment(path string) (io.ReadCloser, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, compressSuffix) {
+		return file, nil
+	}
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to read compressed segment %s: %w", path, err)
+	}
+	return &gzipSegment{Reader: gz, file: file}, nil
+}
+
+type gzipSegment struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g *gzipSegment) Close() error {
+	_ = g.Reader.Close()
+	return g.file.Close()
+}
@@ -0,0 +1,157 @@
+package monitor
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackupName(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "network.log")
+	at := time.Date(2025, 12, 12, 10, 15, 32, 512_000_000, time.Local)
+
+	want := filepath.Join(dir, "network-2025-12-12T10-15-32.512.log")
+	if got := backupName(path, at); got != want {
+		t.Fatalf("backupName() = %q, want %q", got, want)
+	}
+
+	// Taken names, plain or compressed, bump the timestamp
+	writeFile(t, want, "")
+	writeFile(t, filepath.Join(dir, "network-2025-12-12T10-15-32.513.log.gz"), "")
+	want = filepath.Join(dir, "network-2025-12-12T10-15-32.514.log")
+	if got := backupName(path, at); got != want {
+		t.Fatalf("backupName() with taken names = %q, want %q", got, want)
+	}
+}
+
+func TestRotatedSegments(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "network.log")
+	for _, name := range []string{
+		"network.log",
+		"network-2025-12-12T10-00-00.000.log.gz",
+		"network-2025-12-11T10-00-00.000.log",
+		"network-2025-12-13T10-00-00.000.log",
+		"network-2025-12-13T10-00-00.000.log.gz", // compression in progress
+		"network-garbage.log",
+		"network-2025-12-14T10-00-00.000.txt",
+		"other-2025-12-10T10-00-00.000.log",
+	} {
+		writeFile(t, filepath.Join(dir, name), "")
+	}
+
+	got, err := rotatedSegments(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "network-2025-12-11T10-00-00.000.log"),
+		filepath.Join(dir, "network-2025-12-12T10-00-00.000.log.gz"),
+		filepath.Join(dir, "network-2025-12-13T10-00-00.000.log"),
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("rotatedSegments() = %q, want %q", got, want)
+	}
+}
+
+func TestRotatingFileRetention(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		maxBackups int
+		compress   bool
+		wantKept   int
+	}{
+		{name: "keep all", maxBackups: 0, wantKept: 4},
+		{name: "prune", maxBackups: 2, wantKept: 2},
+		{name: "prune compressed", maxBackups: 2, compress: true, wantKept: 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "network.log")
+			r, err := openRotatingFile(path, RotationOptions{MaxSize: 10, MaxBackups: tc.maxBackups, Compress: tc.compress})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, line := range []string{"line 1\n", "line 2\n", "line 3\n", "line 4\n", "line 5\n"} {
+				if _, err := r.Write([]byte(line)); err != nil {
+					t.Fatalf("Write(%q): %v", line, err)
+				}
+			}
+			if err := r.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			segments, err := rotatedSegments(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(segments) != tc.wantKept {
+				t.Fatalf("kept %d segments %q, want %d", len(segments), segments, tc.wantKept)
+			}
+			for _, s := range segments {
+				if strings.HasSuffix(s, compressSuffix) != tc.compress {
+					t.Errorf("segment %s: compressed = %v, want %v", s, !tc.compress, tc.compress)
+				}
+			}
+			// The newest segments survive
+			last := readSegment(t, segments[len(segments)-1])
+			if last != "line 4\n" {
+				t.Errorf("newest segment holds %q, want %q", last, "line 4\n")
+			}
+			if active := readSegment(t, path); active != "line 5\n" {
+				t.Errorf("active file holds %q, want %q", active, "line 5\n")
+			}
+		})
+	}
+}
+
+func TestRotatingFileContinuesAfterFailedRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "network.log")
+	r, err := openRotatingFile(path, RotationOptions{MaxSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = r.Close() }()
+
+	if _, err := r.Write([]byte("line 1\n")); err != nil {
+		t.Fatal(err)
+	}
+	// Renaming a file that is gone fails
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Write([]byte("line 2\n")); err == nil {
+		t.Fatal("Write() after a failed rotation returned no error")
+	}
+	if _, err := r.Write([]byte("x\n")); err != nil {
+		t.Fatalf("Write() after the failed rotation: %v", err)
+	}
+	if got, want := readSegment(t, path), "line 2\nx\n"; got != want {
+		t.Fatalf("active file holds %q, want %q", got, want)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readSegment(t *testing.T, path string) string {
+	t.Helper()
+	f, err := OpenLogSegment(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,460 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConnState is the overall connectivity state derived from all monitors.
+type ConnState string
+
+// Connectivity states. StateUnknown holds until the first probe completes.
+const (
+	StateUnknown  ConnState = "UNKNOWN"
+	StateOnline   ConnState = "ONLINE"
+	StateDegraded ConnState = "DEGRADED"
+	StateOffline  ConnState = "OFFLINE"
+)
+
+// signalOrder fixes the order in which failing signals are listed in reasons.
+var signalOrder = []Signal{
+	SignalLink, SignalRoute, SignalTCP, SignalTCPHealth, SignalDNS, SignalNameservers, SignalHTTP,
+	SignalCaptivePortal, SignalRoute6, SignalTCP6, SignalTCPHealth6, SignalHTTP6,
+}
+
+// ipFamily names the signals that make up the health of one IP family.
+// HTTP is only checked per family for IPv6; the main HTTP check uses
+// whichever family the system prefers.
+type ipFamily struct {
+	name                     string
+	route, tcp, health, http Signal
+}
+
+var ipFamilies = []ipFamily{
+	{name: "IPv4", route: SignalRoute, tcp: SignalTCP, health: SignalTCPHealth},
+	{name: "IPv6", route: SignalRoute6, tcp: SignalTCP6, health: SignalTCPHealth6, http: SignalHTTP6},
+}
+
+// FamilyStatus is the connectivity state of one IP family.
+type FamilyStatus struct {
+	Family  string    `json:"family"`
+	State   ConnState `json:"state"`
+	Since   time.Time `json:"since"`
+	Reasons []string  `json:"reasons,omitempty"`
+}
+
+type signalStatus struct {
+	status Status
+	reason string
+}
+
+// Outage is a period during which connectivity was not ONLINE. Worst is
+// the most severe state reached; Cause is filled in by the classifier.
+type Outage struct {
+	ID      int
+	Start   time.Time
+	Worst   ConnState
+	Reasons []string
+	Cause   OutageCause
+	Detail  string
+}
+
+// StateTracker combines the link, route, TCP keepalive and watchdog signals
+// carried by events into a single connectivity state and emits a STATE event
+// on every transition. Leaving ONLINE opens an outage, which is classified in
+// the background and closed when the state returns to ONLINE.
+//
+// IPv4 and IPv6 are also tracked separately, with an IP event on every
+// transition, so one family failing while the other works is reported as
+// such. IPv6 failures only count towards the overall state on hosts that
+// have IPv6: while there is an IPv6 default route, or once there was one.
+type StateTracker struct {
+	mu         sync.Mutex
+	ctx        context.Context
+	logger     *Logger
+	classifier *OutageClassifier
+	state      ConnState
+	since      time.Time
+	reasons    []string
+	links      map[string]bool
+	signals    map[Signal]signalStatus
+	families   map[string]*FamilyStatus
+	hadIPv6    bool // an IPv6 default route was seen
+	outage     *Outage
+	outageSeq  int
+}
+
+// NewStateTracker creates a tracker that reports transitions through logger
+// and diagnoses outages with classifier.
+func NewStateTracker(ctx context.Context, logger *Logger, classifier *OutageClassifier) *StateTracker {
+	return &StateTracker{
+		ctx:        ctx,
+		logger:     logger,
+		classifier: classifier,
+		state:      StateUnknown,
+		since:      time.Now(),
+		links:      make(map[string]bool),
+		signals:    make(map[Signal]signalStatus),
+		families:   make(map[string]*FamilyStatus, len(ipFamilies)),
+	}
+}
+
+// HandleEvent updates the tracker from an event; events without a signal
+// and events of probes pinned to an uplink are ignored. It is meant to be
+// registered with Logger.AddHook.
+func (t *StateTracker) HandleEvent(e Event) {
+	if e.Signal == "" || e.Status == "" || e.Uplink != "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.record(e)
+
+	for _, f := range ipFamilies {
+		next, reasons := t.evaluateFamily(f)
+		t.updateFamily(f.name, next, reasons, e.Time)
+	}
+
+	next, reasons := t.evaluate()
+	if next == t.state {
+		// A different set of reasons within the same state is not a transition
+		t.reasons = reasons
+		return
+	}
+
+	t.transition(next, reasons, e.Time)
+}
+
+// SetClassifier replaces the classifier used for outages diagnosed from now
+// on; the current state and any open outage are kept.
+func (t *StateTracker) SetClassifier(classifier *OutageClassifier) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.classifier = classifier
+}
+
+// StateSnapshot describes the connectivity state at a point in time.
+// Outage is set while an outage is open; Families holds the state of each
+// IP family once its probes have reported.
+type StateSnapshot struct {
+	State    ConnState
+	Since    time.Time
+	Reasons  []string
+	Outage   *Outage
+	Families []FamilyStatus
+}
+
+// Snapshot returns the current connectivity state, when it was entered,
+// the reasons it is not ONLINE and the open outage, if any.
+func (t *StateTracker) Snapshot() StateSnapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := StateSnapshot{
+		State:   t.state,
+		Since:   t.since,
+		Reasons: append([]string(nil), t.reasons...),
+	}
+	for _, f := range ipFamilies {
+		if fs, ok := t.families[f.name]; ok {
+			c := *fs
+			c.Reasons = append([]string(nil), fs.Reasons...)
+			s.Families = append(s.Families, c)
+		}
+	}
+	if t.outage != nil {
+		o := *t.outage
+		o.Reasons = append([]string(nil), o.Reasons...)
+		s.Outage = &o
+	}
+	return s
+}
+
+func (t *StateTracker) record(e Event) {
+	if e.Signal == SignalLink && e.Interface != "" {
+		// The link is usable while any interface is up
+		t.links[e.Interface] = e.Status == StatusOK
+		status := signalStatus{status: StatusFail, reason: "link_down"}
+		for _, up := range t.links {
+			if up {
+				status = signalStatus{status: StatusOK}
+				break
+			}
+		}
+		t.signals[SignalLink] = status
+		return
+	}
+
+	if e.Signal == SignalRoute6 && e.Status == StatusOK {
+		t.hadIPv6 = true
+	}
+
+	reason := e.Reason
+	if reason == "" && e.Status != StatusOK {
+		reason = fmt.Sprintf("%s_%s", e.Signal, e.Status)
+	}
+	t.signals[e.Signal] = signalStatus{status: e.Status, reason: reason}
+}
+
+// evaluate derives the state from the latest signals. The host is offline
+// when it has no link or route, or when both the persistent TCP connection
+// and HTTP fail; any other failure or warning makes it degraded. The state
+// stays unknown until the TCP or HTTP probe has reported, so the initial
+// interface enumeration cannot cause spurious transitions.
+func (t *StateTracker) evaluate() (ConnState, []string) {
+	failed := func(s Signal) bool { return t.signals[s].status == StatusFail }
+
+	_, tcpKnown := t.signals[SignalTCP]
+	_, httpKnown := t.signals[SignalHTTP]
+	if !tcpKnown && !httpKnown {
+		return StateUnknown, nil
+	}
+
+	var reasons []string
+	for _, signal := range t.orderedSignals() {
+		if st := t.signals[signal]; st.status != StatusOK && t.counts(signal) {
+			reasons = append(reasons, st.reason)
+		}
+	}
+
+	switch {
+	case failed(SignalLink) || failed(SignalRoute) || (failed(SignalTCP) && failed(SignalHTTP)):
+		return StateOffline, reasons
+	case len(reasons) > 0:
+		return StateDegraded, reasons
+	default:
+		return StateOnline, nil
+	}
+}
+
+// counts reports whether a failing signal counts towards the overall state.
+// Losing the IPv6 default route counts once the host had one; the other
+// IPv6 probes count while there is one, since without it their failures
+// only repeat that IPv6 is unavailable.
+func (t *StateTracker) counts(signal Signal) bool {
+	switch signal {
+	case SignalRoute6:
+		return t.hadIPv6
+	case SignalTCP6, SignalTCPHealth6, SignalHTTP6:
+		return t.signals[SignalRoute6].status == StatusOK
+	default:
+		return true
+	}
+}
+
+// evaluateFamily derives the state of one IP family the way evaluate does
+// for the overall state, from the link and the signals of the family. The
+// family is offline without a route or when its TCP targets are lost.
+func (t *StateTracker) evaluateFamily(f ipFamily) (ConnState, []string) {
+	failed := func(s Signal) bool { return s != "" && t.signals[s].status == StatusFail }
+	known := func(s Signal) bool {
+		_, ok := t.signals[s]
+		return s != "" && ok
+	}
+
+	if !known(f.tcp) && !known(f.http) {
+		return StateUnknown, nil
+	}
+
+	var reasons []string
+	for _, signal := range []Signal{SignalLink, f.route, f.tcp, f.health, f.http} {
+		if st, ok := t.signals[signal]; ok && st.status != StatusOK {
+			reasons = append(reasons, st.reason)
+		}
+	}
+
+	switch {
+	case failed(SignalLink) || failed(f.route) || failed(f.tcp) || (!known(f.tcp) && failed(f.http)):
+		return StateOffline, reasons
+	case len(reasons) > 0:
+		return StateDegraded, reasons
+	default:
+		return StateOnline, nil
+	}
+}
+
+// updateFamily records the state of a family and emits an IP event when it
+// changed.
+func (t *StateTracker) updateFamily(name string, next ConnState, reasons []string, at time.Time) {
+	fs, ok := t.families[name]
+	if !ok {
+		if next == StateUnknown {
+			return
+		}
+		fs = &FamilyStatus{Family: name, State: StateUnknown, Since: at}
+		t.families[name] = fs
+	}
+	if next == fs.State {
+		fs.Reasons = reasons
+		return
+	}
+
+	prev, held := fs.State, at.Sub(fs.Since)
+	fs.State, fs.Since, fs.Reasons = next, at, reasons
+
+	msg := fmt.Sprintf("%s %s -> %s", name, prev, next)
+	if len(reasons) > 0 {
+		msg += " reason=" + strings.Join(reasons, ",")
+	}
+	msg += fmt.Sprintf(" after=%s", held.Round(time.Millisecond))
+
+	severity := SeverityInfo
+	if next == StateDegraded || next == StateOffline {
+		severity = SeverityWarn
+	}
+
+	t.logger.Emit(Event{
+		Time:     at,
+		Category: CategoryIP,
+		Severity: severity,
+		Source:   SourceMonitor,
+		Message:  msg,
+		Reason:   strings.Join(reasons, ","),
+		Duration: held,
+		Fields:   map[string]any{"family": name, "from": string(prev), "to": string(next)},
+	})
+}
+
+// orderedSignals lists known signals, core signals first, then any others by name.
+func (t *StateTracker) orderedSignals() []Signal {
+	signals := make([]Signal, 0, len(t.signals))
+	seen := make(map[Signal]bool, len(signalOrder))
+	for _, signal := range signalOrder {
+		seen[signal] = true
+		if _, ok := t.signals[signal]; ok {
+			signals = append(signals, signal)
+		}
+	}
+
+	var extra []Signal
+	for signal := range t.signals {
+		if !seen[signal] {
+			extra = append(extra, signal)
+		}
+	}
+	sort.Slice(extra, func(i, j int) bool { return extra[i] < extra[j] })
+
+	return append(signals, extra...)
+}
+
+func (t *StateTracker) transition(next ConnState, reasons []string, at time.Time) {
+	prev, prevSince := t.state, t.since
+	t.state, t.since, t.reasons = next, at, reasons
+
+	held := at.Sub(prevSince)
+	msg := fmt.Sprintf("STATE %s -> %s", prev, next)
+	if len(reasons) > 0 {
+		msg += " reason=" + strings.Join(reasons, ",")
+	}
+	msg += fmt.Sprintf(" after=%s", held.Round(time.Millisecond))
+
+	severity := SeverityInfo
+	switch next {
+	case StateOffline:
+		severity = SeverityCritical
+	case StateDegraded:
+		severity = SeverityWarn
+	}
+
+	t.logger.Emit(Event{
+		Time:     at,
+		Category: CategoryState,
+		Severity: severity,
+		Source:   SourceMonitor,
+		Message:  msg,
+		Reason:   strings.Join(reasons, ","),
+		Duration: held,
+		Fields:   map[string]any{"from": string(prev), "to": string(next)},
+	})
+
+	broken := next == StateDegraded || next == StateOffline
+	switch {
+	case next == StateOnline && t.outage != nil:
+		t.endOutage(at)
+	case broken && t.outage == nil:
+		t.beginOutage(next, reasons, at)
+	case next == StateOffline && t.outage != nil && t.outage.Worst != StateOffline:
+		// Escalation may point at a different layer; classify again
+		t.outage.Worst = StateOffline
+		t.classify(t.outage.ID)
+	}
+}
+
+func (t *StateTracker) beginOutage(state ConnState, reasons []string, at time.Time) {
+	t.outageSeq++
+	t.outage = &Outage{ID: t.outageSeq, Start: at, Worst: state, Reasons: reasons}
+
+	t.logger.Emit(Event{
+		Time:     at,
+		Category: CategoryOutage,
+		Severity: SeverityWarn,
+		Source:   SourceMonitor,
+		Reason:   strings.Join(reasons, ","),
+		Message: fmt.Sprintf("OUTAGE #%d started state=%s reason=%s",
+			t.outage.ID, state, strings.Join(reasons, ",")),
+		Fields: map[string]any{"outage_id": t.outage.ID, "state": string(state)},
+	})
+
+	t.classify(t.outage.ID)
+}
+
+func (t *StateTracker) endOutage(at time.Time) {
+	o := t.outage
+	t.outage = nil
+
+	cause := o.Cause
+	if cause == "" {
+		cause = "unclassified"
+	}
+	duration := at.Sub(o.Start)
+
+	t.logger.Emit(Event{
+		Time:     at,
+		Category: CategoryOutage,
+		Severity: SeverityInfo,
+		Source:   SourceMonitor,
+		Duration: duration,
+		Message: fmt.Sprintf("OUTAGE #%d ended after=%s state=%s cause=%s",
+			o.ID, duration.Round(time.Millisecond), o.Worst, cause),
+		Fields: map[string]any{"outage_id": o.ID, "state": string(o.Worst), "cause": string(cause)},
+	})
+}
+
+// classify runs the outage classifier in the background and records the
+// diagnosis on the outage with the given ID.
+func (t *StateTracker) classify(id int) {
+	classifier := t.classifier
+	go func() {
+		d := classifier.Classify(t.ctx)
+		if t.ctx.Err() != nil {
+			return
+		}
+		t.recordCause(id, d)
+	}()
+}
+
+func (t *StateTracker) recordCause(id int, d Diagnosis) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// The outage may have ended while the probes ran; log the cause anyway
+	if t.outage != nil && t.outage.ID == id {
+		t.outage.Cause = d.Cause
+		t.outage.Detail = d.Detail
+	}
+
+	t.logger.Emit(Event{
+		Category: CategoryOutage,
+		Severity: SeverityWarn,
+		Source:   SourceMonitor,
+		Gateway:  d.Gateway,
+		Message:  fmt.Sprintf("OUTAGE #%d cause=%s (%s)", id, d.Cause, d.Detail),
+		Fields:   map[string]any{"outage_id": id, "cause": string(d.Cause), "detail": d.Detail},
+	})
+}
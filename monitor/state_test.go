@@ -0,0 +1,351 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// eventRecorder collects the events emitted through a logger.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) record(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+// messages returns the messages of the recorded events of category.
+func (r *eventRecorder) messages(category string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var msgs []string
+	for _, e := range r.events {
+		if e.Category == category {
+			msgs = append(msgs, e.Message)
+		}
+	}
+	return msgs
+}
+
+// transitions returns the recorded STATE and IP transitions as
+// "FROM->TO" and "FAMILY FROM->TO".
+func (r *eventRecorder) transitions() (states, families []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.events {
+		switch e.Category {
+		case CategoryState:
+			states = append(states, fmt.Sprintf("%s->%s", e.Fields["from"], e.Fields["to"]))
+		case CategoryIP:
+			families = append(families, fmt.Sprintf("%s %s->%s", e.Fields["family"], e.Fields["from"], e.Fields["to"]))
+		}
+	}
+	return states, families
+}
+
+// newTestStateTracker returns a tracker whose emitted events are recorded.
+// Outages are classified with a cancelled context, so the classifier
+// returns at once and never reports a cause.
+func newTestStateTracker(t *testing.T) (*StateTracker, *eventRecorder) {
+	t.Helper()
+	logger, err := NewLogger(filepath.Join(t.TempDir(), "network.log"), LoggerOptions{MinLevel: SeverityCritical + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = logger.Close() })
+	rec := &eventRecorder{}
+	logger.AddHook(rec.record)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return NewStateTracker(ctx, logger, NewOutageClassifier(DefaultConfig())), rec
+}
+
+// signalEvent returns an event reporting status for signal, with reason
+// unless it is empty.
+func signalEvent(signal Signal, status Status, reason string) Event {
+	return Event{Signal: signal, Status: status, Reason: reason}
+}
+
+// linkEvent returns a link event for iface.
+func linkEvent(iface string, status Status) Event {
+	return Event{Signal: SignalLink, Status: status, Interface: iface}
+}
+
+// stateCase feeds events to a tracker and expects the STATE and IP
+// transitions it emits and the state it ends in.
+type stateCase struct {
+	name        string
+	events      []Event
+	wantStates  []string
+	wantIP      []string
+	wantState   ConnState
+	wantReasons []string
+}
+
+func runStateCases(t *testing.T, cases []stateCase) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker, rec := newTestStateTracker(t)
+			start := time.Date(2025, 12, 12, 10, 0, 0, 0, time.UTC)
+			for i, e := range tc.events {
+				e.Time = start.Add(time.Duration(i) * time.Second)
+				tracker.HandleEvent(e)
+			}
+
+			states, families := rec.transitions()
+			if !slices.Equal(states, tc.wantStates) {
+				t.Errorf("STATE transitions = %q, want %q", states, tc.wantStates)
+			}
+			if !slices.Equal(families, tc.wantIP) {
+				t.Errorf("IP transitions = %q, want %q", families, tc.wantIP)
+			}
+			snap := tracker.Snapshot()
+			if snap.State != tc.wantState || !slices.Equal(snap.Reasons, tc.wantReasons) {
+				t.Errorf("Snapshot() = %s %q, want %s %q", snap.State, snap.Reasons, tc.wantState, tc.wantReasons)
+			}
+		})
+	}
+}
+
+func TestStateTrackerEvaluate(t *testing.T) {
+	runStateCases(t, []stateCase{
+		{
+			name: "unknown until tcp or http reports",
+			events: []Event{
+				linkEvent("eth0", StatusOK), signalEvent(SignalRoute, StatusOK, ""),
+				signalEvent(SignalDNS, StatusFail, "dns_failed"),
+			},
+			wantState: StateUnknown,
+		},
+		{
+			name:       "online",
+			events:     []Event{signalEvent(SignalRoute, StatusOK, ""), signalEvent(SignalTCP, StatusOK, "")},
+			wantStates: []string{"UNKNOWN->ONLINE"},
+			wantIP:     []string{"IPv4 UNKNOWN->ONLINE"},
+			wantState:  StateOnline,
+		},
+		{
+			name:       "http alone decides",
+			events:     []Event{signalEvent(SignalHTTP, StatusOK, "")},
+			wantStates: []string{"UNKNOWN->ONLINE"},
+			wantState:  StateOnline,
+		},
+		{
+			name:        "dns failure degrades",
+			events:      []Event{signalEvent(SignalTCP, StatusOK, ""), signalEvent(SignalDNS, StatusFail, "dns_failed")},
+			wantStates:  []string{"UNKNOWN->ONLINE", "ONLINE->DEGRADED"},
+			wantIP:      []string{"IPv4 UNKNOWN->ONLINE"},
+			wantState:   StateDegraded,
+			wantReasons: []string{"dns_failed"},
+		},
+		{
+			name:        "warning degrades with a default reason",
+			events:      []Event{signalEvent(SignalTCP, StatusOK, ""), signalEvent(SignalTCPHealth, StatusWarn, "")},
+			wantStates:  []string{"UNKNOWN->ONLINE", "ONLINE->DEGRADED"},
+			wantIP:      []string{"IPv4 UNKNOWN->ONLINE", "IPv4 ONLINE->DEGRADED"},
+			wantState:   StateDegraded,
+			wantReasons: []string{"tcp_health_warn"},
+		},
+		{
+			name: "offline when tcp and http fail, back online in steps",
+			events: []Event{
+				signalEvent(SignalTCP, StatusFail, "tcp_quorum_lost"),
+				signalEvent(SignalHTTP, StatusFail, "http_failed"),
+				signalEvent(SignalTCP, StatusOK, ""),
+				signalEvent(SignalHTTP, StatusOK, ""),
+			},
+			wantStates: []string{"UNKNOWN->DEGRADED", "DEGRADED->OFFLINE", "OFFLINE->DEGRADED", "DEGRADED->ONLINE"},
+			wantIP:     []string{"IPv4 UNKNOWN->OFFLINE", "IPv4 OFFLINE->ONLINE"},
+			wantState:  StateOnline,
+		},
+		{
+			name:        "offline without a default route",
+			events:      []Event{signalEvent(SignalTCP, StatusOK, ""), signalEvent(SignalRoute, StatusFail, "no_route")},
+			wantStates:  []string{"UNKNOWN->ONLINE", "ONLINE->OFFLINE"},
+			wantIP:      []string{"IPv4 UNKNOWN->ONLINE", "IPv4 ONLINE->OFFLINE"},
+			wantState:   StateOffline,
+			wantReasons: []string{"no_route"},
+		},
+		{
+			name: "offline once every interface is down",
+			events: []Event{
+				linkEvent("eth0", StatusOK), linkEvent("wlan0", StatusOK),
+				signalEvent(SignalTCP, StatusOK, ""),
+				linkEvent("eth0", StatusFail), linkEvent("wlan0", StatusFail),
+			},
+			wantStates:  []string{"UNKNOWN->ONLINE", "ONLINE->OFFLINE"},
+			wantIP:      []string{"IPv4 UNKNOWN->ONLINE", "IPv4 ONLINE->OFFLINE"},
+			wantState:   StateOffline,
+			wantReasons: []string{"link_down"},
+		},
+		{
+			name: "reasons change within a state",
+			events: []Event{
+				signalEvent(SignalTCP, StatusOK, ""),
+				signalEvent(Signal("custom"), StatusFail, ""),
+				signalEvent(SignalHTTP, StatusFail, "http_failed"),
+				signalEvent(SignalDNS, StatusFail, "dns_failed"),
+			},
+			wantStates:  []string{"UNKNOWN->ONLINE", "ONLINE->DEGRADED"},
+			wantIP:      []string{"IPv4 UNKNOWN->ONLINE"},
+			wantState:   StateDegraded,
+			wantReasons: []string{"dns_failed", "http_failed", "custom_fail"},
+		},
+		{
+			name: "uplink probes and events without a status are ignored",
+			events: []Event{
+				signalEvent(SignalTCP, StatusOK, ""),
+				{Signal: SignalTCP, Status: StatusFail, Reason: "tcp_quorum_lost", Uplink: "wan"},
+				{Signal: SignalRoute, Message: "route added"},
+			},
+			wantStates: []string{"UNKNOWN->ONLINE"},
+			wantIP:     []string{"IPv4 UNKNOWN->ONLINE"},
+			wantState:  StateOnline,
+		},
+	})
+}
+
+func TestStateTrackerOutage(t *testing.T) {
+	tracker, rec := newTestStateTracker(t)
+	for _, e := range []Event{
+		signalEvent(SignalTCP, StatusOK, ""),
+		signalEvent(SignalDNS, StatusFail, "dns_failed"),
+		signalEvent(SignalHTTP, StatusFail, "http_failed"),
+	} {
+		tracker.HandleEvent(e)
+	}
+	o := tracker.Snapshot().Outage
+	if o == nil || o.ID != 1 || o.Worst != StateDegraded || !slices.Equal(o.Reasons, []string{"dns_failed"}) {
+		t.Fatalf("Snapshot().Outage = %+v, want #1 degraded by dns_failed", o)
+	}
+
+	// Escalating to offline keeps the outage open
+	tracker.HandleEvent(signalEvent(SignalTCP, StatusFail, "tcp_quorum_lost"))
+	if o := tracker.Snapshot().Outage; o == nil || o.ID != 1 || o.Worst != StateOffline {
+		t.Fatalf("Snapshot().Outage = %+v, want #1 escalated to offline", o)
+	}
+	tracker.recordCause(1, Diagnosis{Cause: CauseDNS, Detail: "resolving failed"})
+	if o := tracker.Snapshot().Outage; o == nil || o.Cause != CauseDNS || o.Detail != "resolving failed" {
+		t.Fatalf("Snapshot().Outage = %+v, want the cause recorded", o)
+	}
+
+	for _, e := range []Event{
+		signalEvent(SignalTCP, StatusOK, ""),
+		signalEvent(SignalHTTP, StatusOK, ""),
+		signalEvent(SignalDNS, StatusOK, ""),
+	} {
+		tracker.HandleEvent(e)
+	}
+	if o := tracker.Snapshot().Outage; o != nil {
+		t.Fatalf("Snapshot().Outage = %+v after recovery, want none", o)
+	}
+
+	// A diagnosis that arrives after the outage ended is still logged, and
+	// does not attach to the next outage
+	tracker.recordCause(1, Diagnosis{Cause: CauseUpstream, Detail: "late"})
+	tracker.HandleEvent(signalEvent(SignalDNS, StatusFail, "dns_failed"))
+	tracker.recordCause(1, Diagnosis{Cause: CauseUpstream, Detail: "later"})
+	if o := tracker.Snapshot().Outage; o == nil || o.ID != 2 || o.Cause != "" {
+		t.Fatalf("Snapshot().Outage = %+v, want #2 without a cause", o)
+	}
+
+	want := []string{
+		"OUTAGE #1 started state=DEGRADED reason=dns_failed",
+		"OUTAGE #1 cause=dns_failure (resolving failed)",
+		"OUTAGE #1 ended after=",
+		"OUTAGE #1 cause=upstream_unreachable (late)",
+		"OUTAGE #2 started state=DEGRADED reason=dns_failed",
+		"OUTAGE #1 cause=upstream_unreachable (later)",
+	}
+	got := rec.messages(CategoryOutage)
+	if len(got) != len(want) {
+		t.Fatalf("OUTAGE events = %q, want %d", got, len(want))
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("OUTAGE event %d = %q, want prefix %q", i, got[i], want[i])
+		}
+	}
+	if !strings.HasSuffix(got[2], "state=OFFLINE cause=dns_failure") {
+		t.Errorf("OUTAGE end = %q, want the worst state and cause", got[2])
+	}
+}
+
+func TestStateTrackerIPv6(t *testing.T) {
+	runStateCases(t, []stateCase{
+		{
+			name: "ignored on a host without IPv6",
+			events: []Event{
+				signalEvent(SignalRoute6, StatusFail, "no_route6"),
+				signalEvent(SignalTCP, StatusOK, ""),
+				signalEvent(SignalTCP6, StatusFail, "tcp6_quorum_lost"),
+				signalEvent(SignalHTTP6, StatusFail, "http6_failed"),
+			},
+			wantStates: []string{"UNKNOWN->ONLINE"},
+			wantIP:     []string{"IPv4 UNKNOWN->ONLINE", "IPv6 UNKNOWN->OFFLINE"},
+			wantState:  StateOnline,
+		},
+		{
+			name: "probes count while there is a route",
+			events: []Event{
+				signalEvent(SignalRoute6, StatusOK, ""),
+				signalEvent(SignalTCP, StatusOK, ""),
+				signalEvent(SignalTCP6, StatusFail, "tcp6_quorum_lost"),
+			},
+			wantStates:  []string{"UNKNOWN->ONLINE", "ONLINE->DEGRADED"},
+			wantIP:      []string{"IPv4 UNKNOWN->ONLINE", "IPv6 UNKNOWN->OFFLINE"},
+			wantState:   StateDegraded,
+			wantReasons: []string{"tcp6_quorum_lost"},
+		},
+		{
+			name: "losing the route counts once there was one",
+			events: []Event{
+				signalEvent(SignalRoute6, StatusOK, ""),
+				signalEvent(SignalTCP, StatusOK, ""),
+				signalEvent(SignalTCP6, StatusOK, ""),
+				signalEvent(SignalRoute6, StatusFail, "no_route6"),
+				signalEvent(SignalTCP6, StatusFail, "tcp6_quorum_lost"),
+			},
+			wantStates:  []string{"UNKNOWN->ONLINE", "ONLINE->DEGRADED"},
+			wantIP:      []string{"IPv4 UNKNOWN->ONLINE", "IPv6 UNKNOWN->ONLINE", "IPv6 ONLINE->OFFLINE"},
+			wantState:   StateDegraded,
+			wantReasons: []string{"no_route6"},
+		},
+		{
+			name: "IPv4 fails while IPv6 works",
+			events: []Event{
+				signalEvent(SignalRoute6, StatusOK, ""),
+				signalEvent(SignalTCP, StatusOK, ""),
+				signalEvent(SignalTCP6, StatusOK, ""),
+				signalEvent(SignalTCP, StatusFail, "tcp_quorum_lost"),
+			},
+			wantStates:  []string{"UNKNOWN->ONLINE", "ONLINE->DEGRADED"},
+			wantIP:      []string{"IPv4 UNKNOWN->ONLINE", "IPv6 UNKNOWN->ONLINE", "IPv4 ONLINE->OFFLINE"},
+			wantState:   StateDegraded,
+			wantReasons: []string{"tcp_quorum_lost"},
+		},
+		{
+			name: "IPv6 offline on HTTP alone without TCP targets",
+			events: []Event{
+				signalEvent(SignalRoute6, StatusOK, ""),
+				signalEvent(SignalHTTP, StatusOK, ""),
+				signalEvent(SignalHTTP6, StatusFail, "http6_failed"),
+			},
+			wantStates:  []string{"UNKNOWN->ONLINE", "ONLINE->DEGRADED"},
+			wantIP:      []string{"IPv6 UNKNOWN->OFFLINE"},
+			wantState:   StateDegraded,
+			wantReasons: []string{"http6_failed"},
+		},
+	})
+}
@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MonitorStatus is a point-in-time view of a running monitor. The daemon
+// writes it to a status file that the status command reads.
+type MonitorStatus struct {
+	PID        int            `json:"pid"`
+	Started    time.Time      `json:"started"`
+	Updated    time.Time      `json:"updated"`
+	ConfigPath string         `json:"config,omitempty"`
+	State      ConnState      `json:"state"`
+	Since      time.Time      `json:"since"`
+	Reasons    []string       `json:"reasons,omitempty"`
+	Families   []FamilyStatus `json:"families,omitempty"`
+	Outage     *OutageStatus  `json:"outage,omitempty"`
+	TCPQuorum  int            `json:"tcp_quorum"`
+	TCPTargets []TargetStatus `json:"tcp_targets"`
+	// TCP6Quorum and TCP6Targets describe the IPv6 connections, if any.
+	TCP6Quorum  int            `json:"tcp6_quorum,omitempty"`
+	TCP6Targets []TargetStatus `json:"tcp6_targets,omitempty"`
+	Uplinks     []UplinkStatus `json:"uplinks,omitempty"`
+}
+
+// OutageStatus describes the open outage in a MonitorStatus.
+type OutageStatus struct {
+	ID      int         `json:"id"`
+	Start   time.Time   `json:"start"`
+	Worst   ConnState   `json:"worst"`
+	Cause   OutageCause `json:"cause,omitempty"`
+	Detail  string      `json:"detail,omitempty"`
+	Reasons []string    `json:"reasons,omitempty"`
+}
+
+// WriteStatusFile atomically replaces the status file at path.
+func WriteStatusFile(path string, s MonitorStatus) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode status: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create status directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write status file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to replace status file: %w", err)
+	}
+	return nil
+}
+
+// ReadStatusFile reads a status file written by WriteStatusFile.
+func ReadStatusFile(path string) (MonitorStatus, error) {
+	var s MonitorStatus
+	data, err := os.ReadFile(path) //nolint:gosec // path is derived from the user's home directory
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("failed to decode status file: %w", err)
+	}
+	return s, nil
+}
@@ -3,26 +3,34 @@ package monitor
 import (
 	"context"
 	"fmt"
+	"net"
 	"runtime"
+	"strings"
+	"sync"
+	"time"
 )
 
 // SystemEventsMonitor handles platform-specific network event monitoring
 type SystemEventsMonitor struct {
 	logger *Logger
 	ctx    context.Context
+	mu     sync.Mutex
+	cfg    SystemConfig
+	resolv *ResolvConf // last configuration logged by logDNSServers
 }
 
 // NewSystemEventsMonitor creates a system events monitor for the given context.
-func NewSystemEventsMonitor(ctx context.Context, logger *Logger) *SystemEventsMonitor {
+func NewSystemEventsMonitor(ctx context.Context, logger *Logger, cfg SystemConfig) *SystemEventsMonitor {
 	return &SystemEventsMonitor{
 		logger: logger,
 		ctx:    ctx,
+		cfg:    cfg,
 	}
 }
 
 // Start begins platform-specific system events monitoring.
 func (m *SystemEventsMonitor) Start() error {
-	m.logger.Log("SYSTEM", "Starting system events monitor")
+	m.emit(Event{Category: CategorySystem, Message: "Starting system events monitor"})
 
 	switch runtime.GOOS {
 	case "linux":
@@ -35,3 +43,99 @@ func (m *SystemEventsMonitor) Start() error {
 		return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
 	}
 }
+
+// Reconfigure applies a new configuration; polling loops pick up new
+// intervals and paths on their next tick.
+func (m *SystemEventsMonitor) Reconfigure(cfg SystemConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cfg = cfg
+}
+
+func (m *SystemEventsMonitor) config() SystemConfig {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cfg
+}
+
+// followInterval resets ticker when the configured interval differs from
+// the one it runs at, tracked in current.
+func followInterval(ticker *time.Ticker, current *time.Duration, configured time.Duration) {
+	if configured != *current {
+		*current = configured
+		ticker.Reset(configured)
+	}
+}
+
+// logDNSServers logs the nameservers, search domains and options of
+// resolv.conf; after the first time, it logs what changed.
+func (m *SystemEventsMonitor) logDNSServers() {
+	path := m.config().ResolvConf
+	conf, err := readResolvConf(path)
+	if err != nil {
+		m.emit(Event{
+			Category: CategoryDNS,
+			Severity: SeverityWarn,
+			Err:      err,
+			Message:  fmt.Sprintf("Cannot read DNS configuration: %v", err),
+		})
+		return
+	}
+
+	m.mu.Lock()
+	prev := m.resolv
+	m.resolv = &conf
+	m.mu.Unlock()
+
+	fields := conf.fields()
+	if prev == nil {
+		severity := SeverityInfo
+		if len(conf.Nameservers) == 0 {
+			severity = SeverityWarn
+		}
+		m.emit(Event{
+			Category: CategoryDNS,
+			Severity: severity,
+			Message:  fmt.Sprintf("DNS servers: %s", conf),
+			Fields:   fields,
+		})
+		return
+	}
+
+	changes := diffResolvConf(*prev, conf)
+	if len(changes) == 0 {
+		m.emit(Event{
+			Category: CategoryDNS,
+			Severity: SeverityDebug,
+			Message:  fmt.Sprintf("%s rewritten without changes to the DNS servers", path),
+			Fields:   fields,
+		})
+		return
+	}
+	fields["changes"] = changes
+	m.emit(Event{
+		Category: CategoryDNS,
+		Message:  fmt.Sprintf("DNS servers changed: %s; now %s", strings.Join(changes, ", "), conf),
+		Fields:   fields,
+	})
+}
+
+// emit stamps the event with the system source and forwards it to the logger.
+func (m *SystemEventsMonitor) emit(e Event) {
+	e.Source = SourceSystem
+	m.logger.Emit(e)
+}
+
+// linkStatus maps interface flags to a link signal status. An interface
+// carries traffic only when it is up and running (has carrier); loopback
+// interfaces report no status so they never count towards connectivity.
+func linkStatus(flags net.Flags) Status {
+	switch {
+	case flags&net.FlagLoopback != 0:
+		return ""
+	case flags&net.FlagUp != 0 && flags&net.FlagRunning != 0:
+		return StatusOK
+	default:
+		return StatusFail
+	}
+}
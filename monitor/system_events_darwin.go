@@ -11,7 +11,7 @@ import (
 )
 
 func (m *SystemEventsMonitor) startDarwin() error {
-	m.logger.Log("SYSTEM", "Starting macOS routing socket monitoring")
+	m.emit(Event{Category: CategorySystem, Message: "Starting macOS routing socket monitoring"})
 
 	// Create routing socket
 	fd, err := unix.Socket(unix.AF_ROUTE, unix.SOCK_RAW, unix.AF_UNSPEC)
@@ -19,7 +19,7 @@ func (m *SystemEventsMonitor) startDarwin() error {
 		return fmt.Errorf("failed to create routing socket: %w", err)
 	}
 
-	m.logger.Log("SYSTEM", "Monitoring network changes via routing socket")
+	m.emit(Event{Category: CategorySystem, Message: "Monitoring network changes via routing socket"})
 	m.logNetworkStateDarwin()
 
 	go func() {
@@ -29,7 +29,7 @@ func (m *SystemEventsMonitor) startDarwin() error {
 		for {
 			select {
 			case <-m.ctx.Done():
-				m.logger.Log("SYSTEM", "Stopped macOS routing socket monitoring")
+				m.emit(Event{Category: CategorySystem, Message: "Stopped macOS routing socket monitoring"})
 				return
 			default:
 				n, err := unix.Read(fd, buf)
@@ -63,43 +63,53 @@ func (m *SystemEventsMonitor) parseRoutingMessage(data []byte) {
 
 	switch msgType {
 	case unix.RTM_NEWADDR:
-		m.logger.Log("ADDRESS", "IP address added")
+		m.emit(Event{Category: CategoryAddress, Message: "IP address added"})
 	case unix.RTM_DELADDR:
-		m.logger.Log("ADDRESS", "IP address removed")
+		m.emit(Event{Category: CategoryAddress, Severity: SeverityWarn, Message: "IP address removed"})
 	case unix.RTM_IFINFO:
-		m.logger.Log("LINK", "Interface state changed")
+		m.emit(Event{Category: CategoryLink, Message: "Interface state changed"})
 	case unix.RTM_ADD:
-		m.logger.Log("ROUTE", "Route added")
+		m.emit(Event{Category: CategoryRoute, Message: "Route added"})
 	case unix.RTM_DELETE:
-		m.logger.Log("ROUTE", "Route deleted")
+		m.emit(Event{Category: CategoryRoute, Message: "Route deleted"})
 	case unix.RTM_CHANGE:
-		m.logger.Log("ROUTE", "Route modified")
+		m.emit(Event{Category: CategoryRoute, Message: "Route modified"})
 	}
 }
 
 func (m *SystemEventsMonitor) logNetworkStateDarwin() {
 	interfaces, err := net.Interfaces()
 	if err == nil {
-		m.logger.Log("SYSTEM", fmt.Sprintf("Found %d network interfaces", len(interfaces)))
+		m.emit(Event{Category: CategorySystem, Message: fmt.Sprintf("Found %d network interfaces", len(interfaces))})
 		for _, iface := range interfaces {
 			if iface.Name != "lo0" {
 				state := "DOWN"
 				if iface.Flags&net.FlagUp != 0 {
 					state = "UP"
 				}
-				m.logger.Log("SYSTEM", fmt.Sprintf("  %s: %s", iface.Name, state))
+				m.emit(Event{
+					Category:  CategorySystem,
+					Signal:    SignalLink,
+					Status:    linkStatus(iface.Flags),
+					Interface: iface.Name,
+					Message:   fmt.Sprintf("  %s: %s", iface.Name, state),
+					Fields:    map[string]any{"state": state},
+				})
 			}
 		}
 	}
+
+	m.logDNSServers()
 }
 
 func (m *SystemEventsMonitor) monitorDNSChangesDarwin() {
 	// On macOS, DNS settings can be monitored through system configuration
 	// For simplicity, we poll /etc/resolv.conf
 	lastModTime := time.Time{}
-	resolvPath := "/etc/resolv.conf"
+	cfg := m.config()
+	resolvPath, interval := cfg.ResolvConf, cfg.DNSPollInterval
 
-	ticker := time.NewTicker(2 * time.Second)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -107,6 +117,12 @@ func (m *SystemEventsMonitor) monitorDNSChangesDarwin() {
 		case <-m.ctx.Done():
 			return
 		case <-ticker.C:
+			cfg := m.config()
+			followInterval(ticker, &interval, cfg.DNSPollInterval)
+			if cfg.ResolvConf != resolvPath {
+				resolvPath, lastModTime = cfg.ResolvConf, time.Time{}
+			}
+
 			var stat unix.Stat_t
 			if err := unix.Stat(resolvPath, &stat); err != nil {
 				continue
@@ -114,7 +130,8 @@ func (m *SystemEventsMonitor) monitorDNSChangesDarwin() {
 
 			modTime := time.Unix(stat.Mtim.Sec, stat.Mtim.Nsec)
 			if !lastModTime.IsZero() && modTime.After(lastModTime) {
-				m.logger.Log("DNS", "DNS configuration changed")
+				m.emit(Event{Category: CategoryDNS, Message: "DNS configuration changed"})
+				m.logDNSServers()
 			}
 			lastModTime = modTime
 		}
@@ -12,7 +12,7 @@ import (
 )
 
 func (m *SystemEventsMonitor) startLinux() error {
-	m.logger.Log("SYSTEM", "Starting Linux netlink monitoring")
+	m.emit(Event{Category: CategorySystem, Message: "Starting Linux netlink monitoring"})
 
 	// Subscribe to link updates
 	linkUpdates := make(chan netlink.LinkUpdate)
@@ -52,7 +52,7 @@ func (m *SystemEventsMonitor) startLinux() error {
 				close(linkDone)
 				close(addrDone)
 				close(routeDone)
-				m.logger.Log("SYSTEM", "Stopped Linux netlink monitoring")
+				m.emit(Event{Category: CategorySystem, Message: "Stopped Linux netlink monitoring"})
 				return
 
 			case update := <-linkUpdates:
@@ -75,19 +75,30 @@ func (m *SystemEventsMonitor) handleLinkUpdate(update netlink.LinkUpdate) {
 	attrs := link.Attrs()
 
 	state := "DOWN"
+	severity := SeverityWarn
 	if attrs.Flags&net.FlagUp != 0 {
 		state = "UP"
+		severity = SeverityInfo
 	}
 
-	msg := fmt.Sprintf("Interface %s [%s]: %s (flags: %v)",
-		attrs.Name, link.Type(), state, attrs.Flags)
-	m.logger.Log("LINK", msg)
+	m.emit(Event{
+		Category:  CategoryLink,
+		Severity:  severity,
+		Signal:    SignalLink,
+		Status:    linkStatus(attrs.Flags),
+		Interface: attrs.Name,
+		Message: fmt.Sprintf("Interface %s [%s]: %s (flags: %v)",
+			attrs.Name, link.Type(), state, attrs.Flags),
+		Fields: map[string]any{"state": state, "type": link.Type(), "flags": attrs.Flags.String()},
+	})
 }
 
 func (m *SystemEventsMonitor) handleAddrUpdate(update netlink.AddrUpdate) {
 	action := "ADDED"
+	severity := SeverityInfo
 	if !update.NewAddr {
 		action = "REMOVED"
+		severity = SeverityWarn
 	}
 
 	link, err := netlink.LinkByIndex(update.LinkIndex)
@@ -96,9 +107,15 @@ func (m *SystemEventsMonitor) handleAddrUpdate(update netlink.AddrUpdate) {
 		linkName = link.Attrs().Name
 	}
 
-	msg := fmt.Sprintf("IP address %s on %s: %s",
-		action, linkName, update.LinkAddress.String())
-	m.logger.Log("ADDRESS", msg)
+	m.emit(Event{
+		Category:  CategoryAddress,
+		Severity:  severity,
+		Interface: linkName,
+		Address:   update.LinkAddress.String(),
+		Message: fmt.Sprintf("IP address %s on %s: %s",
+			action, linkName, update.LinkAddress.String()),
+		Fields: map[string]any{"action": action},
+	})
 }
 
 func (m *SystemEventsMonitor) handleRouteUpdate(update netlink.RouteUpdate) {
@@ -112,7 +129,7 @@ func (m *SystemEventsMonitor) handleRouteUpdate(update netlink.RouteUpdate) {
 
 	route := update.Route
 	dst := "default"
-	if route.Dst != nil {
+	if !isDefaultRoute(route.Dst) {
 		dst = route.Dst.String()
 	}
 
@@ -126,15 +143,40 @@ func (m *SystemEventsMonitor) handleRouteUpdate(update netlink.RouteUpdate) {
 		link = l.Attrs().Name
 	}
 
-	msg := fmt.Sprintf("Route %s: %s via %s dev %s", action, dst, via, link)
-	m.logger.Log("ROUTE", msg)
+	e := Event{
+		Category:  CategoryRoute,
+		Interface: link,
+		Gateway:   via,
+		Message:   fmt.Sprintf("Route %s: %s via %s dev %s", action, dst, via, link),
+		Fields:    map[string]any{"action": action, "dst": dst},
+	}
+
+	// Losing the default route cuts off the Internet; other churn is routine.
+	// Another default route may remain, so report what the table holds now.
+	if isDefaultRoute(route.Dst) {
+		lookup, reason := readDefaultRoute, "no_route"
+		e.Signal = SignalRoute
+		if route.Family == netlink.FAMILY_V6 {
+			lookup, reason = readDefaultRoute6, "no_route6"
+			e.Signal = SignalRoute6
+		}
+		e.Status = StatusOK
+		if found, _, err := lookup(); err != nil || !found {
+			e.Severity = SeverityWarn
+			e.Status = StatusFail
+			e.Reason = reason
+		}
+	}
+
+	m.emit(e)
 }
 
 func (m *SystemEventsMonitor) monitorDNSChanges() {
 	lastModTime := time.Time{}
-	resolvPath := "/etc/resolv.conf"
+	cfg := m.config()
+	resolvPath, interval := cfg.ResolvConf, cfg.DNSPollInterval
 
-	ticker := time.NewTicker(2 * time.Second)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -142,6 +184,12 @@ func (m *SystemEventsMonitor) monitorDNSChanges() {
 		case <-m.ctx.Done():
 			return
 		case <-ticker.C:
+			cfg := m.config()
+			followInterval(ticker, &interval, cfg.DNSPollInterval)
+			if cfg.ResolvConf != resolvPath {
+				resolvPath, lastModTime = cfg.ResolvConf, time.Time{}
+			}
+
 			var stat syscall.Stat_t
 			err := syscall.Stat(resolvPath, &stat)
 			if err != nil {
@@ -150,7 +198,7 @@ func (m *SystemEventsMonitor) monitorDNSChanges() {
 
 			modTime := time.Unix(stat.Mtim.Sec, stat.Mtim.Nsec)
 			if !lastModTime.IsZero() && modTime.After(lastModTime) {
-				m.logger.Log("DNS", "DNS configuration changed (resolv.conf modified)")
+				m.emit(Event{Category: CategoryDNS, Message: "DNS configuration changed (resolv.conf modified)"})
 				m.logDNSServers()
 			}
 			lastModTime = modTime
@@ -162,7 +210,7 @@ func (m *SystemEventsMonitor) logNetworkState() {
 	// Log all links
 	links, err := netlink.LinkList()
 	if err == nil {
-		m.logger.Log("SYSTEM", fmt.Sprintf("Found %d network interfaces", len(links)))
+		m.emit(Event{Category: CategorySystem, Message: fmt.Sprintf("Found %d network interfaces", len(links))})
 		for _, link := range links {
 			attrs := link.Attrs()
 			if attrs.Name != "lo" {
@@ -170,44 +218,47 @@ func (m *SystemEventsMonitor) logNetworkState() {
 				if attrs.Flags&net.FlagUp != 0 {
 					state = "UP"
 				}
-				m.logger.Log("SYSTEM", fmt.Sprintf("  %s: %s", attrs.Name, state))
+				m.emit(Event{
+					Category:  CategorySystem,
+					Signal:    SignalLink,
+					Status:    linkStatus(attrs.Flags),
+					Interface: attrs.Name,
+					Message:   fmt.Sprintf("  %s: %s", attrs.Name, state),
+					Fields:    map[string]any{"state": state},
+				})
 			}
 		}
 	}
 
 	// Log default routes
-	routes, err := netlink.RouteList(nil, netlink.FAMILY_V4)
-	if err == nil {
+	for _, family := range []struct {
+		id     int
+		signal Signal
+		label  string
+	}{
+		{netlink.FAMILY_V4, SignalRoute, "Default route"},
+		{netlink.FAMILY_V6, SignalRoute6, "IPv6 default route"},
+	} {
+		routes, err := netlink.RouteList(nil, family.id)
+		if err != nil {
+			continue
+		}
 		for _, route := range routes {
-			if route.Dst == nil { // default route
+			if isDefaultRoute(route.Dst) {
 				via := "direct"
 				if route.Gw != nil {
 					via = route.Gw.String()
 				}
-				m.logger.Log("SYSTEM", fmt.Sprintf("  Default route via %s", via))
+				m.emit(Event{
+					Category: CategorySystem,
+					Signal:   family.signal,
+					Status:   StatusOK,
+					Gateway:  via,
+					Message:  fmt.Sprintf("  %s via %s", family.label, via),
+				})
 			}
 		}
 	}
 
 	m.logDNSServers()
 }
-
-func (m *SystemEventsMonitor) logDNSServers() {
-	// Parse /etc/resolv.conf for DNS servers
-	// This is a simple implementation
-	content, err := syscall.Open("/etc/resolv.conf", syscall.O_RDONLY, 0)
-	if err != nil {
-		return
-	}
-	defer func() { _ = syscall.Close(content) }()
-
-	buf := make([]byte, 4096)
-	n, err := syscall.Read(content, buf)
-	if err != nil {
-		return
-	}
-
-	// Simple parsing - look for nameserver lines
-	_ = string(buf[:n])
-	m.logger.Log("DNS", "Current DNS servers updated")
-}
@@ -22,6 +22,3 @@ func (m *SystemEventsMonitor) monitorDNSChanges() {}
 
 // nolint:unused
 func (m *SystemEventsMonitor) logNetworkState() {}
-
-// nolint:unused
-func (m *SystemEventsMonitor) logDNSServers() {}
@@ -12,7 +12,7 @@ import (
 )
 
 func (m *SystemEventsMonitor) startWindows() error {
-	m.logger.Log("SYSTEM", "Starting Windows IP Helper API monitoring")
+	m.emit(Event{Category: CategorySystem, Message: "Starting Windows IP Helper API monitoring"})
 
 	m.logNetworkStateWindows()
 
@@ -25,12 +25,13 @@ func (m *SystemEventsMonitor) startWindows() error {
 }
 
 func (m *SystemEventsMonitor) monitorInterfaceChanges() {
-	m.logger.Log("SYSTEM", "Monitoring interface changes")
+	m.emit(Event{Category: CategorySystem, Message: "Monitoring interface changes"})
 
 	// Simplified polling approach for Windows
 	lastState := make(map[string]bool)
 
-	ticker := time.NewTicker(2 * time.Second)
+	interval := m.config().InterfacePollInterval
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -38,6 +39,7 @@ func (m *SystemEventsMonitor) monitorInterfaceChanges() {
 		case <-m.ctx.Done():
 			return
 		case <-ticker.C:
+			followInterval(ticker, &interval, m.config().InterfacePollInterval)
 			interfaces, err := net.Interfaces()
 			if err != nil {
 				continue
@@ -54,10 +56,20 @@ func (m *SystemEventsMonitor) monitorInterfaceChanges() {
 
 				if prevState != isUp {
 					state := "DOWN"
+					severity := SeverityWarn
 					if isUp {
 						state = "UP"
+						severity = SeverityInfo
 					}
-					m.logger.Log("LINK", fmt.Sprintf("Interface %s changed to %s", iface.Name, state))
+					m.emit(Event{
+						Category:  CategoryLink,
+						Severity:  severity,
+						Signal:    SignalLink,
+						Status:    linkStatus(iface.Flags),
+						Interface: iface.Name,
+						Message:   fmt.Sprintf("Interface %s changed to %s", iface.Name, state),
+						Fields:    map[string]any{"state": state},
+					})
 					lastState[iface.Name] = isUp
 				}
 			}
@@ -66,9 +78,10 @@ func (m *SystemEventsMonitor) monitorInterfaceChanges() {
 }
 
 func (m *SystemEventsMonitor) monitorRouteChanges() {
-	m.logger.Log("SYSTEM", "Monitoring route changes")
+	m.emit(Event{Category: CategorySystem, Message: "Monitoring route changes"})
 
-	ticker := time.NewTicker(5 * time.Second)
+	interval := m.config().RoutePollInterval
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	var lastRouteCount int
@@ -78,11 +91,12 @@ func (m *SystemEventsMonitor) monitorRouteChanges() {
 		case <-m.ctx.Done():
 			return
 		case <-ticker.C:
+			followInterval(ticker, &interval, m.config().RoutePollInterval)
 			// Simple detection based on route table size change
 			// A more robust implementation would use NotifyRouteChange2
 			currentCount := m.getRouteCount()
 			if lastRouteCount > 0 && currentCount != lastRouteCount {
-				m.logger.Log("ROUTE", "Routing table changed")
+				m.emit(Event{Category: CategoryRoute, Message: "Routing table changed"})
 			}
 			lastRouteCount = currentCount
 		}
@@ -90,7 +104,8 @@ func (m *SystemEventsMonitor) monitorRouteChanges() {
 }
 
 func (m *SystemEventsMonitor) monitorAddressChanges() {
-	ticker := time.NewTicker(3 * time.Second)
+	interval := m.config().AddressPollInterval
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	lastAddrs := make(map[string][]string)
@@ -100,6 +115,7 @@ func (m *SystemEventsMonitor) monitorAddressChanges() {
 		case <-m.ctx.Done():
 			return
 		case <-ticker.C:
+			followInterval(ticker, &interval, m.config().AddressPollInterval)
 			interfaces, err := net.Interfaces()
 			if err != nil {
 				continue
@@ -118,7 +134,11 @@ func (m *SystemEventsMonitor) monitorAddressChanges() {
 
 				prevAddrs, exists := lastAddrs[iface.Name]
 				if exists && !sliceEqual(prevAddrs, currentAddrs) {
-					m.logger.Log("ADDRESS", fmt.Sprintf("IP addresses changed on %s", iface.Name))
+					m.emit(Event{
+						Category:  CategoryAddress,
+						Interface: iface.Name,
+						Message:   fmt.Sprintf("IP addresses changed on %s", iface.Name),
+					})
 				}
 
 				lastAddrs[iface.Name] = currentAddrs
@@ -130,13 +150,20 @@ func (m *SystemEventsMonitor) monitorAddressChanges() {
 func (m *SystemEventsMonitor) logNetworkStateWindows() {
 	interfaces, err := net.Interfaces()
 	if err == nil {
-		m.logger.Log("SYSTEM", fmt.Sprintf("Found %d network interfaces", len(interfaces)))
+		m.emit(Event{Category: CategorySystem, Message: fmt.Sprintf("Found %d network interfaces", len(interfaces))})
 		for _, iface := range interfaces {
 			state := "DOWN"
 			if iface.Flags&net.FlagUp != 0 {
 				state = "UP"
 			}
-			m.logger.Log("SYSTEM", fmt.Sprintf("  %s: %s", iface.Name, state))
+			m.emit(Event{
+				Category:  CategorySystem,
+				Signal:    SignalLink,
+				Status:    linkStatus(iface.Flags),
+				Interface: iface.Name,
+				Message:   fmt.Sprintf("  %s: %s", iface.Name, state),
+				Fields:    map[string]any{"state": state},
+			})
 		}
 	}
 }
@@ -0,0 +1,136 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// TCPInfo is a sample of the kernel's statistics for a connection.
+type TCPInfo struct {
+	// RTT is the smoothed round-trip time and RTTVar its variance.
+	RTT    time.Duration `json:"rtt"`
+	RTTVar time.Duration `json:"rttvar"`
+	// TotalRetrans counts segments retransmitted over the connection's life.
+	TotalRetrans uint32 `json:"total_retrans"`
+	// Lost and Unacked are the segments currently considered lost and in flight.
+	Lost    uint32 `json:"lost"`
+	Unacked uint32 `json:"unacked"`
+	// Cwnd is the congestion window in segments.
+	Cwnd uint32 `json:"cwnd"`
+}
+
+// sampleTCPInfo samples conn every cfg.InfoInterval until ctx is done. Every
+// sample is logged at debug level; retransmits reaching cfg.RetransmitWarn
+// within one interval mark the target degraded, which is usually the first
+// sign of a failing link, well before the connection breaks.
+func (m *TCPKeepaliveMonitor) sampleTCPInfo(ctx context.Context, cfg TCPConfig, conn net.Conn, target string) {
+	if !tcpInfoSupported {
+		return
+	}
+
+	ticker := time.NewTicker(cfg.InfoInterval)
+	defer ticker.Stop()
+
+	prev, err := readTCPInfo(conn)
+	if err != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := readTCPInfo(conn)
+		if err != nil {
+			// The connection loop reports the broken connection
+			return
+		}
+		retrans := info.TotalRetrans - prev.TotalRetrans
+		prev = info
+
+		e := Event{
+			Severity: SeverityDebug,
+			Target:   target,
+			Duration: info.RTT,
+			Message: fmt.Sprintf("TCP_INFO %s: rtt %v ±%v, retransmits +%d (total %d), lost %d, unacked %d, cwnd %d",
+				target, info.RTT, info.RTTVar, retrans, info.TotalRetrans, info.Lost, info.Unacked, info.Cwnd),
+			Fields: map[string]any{
+				"rtt_ms":        durationMillis(info.RTT),
+				"rttvar_ms":     durationMillis(info.RTTVar),
+				"retrans":       retrans,
+				"total_retrans": info.TotalRetrans,
+				"lost":          info.Lost,
+				"unacked":       info.Unacked,
+				"cwnd":          info.Cwnd,
+			},
+		}
+		degraded := ""
+		if int(retrans) >= cfg.RetransmitWarn {
+			e.Severity = SeverityWarn
+			degraded = fmt.Sprintf("%s retransmitted %d segments in %v", target, retrans, cfg.InfoInterval)
+		}
+		m.setTargetHealth(ctx, target, info, degraded, e)
+	}
+}
+
+// setTargetHealth records a TCP_INFO sample of target and logs e. degraded
+// describes why the connection is degraded, or is empty when it is healthy.
+func (m *TCPKeepaliveMonitor) setTargetHealth(ctx context.Context, target string, info TCPInfo, degraded string, e Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ts, ok := m.targets[target]
+	if ctx.Err() != nil || !ok || ts.State != TargetUp {
+		return
+	}
+	ts.TCPInfo = &info
+	ts.Degraded = degraded
+
+	m.emit(e)
+	if aggregate, changed := m.evaluateHealth(); changed {
+		m.emit(aggregate)
+	}
+}
+
+// evaluateHealth reports the tcp_health signal as warn while any connected
+// target is degraded and returns the event to report when it changed.
+// Nothing is reported until a target first degrades. Callers hold m.mu.
+func (m *TCPKeepaliveMonitor) evaluateHealth() (Event, bool) {
+	var degraded []string
+	for _, target := range m.cfg.Targets {
+		if ts, ok := m.targets[target]; ok && ts.State == TargetUp && ts.Degraded != "" {
+			degraded = append(degraded, ts.Degraded)
+		}
+	}
+
+	status := StatusOK
+	if len(degraded) > 0 {
+		status = StatusWarn
+	}
+	if status == m.health || (m.health == "" && status == StatusOK) {
+		return Event{}, false
+	}
+	m.health = status
+
+	if status == StatusOK {
+		return Event{
+			Signal:  m.signal(SignalTCPHealth),
+			Status:  StatusOK,
+			Message: "TCP health recovered: no target is retransmitting",
+		}, true
+	}
+	return Event{
+		Signal:   m.signal(SignalTCPHealth),
+		Status:   StatusWarn,
+		Severity: SeverityWarn,
+		Reason:   m.reason("tcp_retransmits"),
+		Message:  fmt.Sprintf("TCP health degraded: %s", strings.Join(degraded, "; ")),
+		Fields:   map[string]any{"degraded": len(degraded)},
+	}, true
+}
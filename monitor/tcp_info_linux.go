@@ -0,0 +1,46 @@
+//go:build linux
+
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// tcpInfoSupported reports whether readTCPInfo works on this platform.
+const tcpInfoSupported = true
+
+// readTCPInfo samples the kernel's view of conn with getsockopt(TCP_INFO).
+func readTCPInfo(conn net.Conn) (TCPInfo, error) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return TCPInfo{}, fmt.Errorf("not a TCP connection")
+	}
+	rawConn, err := tcpConn.SyscallConn()
+	if err != nil {
+		return TCPInfo{}, fmt.Errorf("failed to access socket: %w", err)
+	}
+
+	var info *unix.TCPInfo
+	var sockErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		info, sockErr = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+	}); err != nil {
+		return TCPInfo{}, fmt.Errorf("failed to access socket: %w", err)
+	}
+	if sockErr != nil {
+		return TCPInfo{}, fmt.Errorf("failed to read TCP_INFO: %w", sockErr)
+	}
+
+	return TCPInfo{
+		RTT:          time.Duration(info.Rtt) * time.Microsecond,
+		RTTVar:       time.Duration(info.Rttvar) * time.Microsecond,
+		TotalRetrans: info.Total_retrans,
+		Lost:         info.Lost,
+		Unacked:      info.Unacked,
+		Cwnd:         info.Snd_cwnd,
+	}, nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package monitor
+
+import (
+	"errors"
+	"net"
+)
+
+// tcpInfoSupported reports whether readTCPInfo works on this platform.
+const tcpInfoSupported = false
+
+// readTCPInfo is not supported on non-Linux platforms.
+func readTCPInfo(net.Conn) (TCPInfo, error) {
+	return TCPInfo{}, errors.New("TCP_INFO is only available on Linux")
+}
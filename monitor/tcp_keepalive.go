@@ -3,130 +3,425 @@ package monitor
 import (
 	"context"
 	"fmt"
+	"math/rand/v2"
 	"net"
+	"reflect"
+	"strings"
+	"sync"
 	"time"
 )
 
+// failureSummaryInterval is how often a target that keeps failing to
+// reconnect is reported; the attempts in between are logged at debug level.
+const failureSummaryInterval = 5 * time.Minute
+
+// Keepalive target states.
 const (
-	keepaliveTarget = "1.1.1.1:443" // Cloudflare DNS over HTTPS
-	reconnectDelay  = 5 * time.Second
+	TargetUnknown = "unknown"
+	TargetUp      = "up"
+	TargetDown    = "down"
 )
 
-// TCPKeepaliveMonitor maintains a persistent TCP connection and reports connectivity.
+// TargetStatus describes the connection to one keepalive target.
+type TargetStatus struct {
+	Target    string    `json:"target"`
+	State     string    `json:"state"`
+	Since     time.Time `json:"since"`
+	LastError string    `json:"last_error,omitempty"`
+	// RTT is the round-trip time of the last heartbeat (heartbeat mode only).
+	RTT time.Duration `json:"rtt,omitempty"`
+	// TCPInfo is the latest kernel sample of the connection (Linux only)
+	// and Degraded explains why the sample marked the connection degraded.
+	TCPInfo  *TCPInfo `json:"tcp_info,omitempty"`
+	Degraded string   `json:"degraded,omitempty"`
+	// Keepalive holds the keepalive settings of the current connection.
+	Keepalive *KeepaliveSettings `json:"keepalive,omitempty"`
+	// Failures counts the failed attempts since the target went down.
+	Failures int `json:"failures,omitempty"`
+
+	lastReport time.Time // when the failures were last logged
+}
+
+// TCPKeepaliveMonitor maintains a persistent TCP connection to each
+// configured target. Every target reports independently; the Internet is
+// reported down only when at least a quorum of targets is down, so a single
+// failing or blocked host is not mistaken for an outage.
+//
+// In keepalive mode a connection is only known to be dead once a read fails,
+// which for a silently black-holed path means when the kernel keepalives
+// give up. In heartbeat mode the targets run `network-monitor echo-server`
+// and every heartbeat must be echoed within a timeout, which detects such
+// paths within seconds and measures the round-trip time.
 type TCPKeepaliveMonitor struct {
 	logger *Logger
 	ctx    context.Context
-	conn   net.Conn
+
+	mu      sync.Mutex
+	cfg     TCPConfig
+	cancel  context.CancelFunc // stops the connection loops of the current config
+	targets map[string]*TargetStatus
+	status  Status        // last reported aggregate status; empty until decided
+	health  Status        // last reported tcp_health status; empty until degraded
+	retry   chan struct{} // closed to cut reconnect waits short
+
+	uplink *UplinkConfig // pins the connections to an uplink; nil follows the routing table
+	ipv6   bool          // connects over IPv6 and reports the IPv6 signals
 }
 
 // NewTCPKeepaliveMonitor constructs a TCP keepalive monitor.
-func NewTCPKeepaliveMonitor(ctx context.Context, logger *Logger) *TCPKeepaliveMonitor {
+func NewTCPKeepaliveMonitor(ctx context.Context, logger *Logger, cfg TCPConfig) *TCPKeepaliveMonitor {
 	return &TCPKeepaliveMonitor{
 		logger: logger,
 		ctx:    ctx,
+		cfg:    cfg,
+		retry:  make(chan struct{}),
 	}
 }
 
-// Start launches the TCP keepalive monitoring loop.
+// newUplinkTCPKeepaliveMonitor constructs a TCP keepalive monitor whose
+// connections are pinned to uplink.
+func newUplinkTCPKeepaliveMonitor(ctx context.Context, logger *Logger, cfg TCPConfig, uplink *UplinkConfig) *TCPKeepaliveMonitor {
+	m := NewTCPKeepaliveMonitor(ctx, logger, cfg)
+	m.uplink = uplink
+	return m
+}
+
+// newIPv6TCPKeepaliveMonitor constructs a TCP keepalive monitor that
+// connects to the targets of cfg over IPv6 and reports the tcp6 and
+// tcp_health6 signals, so IPv6 can fail without taking IPv4 down with it.
+func newIPv6TCPKeepaliveMonitor(ctx context.Context, logger *Logger, cfg TCPConfig) *TCPKeepaliveMonitor {
+	m := NewTCPKeepaliveMonitor(ctx, logger, cfg)
+	m.ipv6 = true
+	return m
+}
+
+// Start launches one connection loop per target.
 func (m *TCPKeepaliveMonitor) Start() error {
-	m.logger.Log("TCP", fmt.Sprintf("Starting persistent TCP keepalive monitor to %s", keepaliveTarget))
+	cfg := m.config()
+	if len(cfg.Targets) == 0 {
+		m.emit(Event{Message: "No TCP targets configured, not monitoring"})
+		return nil
+	}
+	m.emit(Event{
+		Message: fmt.Sprintf("Starting persistent TCP %s monitor to %s (down when %d of %d fail)",
+			cfg.Mode, strings.Join(cfg.Targets, ", "), cfg.EffectiveQuorum(), len(cfg.Targets)),
+	})
+
+	m.startTargets(cfg)
 
-	go m.maintainConnection()
+	go func() {
+		<-m.ctx.Done()
+		m.emit(Event{Message: "Stopped TCP keepalive monitor"})
+	}()
 
 	return nil
 }
 
-func (m *TCPKeepaliveMonitor) maintainConnection() {
-	for {
-		select {
-		case <-m.ctx.Done():
-			if m.conn != nil {
-				_ = m.conn.Close()
-			}
-			m.logger.Log("TCP", "Stopped TCP keepalive monitor")
-			return
-		default:
-			if err := m.connect(); err != nil {
-				m.logger.Log("TCP", fmt.Sprintf("ERROR: Failed to connect: %v", err))
-				time.Sleep(reconnectDelay)
-				continue
-			}
+// Reconfigure applies a new configuration; all connections are replaced by
+// ones made with the new settings.
+func (m *TCPKeepaliveMonitor) Reconfigure(cfg TCPConfig) {
+	m.mu.Lock()
+	if reflect.DeepEqual(cfg, m.cfg) {
+		m.mu.Unlock()
+		return
+	}
+	m.cfg = cfg
+	m.mu.Unlock()
 
-			// Monitor the connection
-			if err := m.monitorConnection(); err != nil {
-				m.logger.Log("TCP", fmt.Sprintf("ERROR: Connection failed: %v", err))
-				if m.conn != nil {
-					_ = m.conn.Close()
-					m.conn = nil
-				}
-				time.Sleep(reconnectDelay)
+	if len(cfg.Targets) == 0 {
+		m.emit(Event{Message: "Configuration changed, no TCP targets configured, not monitoring"})
+		m.startTargets(cfg)
+		return
+	}
+	m.emit(Event{
+		Message: fmt.Sprintf("Configuration changed, reconnecting to %s in %s mode (down when %d of %d fail)",
+			strings.Join(cfg.Targets, ", "), cfg.Mode, cfg.EffectiveQuorum(), len(cfg.Targets)),
+	})
+	m.startTargets(cfg)
+}
+
+func (m *TCPKeepaliveMonitor) config() TCPConfig {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cfg
+}
+
+// startTargets stops the connection loops of the previous config, if any,
+// and starts one per target of cfg. The aggregate status is kept, so a
+// reload does not by itself report the Internet up or down.
+func (m *TCPKeepaliveMonitor) startTargets(cfg TCPConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel != nil {
+		m.cancel()
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.cancel = cancel
+
+	now := time.Now()
+	m.targets = make(map[string]*TargetStatus, len(cfg.Targets))
+	for _, target := range cfg.Targets {
+		m.targets[target] = &TargetStatus{Target: target, State: TargetUnknown, Since: now}
+		go m.maintainConnection(ctx, cfg, target)
+	}
+	if len(cfg.Targets) == 0 && m.status == StatusFail {
+		// Nothing is monitored any more, so nothing is down
+		m.status = StatusOK
+		m.emit(Event{
+			Signal:  m.signal(SignalTCP),
+			Status:  StatusOK,
+			Message: "TCP monitoring stopped: no targets configured",
+		})
+	}
+	if aggregate, changed := m.evaluateHealth(); changed {
+		m.emit(aggregate)
+	}
+}
+
+func (m *TCPKeepaliveMonitor) maintainConnection(ctx context.Context, cfg TCPConfig, target string) {
+	for ctx.Err() == nil {
+		start := time.Now()
+		conn, err := m.connect(ctx, cfg, target)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
 			}
+			failures := m.targetDown(ctx, cfg, target, "tcp_connect_error", err,
+				fmt.Sprintf("Failed to connect to %s: %v", target, err))
+			m.waitRetry(ctx, cfg.reconnectBackoff(failures))
+			continue
+		}
+
+		// Closing the connection unblocks a pending read when the loop is stopped
+		stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+		sampleCtx, stopSampling := context.WithCancel(ctx)
+		go m.sampleTCPInfo(sampleCtx, cfg, conn, target)
+		reason, msg := "tcp_read_error", "Connection to %s failed: %v"
+		if cfg.Mode == TCPModeHeartbeat {
+			reason, msg = "tcp_heartbeat_failed", "Heartbeat to %s failed: %v"
+			err = m.monitorHeartbeat(ctx, cfg, conn, target, time.Since(start))
+		} else {
+			m.connected(ctx, conn, target, time.Since(start))
+			err = m.monitorConnection(ctx, cfg, conn)
 		}
+		stopSampling()
+		stop()
+		_ = conn.Close()
+
+		if ctx.Err() != nil {
+			return
+		}
+		failures := m.targetDown(ctx, cfg, target, reason, err, fmt.Sprintf(msg, target, err))
+		m.waitRetry(ctx, cfg.reconnectBackoff(failures))
 	}
 }
 
-func (m *TCPKeepaliveMonitor) connect() error {
-	if m.conn != nil {
-		return nil // Already connected
+// reconnectBackoff returns how long to wait after the given number of
+// consecutive failed attempts: ReconnectDelay doubled for every further
+// failure up to ReconnectMaxDelay, with the upper half randomized so targets
+// that failed together do not retry in lockstep.
+func (c TCPConfig) reconnectBackoff(failures int) time.Duration {
+	d := c.ReconnectDelay
+	for i := 1; i < failures && d < c.ReconnectMaxDelay; i++ {
+		d *= 2
 	}
+	d = min(d, c.ReconnectMaxDelay)
+	return d/2 + time.Duration(rand.Int64N(int64(d/2)+1))
+}
 
-	dialer := &net.Dialer{
-		Timeout:   10 * time.Second,
-		KeepAlive: 30 * time.Second,
+// waitRetry waits d before the next reconnect attempt. The wait ends early
+// when the loop is stopped or when a network change makes an immediate
+// retry worthwhile.
+func (m *TCPKeepaliveMonitor) waitRetry(ctx context.Context, d time.Duration) {
+	m.mu.Lock()
+	retry := m.retry
+	m.mu.Unlock()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	case <-retry:
+	}
+}
+
+// HandleEvent retries down targets immediately when an interface comes up
+// or a route is added, rather than waiting out the backoff. It is meant to
+// be registered with Logger.AddHook.
+func (m *TCPKeepaliveMonitor) HandleEvent(e Event) {
+	if e.Source != SourceSystem {
+		return
 	}
 
-	conn, err := dialer.DialContext(m.ctx, "tcp", keepaliveTarget)
+	var change string
+	switch {
+	case e.Signal == SignalLink && e.Status == StatusOK:
+		change = fmt.Sprintf("interface %s is up", e.Interface)
+	case e.Category == CategoryRoute && stringField(e.Fields, "action") == "ADDED":
+		change = fmt.Sprintf("route %s added", stringField(e.Fields, "dst"))
+	default:
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var down []string
+	for _, target := range m.cfg.Targets {
+		if ts, ok := m.targets[target]; ok && ts.State == TargetDown {
+			down = append(down, target)
+		}
+	}
+	if len(down) == 0 {
+		return
+	}
+
+	close(m.retry)
+	m.retry = make(chan struct{})
+	m.emit(Event{
+		Message: fmt.Sprintf("Network changed (%s), retrying %s now", change, strings.Join(down, ", ")),
+	})
+}
+
+func (m *TCPKeepaliveMonitor) connect(ctx context.Context, cfg TCPConfig, target string) (net.Conn, error) {
+	keepalive := net.KeepAliveConfig{
+		Enable:   true,
+		Idle:     cfg.KeepaliveIdle,
+		Interval: cfg.KeepaliveInterval,
+		Count:    cfg.KeepaliveCount,
+	}
+	network := m.network()
+	dialer := newDialer(m.uplink, network, cfg.DialTimeout)
+	dialer.KeepAliveConfig = keepalive
+
+	conn, err := dialer.DialContext(ctx, network, target)
 	if err != nil {
-		return fmt.Errorf("dial failed: %w", err)
+		return nil, fmt.Errorf("dial failed: %w", err)
 	}
 
-	// Enable TCP keepalive at the socket level
+	// Enable TCP keepalive at the socket level; the dialer ignores failures
 	if tcpConn, ok := conn.(*net.TCPConn); ok {
-		if err := tcpConn.SetKeepAlive(true); err != nil {
+		if err := tcpConn.SetKeepAliveConfig(keepalive); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("failed to configure keepalive: %w", err)
+		}
+
+		// Apply platform-specific tuning and read back what the kernel uses
+		rawConn, err := tcpConn.SyscallConn()
+		if err != nil {
 			_ = conn.Close()
-			return fmt.Errorf("failed to enable keepalive: %w", err)
+			return nil, fmt.Errorf("failed to access socket: %w", err)
 		}
-		if err := tcpConn.SetKeepAlivePeriod(30 * time.Second); err != nil {
+		var platformErr, readErr error
+		var effective KeepaliveSettings
+		_ = rawConn.Control(func(fd uintptr) {
+			platformErr = applyPlatformKeepalive(fd, cfg)
+			effective, readErr = readKeepalive(fd)
+		})
+		if platformErr != nil {
 			_ = conn.Close()
-			return fmt.Errorf("failed to set keepalive period: %w", err)
+			return nil, platformErr
 		}
+		m.logKeepalive(target, cfg, effective, readErr)
+	}
 
-		// Apply platform-specific keepalive tuning (no-op where unsupported)
-		if rawConn, err := tcpConn.SyscallConn(); err == nil {
-			_ = rawConn.Control(func(fd uintptr) {
-				applyPlatformKeepalive(fd)
-			})
+	return conn, nil
+}
+
+// KeepaliveSettings are the keepalive parameters in effect on a socket.
+type KeepaliveSettings struct {
+	Idle        time.Duration
+	Interval    time.Duration
+	Count       int
+	UserTimeout time.Duration
+}
+
+// String describes the settings and the detection latency they imply.
+func (k KeepaliveSettings) String() string {
+	userTimeout := "kernel default"
+	if k.UserTimeout > 0 {
+		userTimeout = k.UserTimeout.String()
+	}
+	return fmt.Sprintf("idle %v, interval %v, count %d, user timeout %s (idle connection declared dead after %v)",
+		k.Idle, k.Interval, k.Count, userTimeout, k.Idle+time.Duration(k.Count)*k.Interval)
+}
+
+// logKeepalive logs the keepalive settings of a new connection to target:
+// at info level the first time or when they change, at debug level otherwise.
+// Where the kernel cannot be queried the requested settings are logged.
+func (m *TCPKeepaliveMonitor) logKeepalive(target string, cfg TCPConfig, effective KeepaliveSettings, readErr error) {
+	msg := "Keepalive on %s: %s"
+	if readErr != nil {
+		effective = KeepaliveSettings{
+			Idle:        cfg.KeepaliveIdle,
+			Interval:    cfg.KeepaliveInterval,
+			Count:       cfg.KeepaliveCount,
+			UserTimeout: cfg.UserTimeout,
 		}
+		msg = "Keepalive on %s: %s (requested; not read back from the socket)"
 	}
 
-	m.conn = conn
-	m.logger.Log("TCP", fmt.Sprintf("SUCCESS: Connected to %s", keepaliveTarget))
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	return nil
+	ts, ok := m.targets[target]
+	if !ok {
+		return
+	}
+	severity := SeverityDebug
+	if ts.Keepalive == nil || *ts.Keepalive != effective {
+		severity = SeverityInfo
+	}
+	ts.Keepalive = &effective
+
+	m.emit(Event{
+		Severity: severity,
+		Target:   target,
+		Message:  fmt.Sprintf(msg, target, effective),
+		Fields: map[string]any{
+			"keepalive_idle_s":     effective.Idle.Seconds(),
+			"keepalive_interval_s": effective.Interval.Seconds(),
+			"keepalive_count":      effective.Count,
+			"user_timeout_ms":      durationMillis(effective.UserTimeout),
+		},
+	})
 }
 
-func (m *TCPKeepaliveMonitor) monitorConnection() error {
+// connected marks target up once its connection is established.
+func (m *TCPKeepaliveMonitor) connected(ctx context.Context, conn net.Conn, target string, took time.Duration) {
+	m.targetUp(ctx, target, 0, Event{
+		Target:   target,
+		Address:  conn.LocalAddr().String(),
+		Duration: took,
+		Message:  fmt.Sprintf("SUCCESS: Connected to %s (took %v)", target, took),
+	})
+}
+
+func (m *TCPKeepaliveMonitor) monitorConnection(ctx context.Context, cfg TCPConfig, conn net.Conn) error {
 	// Set read deadline to detect connection failures
-	readTimeout := 60 * time.Second
-	ticker := time.NewTicker(30 * time.Second)
+	readTimeout := cfg.ReadTimeout
+	ticker := time.NewTicker(cfg.CheckInterval)
 	defer ticker.Stop()
 
 	buf := make([]byte, 1)
 
 	for {
 		select {
-		case <-m.ctx.Done():
+		case <-ctx.Done():
 			return nil
 		case <-ticker.C:
 			// Try to read with timeout
-			_ = m.conn.SetReadDeadline(time.Now().Add(readTimeout))
-			n, err := m.conn.Read(buf)
+			_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+			n, err := conn.Read(buf)
 
 			if err != nil {
 				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
 					// Timeout is expected, connection is still alive
 					// Reset deadline
-					_ = m.conn.SetReadDeadline(time.Time{})
+					_ = conn.SetReadDeadline(time.Time{})
 					continue
 				}
 				// Real error - connection is broken
@@ -135,13 +430,292 @@ func (m *TCPKeepaliveMonitor) monitorConnection() error {
 
 			if n > 0 {
 				// Unexpected data, but connection is alive
-				_ = m.conn.SetReadDeadline(time.Time{})
+				_ = conn.SetReadDeadline(time.Time{})
 			}
 		}
 	}
 }
 
-// IsConnected reports whether the monitor currently has an active connection.
+// monitorHeartbeat exchanges heartbeats with the echo server at target until
+// one is not echoed in time. The target is only marked up once the first
+// heartbeat is echoed, so a host that accepts connections but does not run
+// the echo server is never counted as up.
+func (m *TCPKeepaliveMonitor) monitorHeartbeat(ctx context.Context, cfg TCPConfig, conn net.Conn, target string, took time.Duration) error {
+	ticker := time.NewTicker(cfg.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for seq := uint32(1); ; seq++ {
+		rtt, err := exchangeHeartbeat(conn, seq, cfg.HeartbeatTimeout)
+		if err != nil {
+			return err
+		}
+
+		if seq == 1 {
+			m.targetUp(ctx, target, rtt, Event{
+				Target:   target,
+				Address:  conn.LocalAddr().String(),
+				Duration: took,
+				Message: fmt.Sprintf("SUCCESS: Connected to %s, first heartbeat echoed in %v (took %v)",
+					target, rtt, took),
+			})
+		} else {
+			m.heartbeat(ctx, target, seq, rtt)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// heartbeat records the round-trip time of an echoed heartbeat and logs it
+// at debug level.
+func (m *TCPKeepaliveMonitor) heartbeat(ctx context.Context, target string, seq uint32, rtt time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ts, ok := m.targets[target]
+	if ctx.Err() != nil || !ok {
+		return
+	}
+	ts.RTT = rtt
+
+	m.emit(Event{
+		Severity: SeverityDebug,
+		Target:   target,
+		Duration: rtt,
+		Message:  fmt.Sprintf("Heartbeat %d to %s echoed in %v", seq, target, rtt),
+		Fields:   map[string]any{"seq": seq},
+	})
+}
+
+// targetUp records a successful connection and logs it with e.
+func (m *TCPKeepaliveMonitor) targetUp(ctx context.Context, target string, rtt time.Duration, e Event) {
+	e.Fields = map[string]any{"target_state": TargetUp}
+	if rtt > 0 {
+		e.Fields["rtt_ms"] = durationMillis(rtt)
+	}
+	m.setTargetState(ctx, target, TargetUp, "", rtt, e)
+}
+
+// targetDown records a failed connection attempt or a broken connection and
+// returns the number of consecutive failed attempts.
+func (m *TCPKeepaliveMonitor) targetDown(ctx context.Context, cfg TCPConfig, target, reason string, err error, msg string) int {
+	// Losing one target of several is not an outage by itself
+	severity := SeverityWarn
+	if len(cfg.Targets) == 1 {
+		severity = SeverityError
+	}
+	return m.setTargetState(ctx, target, TargetDown, err.Error(), 0, Event{
+		Severity: severity,
+		Target:   target,
+		Reason:   m.reason(reason),
+		Err:      err,
+		Message:  msg,
+		Fields:   map[string]any{"target_state": TargetDown},
+	})
+}
+
+// setTargetState updates one target, logs e and, when the number of targets
+// down crosses the quorum, reports the aggregate TCP signal. Updates from
+// loops of a replaced config are dropped. Events are emitted under the lock
+// so aggregate reports reach the state tracker in order.
+//
+// Failed attempts after the first are collapsed: they are logged at debug
+// level and summarized every failureSummaryInterval and on reconnection.
+// It returns the number of consecutive failed attempts.
+func (m *TCPKeepaliveMonitor) setTargetState(ctx context.Context, target, state, lastErr string, rtt time.Duration, e Event) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ts, ok := m.targets[target]
+	if ctx.Err() != nil || !ok {
+		return 0
+	}
+
+	now := time.Now()
+	switch {
+	case state == TargetDown && ts.State == TargetDown:
+		ts.Failures++
+		if now.Sub(ts.lastReport) < failureSummaryInterval {
+			e.Severity = SeverityDebug
+			break
+		}
+		ts.lastReport = now
+		e.Message = fmt.Sprintf("Reconnect to %s failed %d times over %s (last error: %v)",
+			target, ts.Failures, HumanDuration(now.Sub(ts.Since)), e.Err)
+	case state == TargetDown:
+		ts.Failures, ts.lastReport = 1, now
+	default:
+		if ts.State == TargetDown && ts.Failures > 1 {
+			m.emit(Event{
+				Target: target,
+				Message: fmt.Sprintf("Reconnected to %s after %d failed attempts over %s",
+					target, ts.Failures, HumanDuration(now.Sub(ts.Since))),
+				Fields: map[string]any{"failures": ts.Failures},
+			})
+		}
+		ts.Failures = 0
+	}
+	if state == TargetDown {
+		e.Fields["failures"] = ts.Failures
+	}
+
+	if ts.State != state {
+		ts.State, ts.Since = state, now
+	}
+	ts.LastError, ts.RTT = lastErr, rtt
+	if state != TargetUp {
+		ts.TCPInfo, ts.Degraded = nil, ""
+	}
+
+	m.emit(e)
+	if aggregate, changed := m.evaluateQuorum(); changed {
+		m.emit(aggregate)
+	}
+	if aggregate, changed := m.evaluateHealth(); changed {
+		m.emit(aggregate)
+	}
+	return ts.Failures
+}
+
+// evaluateQuorum decides the aggregate status from the target states and
+// returns the event to report when it changed. The status stays undecided
+// while unknown targets could still tip it either way. Callers hold m.mu.
+func (m *TCPKeepaliveMonitor) evaluateQuorum() (Event, bool) {
+	quorum := m.cfg.EffectiveQuorum()
+	var up, down int
+	for _, ts := range m.targets {
+		switch ts.State {
+		case TargetUp:
+			up++
+		case TargetDown:
+			down++
+		}
+	}
+
+	var status Status
+	switch {
+	case down >= quorum:
+		status = StatusFail
+	case len(m.targets)-up < quorum:
+		status = StatusOK
+	default:
+		return Event{}, false
+	}
+	if status == m.status {
+		return Event{}, false
+	}
+	m.status = status
+
+	e := Event{
+		Signal: m.signal(SignalTCP),
+		Status: status,
+		Fields: map[string]any{
+			"up":      up,
+			"down":    down,
+			"quorum":  quorum,
+			"targets": m.targetStates(),
+		},
+	}
+	if status == StatusFail {
+		e.Severity = SeverityError
+		e.Reason = m.reason("tcp_quorum_lost")
+		e.Message = fmt.Sprintf("TCP quorum lost: %d of %d targets down (quorum %d): %s",
+			down, len(m.targets), quorum, m.describeTargets())
+	} else {
+		e.Message = fmt.Sprintf("TCP quorum met: %d of %d targets up (quorum %d): %s",
+			up, len(m.targets), quorum, m.describeTargets())
+	}
+	return e, true
+}
+
+// targetStates maps each target to its state. Callers hold m.mu.
+func (m *TCPKeepaliveMonitor) targetStates() map[string]string {
+	states := make(map[string]string, len(m.targets))
+	for target, ts := range m.targets {
+		states[target] = ts.State
+	}
+	return states
+}
+
+// describeTargets lists target states in config order. Callers hold m.mu.
+func (m *TCPKeepaliveMonitor) describeTargets() string {
+	parts := make([]string, 0, len(m.cfg.Targets))
+	for _, target := range m.cfg.Targets {
+		if ts, ok := m.targets[target]; ok {
+			parts = append(parts, target+"="+ts.State)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// network is the network the targets are dialed on. The IPv4 and IPv6
+// monitors each stick to their own family, so a host name target cannot
+// fall back to the other one.
+func (m *TCPKeepaliveMonitor) network() string {
+	if m.ipv6 {
+		return "tcp6"
+	}
+	return "tcp4"
+}
+
+// signal maps an IPv4 signal to the one this monitor reports.
+func (m *TCPKeepaliveMonitor) signal(s Signal) Signal {
+	if !m.ipv6 {
+		return s
+	}
+	switch s {
+	case SignalTCP:
+		return SignalTCP6
+	case SignalTCPHealth:
+		return SignalTCPHealth6
+	}
+	return s
+}
+
+// reason maps an IPv4 reason such as tcp_quorum_lost to the one this
+// monitor reports (tcp6_quorum_lost).
+func (m *TCPKeepaliveMonitor) reason(r string) string {
+	if !m.ipv6 {
+		return r
+	}
+	return strings.Replace(r, "tcp_", "tcp6_", 1)
+}
+
+// emit stamps the event with the TCP category and source and forwards it to
+// the logger. Messages of the IPv6 monitor are marked as such.
+func (m *TCPKeepaliveMonitor) emit(e Event) {
+	e.Category = CategoryTCP
+	e.Source = SourceTCP
+	if m.uplink != nil {
+		e.Uplink = m.uplink.Name
+	}
+	if m.ipv6 {
+		e.Message = "IPv6: " + e.Message
+	}
+	m.logger.Emit(e)
+}
+
+// Targets returns the state of every target in config order.
+func (m *TCPKeepaliveMonitor) Targets() []TargetStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]TargetStatus, 0, len(m.cfg.Targets))
+	for _, target := range m.cfg.Targets {
+		if ts, ok := m.targets[target]; ok {
+			statuses = append(statuses, *ts)
+		}
+	}
+	return statuses
+}
+
+// IsConnected reports whether fewer than a quorum of targets are down.
 func (m *TCPKeepaliveMonitor) IsConnected() bool {
-	return m.conn != nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status == StatusOK
 }
@@ -2,11 +2,54 @@
 
 package monitor
 
-import "syscall"
+import (
+	"fmt"
+	"time"
 
-// applyPlatformKeepalive sets aggressive keepalive parameters on Linux.
-func applyPlatformKeepalive(fd uintptr) {
-	// 10s between probes, 3 failed probes before drop
-	_ = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL, 10)
-	_ = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT, 3)
+	"golang.org/x/sys/unix"
+)
+
+// applyPlatformKeepalive sets TCP_USER_TIMEOUT on Linux so sent data that
+// stays unacknowledged drops the connection after cfg.UserTimeout instead
+// of the kernel's ~15 minutes of retransmissions.
+func applyPlatformKeepalive(fd uintptr, cfg TCPConfig) error {
+	if cfg.UserTimeout <= 0 {
+		return nil
+	}
+	if err := unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_USER_TIMEOUT,
+		int(cfg.UserTimeout.Milliseconds())); err != nil {
+		return fmt.Errorf("failed to set TCP_USER_TIMEOUT: %w", err)
+	}
+	return nil
+}
+
+// readKeepalive reads the keepalive settings in effect on the socket.
+func readKeepalive(fd uintptr) (KeepaliveSettings, error) {
+	get := func(opt int) (int, error) {
+		return unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, opt)
+	}
+
+	idle, err := get(unix.TCP_KEEPIDLE)
+	if err != nil {
+		return KeepaliveSettings{}, fmt.Errorf("failed to read TCP_KEEPIDLE: %w", err)
+	}
+	interval, err := get(unix.TCP_KEEPINTVL)
+	if err != nil {
+		return KeepaliveSettings{}, fmt.Errorf("failed to read TCP_KEEPINTVL: %w", err)
+	}
+	count, err := get(unix.TCP_KEEPCNT)
+	if err != nil {
+		return KeepaliveSettings{}, fmt.Errorf("failed to read TCP_KEEPCNT: %w", err)
+	}
+	userTimeout, err := get(unix.TCP_USER_TIMEOUT)
+	if err != nil {
+		return KeepaliveSettings{}, fmt.Errorf("failed to read TCP_USER_TIMEOUT: %w", err)
+	}
+
+	return KeepaliveSettings{
+		Idle:        time.Duration(idle) * time.Second,
+		Interval:    time.Duration(interval) * time.Second,
+		Count:       count,
+		UserTimeout: time.Duration(userTimeout) * time.Millisecond,
+	}, nil
 }
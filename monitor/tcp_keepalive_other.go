@@ -2,5 +2,13 @@
 
 package monitor
 
-// applyPlatformKeepalive is a no-op on non-Linux platforms.
-func applyPlatformKeepalive(fd uintptr) {}
+import "errors"
+
+// applyPlatformKeepalive is a no-op on non-Linux platforms, which have no
+// TCP_USER_TIMEOUT.
+func applyPlatformKeepalive(fd uintptr, cfg TCPConfig) error { return nil }
+
+// readKeepalive is not supported on non-Linux platforms.
+func readKeepalive(fd uintptr) (KeepaliveSettings, error) {
+	return KeepaliveSettings{}, errors.New("reading keepalive settings is only supported on Linux")
+}
@@ -0,0 +1,123 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newQuorumTestMonitor returns a monitor whose targets are in the given
+// states, in order, with the aggregate status last reported as status.
+func newQuorumTestMonitor(quorum int, status Status, states ...string) *TCPKeepaliveMonitor {
+	cfg := TCPConfig{Quorum: quorum}
+	m := NewTCPKeepaliveMonitor(context.Background(), nil, cfg)
+	m.targets = make(map[string]*TargetStatus, len(states))
+	for i, state := range states {
+		target := string(rune('a'+i)) + ".example:443"
+		m.cfg.Targets = append(m.cfg.Targets, target)
+		m.targets[target] = &TargetStatus{Target: target, State: state}
+	}
+	m.status = status
+	return m
+}
+
+func TestEvaluateQuorum(t *testing.T) {
+	const (
+		up      = TargetUp
+		down    = TargetDown
+		unknown = TargetUnknown
+	)
+	for _, tc := range []struct {
+		name    string
+		quorum  int
+		status  Status // last reported
+		states  []string
+		want    Status // empty when nothing is reported
+		changed bool
+	}{
+		{name: "all unknown", states: []string{unknown, unknown, unknown}},
+		{name: "one up could still be outvoted", states: []string{up, unknown, unknown}},
+		{name: "majority up", states: []string{up, up, unknown}, want: StatusOK, changed: true},
+		{name: "one of three down", states: []string{up, up, down}, want: StatusOK, changed: true},
+		{name: "undecided with one down", states: []string{up, down, unknown}},
+		{name: "majority down", states: []string{down, down, up}, want: StatusFail, changed: true},
+		{name: "majority down, rest unknown", states: []string{down, down, unknown}, want: StatusFail, changed: true},
+		{name: "unchanged", status: StatusOK, states: []string{up, up, down}, want: StatusOK},
+		{name: "recovered", status: StatusFail, states: []string{up, up, down}, want: StatusOK, changed: true},
+		{name: "quorum of one", quorum: 1, states: []string{down, up, up}, want: StatusFail, changed: true},
+		{name: "quorum of all", quorum: 3, states: []string{down, down, up}, want: StatusOK, changed: true},
+		{name: "even number of targets", states: []string{down, up, up, up}, want: StatusOK, changed: true},
+		{name: "half of an even number down", states: []string{down, down, up, up}, want: StatusOK, changed: true},
+		{name: "half down, rest unknown", states: []string{down, down, unknown, unknown}},
+		{name: "single target up", states: []string{up}, want: StatusOK, changed: true},
+		{name: "single target down", states: []string{down}, want: StatusFail, changed: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newQuorumTestMonitor(tc.quorum, tc.status, tc.states...)
+			e, changed := m.evaluateQuorum()
+			if changed != tc.changed {
+				t.Fatalf("evaluateQuorum() changed = %v, want %v (event %+v)", changed, tc.changed, e)
+			}
+			if m.status != tc.want {
+				t.Errorf("status = %q, want %q", m.status, tc.want)
+			}
+			if !changed {
+				return
+			}
+			if e.Signal != SignalTCP || e.Status != tc.want {
+				t.Errorf("event = %s %s, want %s %s", e.Signal, e.Status, SignalTCP, tc.want)
+			}
+			if wantReason := map[Status]string{StatusFail: "tcp_quorum_lost"}[tc.want]; e.Reason != wantReason {
+				t.Errorf("Reason = %q, want %q", e.Reason, wantReason)
+			}
+			if e.Fields["quorum"] != m.cfg.EffectiveQuorum() {
+				t.Errorf("quorum field = %v, want %d", e.Fields["quorum"], m.cfg.EffectiveQuorum())
+			}
+		})
+	}
+}
+
+func TestEvaluateQuorumIPv6(t *testing.T) {
+	m := newQuorumTestMonitor(0, "", TargetDown, TargetDown, TargetUp)
+	m.ipv6 = true
+	e, changed := m.evaluateQuorum()
+	if !changed || e.Signal != SignalTCP6 || e.Status != StatusFail || e.Reason != "tcp6_quorum_lost" {
+		t.Errorf("evaluateQuorum() = %s %s %q, %v; want %s fail tcp6_quorum_lost", e.Signal, e.Status, e.Reason, changed, SignalTCP6)
+	}
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		delay    time.Duration
+		maxDelay time.Duration
+		failures int
+		want     time.Duration // before jitter
+	}{
+		{name: "no failures yet", delay: time.Second, maxDelay: time.Minute, failures: 0, want: time.Second},
+		{name: "first failure", delay: time.Second, maxDelay: time.Minute, failures: 1, want: time.Second},
+		{name: "doubles", delay: time.Second, maxDelay: time.Minute, failures: 2, want: 2 * time.Second},
+		{name: "doubles again", delay: time.Second, maxDelay: time.Minute, failures: 5, want: 16 * time.Second},
+		{name: "capped", delay: time.Second, maxDelay: 30 * time.Second, failures: 6, want: 30 * time.Second},
+		{name: "capped after many failures", delay: time.Second, maxDelay: 30 * time.Second, failures: 1000,
+			want: 30 * time.Second},
+		{name: "delay above the cap", delay: time.Minute, maxDelay: 30 * time.Second, failures: 1, want: 30 * time.Second},
+		{name: "no delay", maxDelay: 30 * time.Second, failures: 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := TCPConfig{ReconnectDelay: tc.delay, ReconnectMaxDelay: tc.maxDelay}
+			seen := make(map[time.Duration]bool)
+			for range 200 {
+				d := cfg.reconnectBackoff(tc.failures)
+				if d < tc.want/2 || d > tc.want {
+					t.Fatalf("reconnectBackoff(%d) = %v, want within [%v, %v]", tc.failures, d, tc.want/2, tc.want)
+				}
+				seen[d] = true
+			}
+			// The upper half is randomized so targets do not retry in lockstep
+			if tc.want > 0 && len(seen) < 2 {
+				t.Errorf("reconnectBackoff(%d) always returned %v, want jitter", tc.failures, tc.want)
+			}
+		})
+	}
+}
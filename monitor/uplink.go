@@ -0,0 +1,184 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// uplinkSignalOrder fixes the order in which failing signals of an uplink
+// are listed in reasons.
+var uplinkSignalOrder = []Signal{SignalTCP, SignalTCPHealth, SignalDNS, SignalHTTP, SignalCaptivePortal}
+
+// UplinkMonitor runs a TCP keepalive monitor and a watchdog pinned to one
+// uplink and combines their signals into the health of that uplink. Its
+// events carry the uplink name and do not affect the overall state.
+type UplinkMonitor struct {
+	logger   *Logger
+	cfg      UplinkConfig
+	cancel   context.CancelFunc
+	tcp      *TCPKeepaliveMonitor
+	watchdog *WatchdogMonitor
+
+	mu      sync.Mutex
+	signals map[Signal]signalStatus
+	state   ConnState
+	since   time.Time
+	reasons []string
+}
+
+// UplinkStatus describes the health of one uplink in a MonitorStatus.
+type UplinkStatus struct {
+	Name       string         `json:"name"`
+	Interface  string         `json:"interface,omitempty"`
+	Source     string         `json:"source,omitempty"`
+	State      ConnState      `json:"state"`
+	Since      time.Time      `json:"since"`
+	Reasons    []string       `json:"reasons,omitempty"`
+	TCPTargets []TargetStatus `json:"tcp_targets"`
+}
+
+// NewUplinkMonitor constructs the probes of uplink with the TCP and watchdog
+// settings of cfg.
+func NewUplinkMonitor(ctx context.Context, logger *Logger, uplink UplinkConfig, cfg *Config) *UplinkMonitor {
+	ctx, cancel := context.WithCancel(ctx)
+	return &UplinkMonitor{
+		logger:   logger,
+		cfg:      uplink,
+		cancel:   cancel,
+		tcp:      newUplinkTCPKeepaliveMonitor(ctx, logger, cfg.TCP, &uplink),
+		watchdog: newUplinkWatchdogMonitor(ctx, logger, cfg.Watchdog, cfg.System.ResolvConf, &uplink),
+		signals:  make(map[Signal]signalStatus),
+		state:    StateUnknown,
+		since:    time.Now(),
+	}
+}
+
+// Start launches the pinned probes.
+func (u *UplinkMonitor) Start() error {
+	u.emit(Event{Message: fmt.Sprintf("Starting probes pinned to uplink %s", u.cfg)})
+
+	if err := u.tcp.Start(); err != nil {
+		return fmt.Errorf("failed to start TCP keepalive monitor: %w", err)
+	}
+	if err := u.watchdog.Start(); err != nil {
+		return fmt.Errorf("failed to start watchdog monitor: %w", err)
+	}
+	return nil
+}
+
+// Stop stops the pinned probes.
+func (u *UplinkMonitor) Stop() {
+	u.cancel()
+}
+
+// Reconfigure applies new TCP and watchdog settings to the pinned probes.
+func (u *UplinkMonitor) Reconfigure(cfg *Config) {
+	u.tcp.Reconfigure(cfg.TCP)
+	u.watchdog.Reconfigure(cfg.Watchdog, cfg.System.ResolvConf)
+}
+
+// HandleEvent updates the uplink health from the signals of its probes and
+// passes network changes on to its TCP monitor. It is meant to be called
+// from a Logger hook.
+func (u *UplinkMonitor) HandleEvent(e Event) {
+	u.tcp.HandleEvent(e)
+
+	if e.Uplink != u.cfg.Name || e.Signal == "" || e.Status == "" {
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	reason := e.Reason
+	if reason == "" && e.Status != StatusOK {
+		reason = fmt.Sprintf("%s_%s", e.Signal, e.Status)
+	}
+	u.signals[e.Signal] = signalStatus{status: e.Status, reason: reason}
+
+	next, reasons := u.evaluate()
+	if next == u.state {
+		u.reasons = reasons
+		return
+	}
+
+	prev := u.state
+	u.state, u.since, u.reasons = next, e.Time, reasons
+
+	severity := SeverityInfo
+	switch next {
+	case StateOffline:
+		severity = SeverityError
+	case StateDegraded:
+		severity = SeverityWarn
+	}
+	msg := fmt.Sprintf("Uplink %s %s -> %s", u.cfg, prev, next)
+	if len(reasons) > 0 {
+		msg += " reason=" + strings.Join(reasons, ",")
+	}
+	u.emit(Event{
+		Severity:  severity,
+		Interface: u.cfg.Interface,
+		Address:   u.cfg.Source,
+		Reason:    strings.Join(reasons, ","),
+		Message:   msg,
+		Fields:    map[string]any{"from": string(prev), "to": string(next)},
+	})
+}
+
+// evaluate derives the uplink health: offline while its TCP quorum is lost,
+// degraded while any other probe fails or warns, unknown until the TCP
+// monitor has reported. Callers hold u.mu.
+func (u *UplinkMonitor) evaluate() (ConnState, []string) {
+	tcp, known := u.signals[SignalTCP]
+	if !known {
+		return StateUnknown, nil
+	}
+
+	var reasons []string
+	for _, signal := range uplinkSignalOrder {
+		if st, ok := u.signals[signal]; ok && st.status != StatusOK {
+			reasons = append(reasons, st.reason)
+		}
+	}
+
+	switch {
+	case tcp.status == StatusFail:
+		return StateOffline, reasons
+	case len(reasons) > 0:
+		return StateDegraded, reasons
+	default:
+		return StateOnline, nil
+	}
+}
+
+// Status returns the health of the uplink and the state of its TCP targets.
+func (u *UplinkMonitor) Status() UplinkStatus {
+	u.mu.Lock()
+	status := UplinkStatus{
+		Name:      u.cfg.Name,
+		Interface: u.cfg.Interface,
+		Source:    u.cfg.Source,
+		State:     u.state,
+		Since:     u.since,
+		Reasons:   append([]string(nil), u.reasons...),
+	}
+	u.mu.Unlock()
+
+	// The TCP monitor emits its events, which HandleEvent handles under
+	// u.mu, while holding its own lock; taking its lock under u.mu would
+	// deadlock.
+	status.TCPTargets = u.tcp.Targets()
+	return status
+}
+
+// emit stamps the event with the uplink category and name and forwards it to the logger.
+func (u *UplinkMonitor) emit(e Event) {
+	e.Category = CategoryUplink
+	e.Source = SourceMonitor
+	e.Uplink = u.cfg.Name
+	u.logger.Emit(e)
+}
@@ -0,0 +1,64 @@
+package monitor
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestUplinkStatusWhileTCPEmits calls Status while the TCP monitor emits an
+// aggregate event, which it does holding its lock, and the event reaches the
+// uplink through a logger hook.
+func TestUplinkStatusWhileTCPEmits(t *testing.T) {
+	logger, err := NewLogger(filepath.Join(t.TempDir(), "network.log"), LoggerOptions{MinLevel: SeverityCritical})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = logger.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := DefaultConfig()
+	cfg.TCP.Targets = []string{"192.0.2.1:443"}
+	u := NewUplinkMonitor(ctx, logger, UplinkConfig{Name: "wan", Source: "192.0.2.10"}, cfg)
+	u.tcp.targets = map[string]*TargetStatus{
+		"192.0.2.1:443": {Target: "192.0.2.1:443", State: TargetUnknown, Since: time.Now()},
+	}
+
+	statusDone := make(chan UplinkStatus, 1)
+	logger.AddHook(func(e Event) {
+		if e.Signal != SignalTCP || e.Uplink != "wan" {
+			return
+		}
+		// Let Status run while the TCP monitor holds its lock, before the
+		// event reaches the uplink
+		go func() { statusDone <- u.Status() }()
+		time.Sleep(50 * time.Millisecond)
+	})
+	logger.AddHook(u.HandleEvent)
+
+	emitted := make(chan struct{})
+	go func() {
+		defer close(emitted)
+		u.tcp.targetUp(ctx, "192.0.2.1:443", 0, Event{Target: "192.0.2.1:443", Message: "connected"})
+	}()
+
+	timeout := time.After(5 * time.Second)
+	select {
+	case <-emitted:
+	case <-timeout:
+		t.Fatal("the TCP event did not reach the uplink: deadlock")
+	}
+	select {
+	case status := <-statusDone:
+		if len(status.TCPTargets) != 1 || status.TCPTargets[0].State != TargetUp {
+			t.Errorf("Status().TCPTargets = %+v, want the target up", status.TCPTargets)
+		}
+	case <-timeout:
+		t.Fatal("Status() did not return: deadlock")
+	}
+	if status := u.Status(); status.State != StateOnline {
+		t.Errorf("Status().State = %s, want %s", status.State, StateOnline)
+	}
+}
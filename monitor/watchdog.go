@@ -5,156 +5,370 @@ import (
 	"fmt"
 	"net"
 	"net/http"
-	"runtime"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-const (
-	watchdogInterval = 30 * time.Second
-	dnsTestDomain    = "www.google.com"
-	httpTestURL      = "https://www.google.com"
-	httpTimeout      = 10 * time.Second
-)
-
-// WatchdogMonitor performs periodic DNS/HTTP/default route checks.
+// WatchdogMonitor periodically runs the built-in route, DNS and HTTP checks
+// and the checks added in the config. Checks sharing an interval run
+// together as a round: concurrently, each with its own timeout, followed by
+// a summary of the round.
 type WatchdogMonitor struct {
-	logger     *Logger
-	ctx        context.Context
-	httpClient *http.Client
+	logger *Logger
+	ctx    context.Context
+	mu     sync.Mutex
+	cfg    WatchdogConfig
+	// resolvConf is system.resolv_conf, the default of the checks that
+	// read the system's nameservers.
+	resolvConf string
+	cancel     context.CancelFunc // stops the rounds of the current config
+	rounds     atomic.Int64       // numbers the rounds
+
+	uplink *UplinkConfig // pins the probes to an uplink; nil follows the routing table
+}
+
+// scheduledCheck is a check with its schedule. Results of built-in checks
+// are reported as signal; configured checks have no signal and do not
+// change the connectivity state.
+type scheduledCheck struct {
+	check    Check
+	signal   Signal
+	interval time.Duration
+	timeout  time.Duration
 }
 
 // NewWatchdogMonitor constructs a watchdog monitor.
-func NewWatchdogMonitor(ctx context.Context, logger *Logger) *WatchdogMonitor {
+func NewWatchdogMonitor(ctx context.Context, logger *Logger, cfg WatchdogConfig, resolvConf string) *WatchdogMonitor {
 	return &WatchdogMonitor{
-		logger: logger,
-		ctx:    ctx,
-		httpClient: &http.Client{
-			Timeout: httpTimeout,
-			CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
-				return http.ErrUseLastResponse // Don't follow redirects (captive portal detection)
-			},
+		logger:     logger,
+		ctx:        ctx,
+		cfg:        cfg,
+		resolvConf: resolvConf,
+	}
+}
+
+// newUplinkWatchdogMonitor constructs a watchdog whose DNS and HTTP checks
+// are pinned to uplink. The default route check, the IPv6 checks and the
+// configured checks are left to the main watchdog.
+func newUplinkWatchdogMonitor(ctx context.Context, logger *Logger, cfg WatchdogConfig, resolvConf string, uplink *UplinkConfig) *WatchdogMonitor {
+	m := NewWatchdogMonitor(ctx, logger, cfg, resolvConf)
+	m.uplink = uplink
+	return m
+}
+
+func newWatchdogHTTPClient(timeout time.Duration, uplink *UplinkConfig) *http.Client {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if uplink != nil {
+		t.DialContext = newDialer(uplink, "tcp", timeout).DialContext
+	}
+	return newHTTPClient(timeout, t)
+}
+
+// newFamilyHTTPClient returns a client that connects over network (tcp4 or
+// tcp6) only, so the HTTP check cannot fall back to the other family and
+// hide a broken path.
+func newFamilyHTTPClient(timeout time.Duration, network string) *http.Client {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: timeout}
+	t.DialContext = func(ctx context.Context, _, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, address)
+	}
+	return newHTTPClient(timeout, t)
+}
+
+// newHTTPClient returns a client that opens a new connection for every
+// request, so that every round measures DNS, connect and TLS handshake
+// alike.
+func newHTTPClient(timeout time.Duration, transport *http.Transport) *http.Client {
+	transport.DisableKeepAlives = true
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+			return http.ErrUseLastResponse // Don't follow redirects (captive portal detection)
 		},
 	}
 }
 
+// Reconfigure replaces the checks with ones built from a new configuration.
+func (m *WatchdogMonitor) Reconfigure(cfg WatchdogConfig, resolvConf string) {
+	m.mu.Lock()
+	if reflect.DeepEqual(cfg, m.cfg) && resolvConf == m.resolvConf {
+		m.mu.Unlock()
+		return
+	}
+	m.cfg, m.resolvConf = cfg, resolvConf
+	m.mu.Unlock()
+
+	m.startChecks(m.buildChecks(cfg, resolvConf))
+}
+
+func (m *WatchdogMonitor) config() (WatchdogConfig, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cfg, m.resolvConf
+}
+
 // Start begins the watchdog periodic checks.
 func (m *WatchdogMonitor) Start() error {
-	m.logger.Log("WATCHDOG", "Starting watchdog monitor")
+	checks := m.buildChecks(m.config())
+	m.emit(Event{Message: fmt.Sprintf("Starting watchdog monitor (%s)", describeSchedule(checks))})
 
-	go m.runChecks()
+	m.startChecks(checks)
+
+	go func() {
+		<-m.ctx.Done()
+		m.emit(Event{Message: "Stopped watchdog monitor"})
+	}()
 
 	return nil
 }
 
-func (m *WatchdogMonitor) runChecks() {
-	m.performChecks()
-
-	ticker := time.NewTicker(watchdogInterval)
-	defer ticker.Stop()
+// buildChecks builds the built-in checks, adjusted by the config entries
+// named after them, and the configured checks. A check that cannot be
+// built is logged and left out.
+func (m *WatchdogMonitor) buildChecks(cfg WatchdogConfig, resolvConf string) []scheduledCheck {
+	env := CheckEnv{Watchdog: cfg, Uplink: m.uplink, ResolvConf: resolvConf}
+	overrides := make(map[string]CheckConfig)
+	for _, c := range cfg.Checks {
+		if c.Type == "" {
+			overrides[c.Name] = c
+		}
+	}
 
-	for {
-		select {
-		case <-m.ctx.Done():
-			m.logger.Log("WATCHDOG", "Stopped watchdog monitor")
+	var checks []scheduledCheck
+	add := func(c CheckConfig, signal Signal) {
+		if c.Interval <= 0 {
+			c.Interval = cfg.Interval
+		}
+		if c.Timeout <= 0 {
+			c.Timeout = defaultCheckTimeout
+		}
+		check, err := NewCheck(c, env)
+		if err != nil {
+			m.emit(Event{
+				Severity: SeverityError,
+				Err:      err,
+				Message:  fmt.Sprintf("Failed to set up check %s: %v", c.Name, err),
+			})
 			return
-		case <-ticker.C:
-			m.performChecks()
 		}
+		checks = append(checks, scheduledCheck{check: check, signal: signal, interval: c.Interval, timeout: c.Timeout})
 	}
-}
-
-func (m *WatchdogMonitor) performChecks() {
-	m.logger.Log("WATCHDOG", "Running periodic checks...")
 
-	m.checkDefaultRoute()
-	m.checkDNS()
-	m.checkHTTP()
+	for _, b := range builtinChecks(cfg, m.uplink != nil) {
+		c := b.cfg
+		if o, ok := overrides[c.Name]; ok {
+			if o.Disabled {
+				continue
+			}
+			if o.Interval > 0 {
+				c.Interval = o.Interval
+			}
+			if o.Timeout > 0 {
+				c.Timeout = o.Timeout
+			}
+		}
+		add(c, b.signal)
+	}
+	if m.uplink == nil {
+		for _, c := range cfg.Checks {
+			if c.Type != "" && !c.Disabled {
+				add(c, "")
+			}
+		}
+	}
+	return checks
 }
 
-func (m *WatchdogMonitor) checkDefaultRoute() {
-	switch runtime.GOOS {
-	case "linux":
-		m.checkDefaultRouteLinux()
-	case "darwin", "windows":
-		m.checkDefaultRouteGeneric()
-	default:
-		m.logger.Log("WATCHDOG", "Default route check not supported on this platform")
+// describeSchedule lists the checks by interval, e.g.
+// "every 30s: route, dns, http; every 10s: vpn".
+func describeSchedule(checks []scheduledCheck) string {
+	var parts []string
+	for _, round := range groupByInterval(checks) {
+		names := make([]string, len(round))
+		for i, sc := range round {
+			names[i] = sc.check.Name()
+		}
+		parts = append(parts, fmt.Sprintf("every %v: %s", round[0].interval, strings.Join(names, ", ")))
 	}
+	if len(parts) == 0 {
+		return "no checks"
+	}
+	return strings.Join(parts, "; ")
 }
 
-func (m *WatchdogMonitor) checkDefaultRouteGeneric() {
-	// Generic check - try to get a UDP connection to check routing
-	conn, err := net.DialTimeout("udp", "8.8.8.8:53", 2*time.Second)
-	if err != nil {
-		m.logger.Log("WATCHDOG", "✗ WARNING: Cannot establish UDP connection (no route?)")
-		return
+// groupByInterval groups checks with the same interval into rounds, in
+// the order the intervals first appear.
+func groupByInterval(checks []scheduledCheck) [][]scheduledCheck {
+	var rounds [][]scheduledCheck
+	index := make(map[time.Duration]int)
+	for _, sc := range checks {
+		i, ok := index[sc.interval]
+		if !ok {
+			i = len(rounds)
+			index[sc.interval] = i
+			rounds = append(rounds, nil)
+		}
+		rounds[i] = append(rounds[i], sc)
 	}
-	defer func() { _ = conn.Close() }()
-
-	localAddr := conn.LocalAddr().String()
-	m.logger.Log("WATCHDOG", fmt.Sprintf("✓ Default route exists (local addr: %s)", localAddr))
+	return rounds
 }
 
-func (m *WatchdogMonitor) checkDNS() {
-	start := time.Now()
+// startChecks stops the rounds of the previous config, if any, and starts
+// one loop per interval.
+func (m *WatchdogMonitor) startChecks(checks []scheduledCheck) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	resolver := &net.Resolver{
-		PreferGo: true,
-		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-			d := net.Dialer{
-				Timeout: 5 * time.Second,
-			}
-			return d.DialContext(ctx, network, address)
-		},
+	if m.cancel != nil {
+		m.cancel()
 	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.cancel = cancel
 
-	ctx, cancel := context.WithTimeout(m.ctx, 5*time.Second)
-	defer cancel()
+	for _, round := range groupByInterval(checks) {
+		go m.runRounds(ctx, round)
+	}
+}
 
-	addrs, err := resolver.LookupHost(ctx, dnsTestDomain)
-	duration := time.Since(start)
+func (m *WatchdogMonitor) runRounds(ctx context.Context, checks []scheduledCheck) {
+	m.runRound(ctx, checks)
 
-	if err != nil {
-		m.logger.Log("WATCHDOG", fmt.Sprintf("✗ DNS FAILED: %v (took %v)", err, duration))
-		return
-	}
+	ticker := time.NewTicker(checks[0].interval)
+	defer ticker.Stop()
 
-	if len(addrs) > 0 {
-		m.logger.Log("WATCHDOG", fmt.Sprintf("✓ DNS working: %s -> %s (took %v)",
-			dnsTestDomain, addrs[0], duration))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.runRound(ctx, checks)
+		}
 	}
 }
 
-func (m *WatchdogMonitor) checkHTTP() {
+// runRound runs the checks concurrently, so a slow check neither delays
+// the others nor skews their timestamps. Every result is logged as it
+// arrives and the round is then summarized in one event.
+func (m *WatchdogMonitor) runRound(ctx context.Context, checks []scheduledCheck) {
+	round := m.rounds.Add(1)
+	m.emit(Event{Severity: SeverityDebug, Message: fmt.Sprintf("Running round %d of periodic checks...", round)})
+
 	start := time.Now()
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, sc := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, sc.timeout)
+			defer cancel()
+			results[i] = sc.check.Run(checkCtx)
+			if ctx.Err() == nil {
+				m.report(round, sc, results[i])
+			}
+		}()
+	}
+	wg.Wait()
 
-	req, err := http.NewRequestWithContext(m.ctx, "HEAD", httpTestURL, nil)
-	if err != nil {
-		m.logger.Log("WATCHDOG", fmt.Sprintf("✗ HTTP request creation failed: %v", err))
+	if ctx.Err() != nil {
 		return
 	}
+	m.summarize(round, checks, results, time.Since(start))
+}
 
-	resp, err := m.httpClient.Do(req)
-	duration := time.Since(start)
+// summarize emits the summary event of a round: the outcome of every check,
+// how many passed, warned and failed, and how long the round took.
+func (m *WatchdogMonitor) summarize(round int64, checks []scheduledCheck, results []Result, took time.Duration) {
+	var passed, warned, failed int
+	var problems []string
+	summary := make([]map[string]any, len(checks))
+	for i, r := range results {
+		name := checks[i].check.Name()
+		status := r.Status
+		switch status {
+		case StatusOK:
+			passed++
+		case StatusWarn:
+			warned++
+			problems = append(problems, name)
+		default:
+			// A check that could not run counts as failed
+			failed++
+			problems = append(problems, name)
+			if status == "" {
+				status = "error"
+			}
+		}
+		entry := map[string]any{"check": name, "status": string(status), "latency_ms": durationMillis(r.Latency)}
+		if r.Reason != "" {
+			entry["reason"] = r.Reason
+		}
+		summary[i] = entry
+	}
 
-	if err != nil {
-		m.logger.Log("WATCHDOG", fmt.Sprintf("✗ HTTP FAILED: %v (took %v)", err, duration))
-		return
+	msg := fmt.Sprintf("Round %d: %d of %d checks passed in %v", round, passed, len(checks), took.Round(time.Millisecond))
+	severity := SeverityInfo
+	if len(problems) > 0 {
+		msg += fmt.Sprintf(" (not ok: %s)", strings.Join(problems, ", "))
+		severity = SeverityWarn
 	}
-	defer func() { _ = resp.Body.Close() }()
+	m.emit(Event{
+		Severity: severity,
+		Duration: took,
+		Message:  msg,
+		Fields: map[string]any{
+			"round":   round,
+			"passed":  passed,
+			"warned":  warned,
+			"failed":  failed,
+			"results": summary,
+		},
+	})
+}
 
-	// Detect captive portal
-	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
-		location := resp.Header.Get("Location")
-		m.logger.Log("WATCHDOG", fmt.Sprintf("⚠ CAPTIVE PORTAL detected: redirect to %s", location))
-		return
+// report logs the result of a check in a round. Messages of configured
+// checks are prefixed with the check name.
+func (m *WatchdogMonitor) report(round int64, sc scheduledCheck, r Result) {
+	name := sc.check.Name()
+	fields := make(map[string]any, len(r.Details)+2)
+	for k, v := range r.Details {
+		fields[k] = v
 	}
+	fields["check"] = name
+	fields["round"] = round
+
+	e := Event{
+		Severity: r.severity(),
+		Status:   r.Status,
+		Reason:   r.Reason,
+		Target:   r.Target,
+		Address:  r.Address,
+		Gateway:  r.Gateway,
+		Duration: r.Latency,
+		Err:      r.Err,
+		Message:  r.Message,
+		Fields:   fields,
+	}
+	if r.Status != "" {
+		e.Signal = sc.signal
+	}
+	if sc.signal == "" {
+		e.Message = fmt.Sprintf("Check %s: %s", name, r.Message)
+	}
+	m.emit(e)
+}
 
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		m.logger.Log("WATCHDOG", fmt.Sprintf("✓ HTTP working: %d %s (took %v)",
-			resp.StatusCode, resp.Status, duration))
-	} else {
-		m.logger.Log("WATCHDOG", fmt.Sprintf("⚠ HTTP unexpected status: %d %s (took %v)",
-			resp.StatusCode, resp.Status, duration))
+// emit stamps the event with the watchdog category and source and forwards it to the logger.
+func (m *WatchdogMonitor) emit(e Event) {
+	e.Category = CategoryWatchdog
+	e.Source = SourceWatchdog
+	if m.uplink != nil {
+		e.Uplink = m.uplink.Name
 	}
+	m.logger.Emit(e)
 }
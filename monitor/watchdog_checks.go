@@ -0,0 +1,364 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"runtime"
+	"time"
+)
+
+// The check types every build provides.
+func init() {
+	RegisterCheck("route", newRouteCheck)
+	RegisterCheck("dns", newDNSCheck)
+	RegisterCheck("http", newHTTPCheck)
+	RegisterCheck("tcp", newTCPCheck)
+}
+
+// ipFamilyParam validates the family key of a check: 0 for whichever family
+// the system picks, 4 or 6.
+func ipFamilyParam(family int) error {
+	switch family {
+	case 0, 4, 6:
+		return nil
+	}
+	return fmt.Errorf("family must be 4 or 6, got %d", family)
+}
+
+// errNoRouteTable is returned by readDefaultRoute and readDefaultRoute6
+// where the routing table cannot be read (everywhere but Linux).
+var errNoRouteTable = errors.New("routing table cannot be read on this platform")
+
+// probeDefaultRoute looks up the IPv4 or, with v6, IPv6 default route like
+// readDefaultRoute. Where the routing table cannot be read it infers the
+// route from whether the OS can route a UDP socket to probe
+// (watchdog.route_probe or route_probe6), and the gateway is unknown.
+func probeDefaultRoute(ctx context.Context, v6 bool, probe string) (bool, net.IP, error) {
+	lookup, network := readDefaultRoute, "udp4"
+	if v6 {
+		lookup, network = readDefaultRoute6, "udp6"
+	}
+	found, gw, err := lookup()
+	if !errors.Is(err, errNoRouteTable) {
+		return found, gw, err
+	}
+
+	d := net.Dialer{Timeout: 2 * time.Second}
+	conn, err := d.DialContext(ctx, network, probe)
+	if err != nil {
+		return false, nil, nil //nolint:nilerr // an unroutable destination means no route, not a failure
+	}
+	_ = conn.Close()
+	return true, nil, nil
+}
+
+// routeCheck verifies that the routing table has a default route.
+type routeCheck struct {
+	name  string
+	v6    bool
+	probe string // UDP address used where the routing table cannot be read
+}
+
+func newRouteCheck(cfg CheckConfig, env CheckEnv) (Check, error) {
+	var p struct {
+		Family int    `yaml:"family"`
+		Probe  string `yaml:"probe"`
+	}
+	if err := cfg.DecodeParams(&p); err != nil {
+		return nil, err
+	}
+	if err := ipFamilyParam(p.Family); err != nil {
+		return nil, err
+	}
+
+	c := &routeCheck{name: cfg.Name, v6: p.Family == 6, probe: p.Probe}
+	switch {
+	case c.probe != "":
+	case c.v6:
+		c.probe = env.Watchdog.RouteProbe6
+	default:
+		c.probe = env.Watchdog.RouteProbe
+	}
+	if err := validateHostPort(c.probe); err != nil {
+		return nil, fmt.Errorf("probe: %w", err)
+	}
+	return c, nil
+}
+
+func (c *routeCheck) Name() string { return c.name }
+
+func (c *routeCheck) Run(ctx context.Context) Result {
+	switch runtime.GOOS {
+	case "linux":
+		return routeTableResult(c.v6)
+	case "darwin", "windows":
+		return c.probeRoute(ctx)
+	default:
+		return Result{Severity: SeverityWarn.Override(), Message: "Default route check not supported on this platform"}
+	}
+}
+
+// probeRoute infers the default route from whether the OS can route a UDP
+// socket to the probe address.
+func (c *routeCheck) probeRoute(ctx context.Context) Result {
+	network, reason := "udp4", "no_route"
+	found, udp := "Default route exists", "UDP"
+	if c.v6 {
+		network, reason = "udp6", "no_route6"
+		found, udp = "IPv6 default route exists", "IPv6 UDP"
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, c.probe)
+	if err != nil {
+		return Result{
+			Status:  StatusFail,
+			Reason:  reason,
+			Err:     err,
+			Message: fmt.Sprintf("✗ Cannot establish %s connection (no route?)", udp),
+		}
+	}
+	defer func() { _ = conn.Close() }()
+
+	localAddr := conn.LocalAddr().String()
+	return Result{
+		Status:  StatusOK,
+		Address: localAddr,
+		Message: fmt.Sprintf("✓ %s (local addr: %s)", found, localAddr),
+	}
+}
+
+// dnsCheck resolves a domain with the system's nameservers.
+type dnsCheck struct {
+	name   string
+	domain string
+	uplink *UplinkConfig
+}
+
+func newDNSCheck(cfg CheckConfig, env CheckEnv) (Check, error) {
+	var p struct {
+		Domain string `yaml:"domain"`
+	}
+	if err := cfg.DecodeParams(&p); err != nil {
+		return nil, err
+	}
+	if p.Domain == "" {
+		p.Domain = env.Watchdog.DNSDomain
+	}
+	return &dnsCheck{name: cfg.Name, domain: p.Domain, uplink: env.Uplink}, nil
+}
+
+func (c *dnsCheck) Name() string { return c.name }
+
+func (c *dnsCheck) Run(ctx context.Context) Result {
+	start := time.Now()
+
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return newDialer(c.uplink, network, 0).DialContext(ctx, network, address)
+		},
+	}
+
+	addrs, err := resolver.LookupHost(ctx, c.domain)
+	duration := time.Since(start)
+
+	if err != nil {
+		return Result{
+			Status:  StatusFail,
+			Reason:  "dns_failed",
+			Target:  c.domain,
+			Latency: duration,
+			Err:     err,
+			Message: fmt.Sprintf("✗ DNS FAILED: %v (took %v)", err, duration),
+		}
+	}
+
+	return Result{
+		Status:  StatusOK,
+		Target:  c.domain,
+		Address: addrs[0],
+		Latency: duration,
+		Message: fmt.Sprintf("✓ DNS working: %s -> %s (took %v)", c.domain, addrs[0], duration),
+	}
+}
+
+// httpCheck sends a HEAD request. A redirect proves the server answered;
+// captive portals are detected by the captive_portal check, with endpoints
+// whose answer is known.
+type httpCheck struct {
+	name   string
+	url    string
+	client *http.Client
+	label  string // names the check in messages
+	prefix string // prefixes the reasons
+}
+
+func newHTTPCheck(cfg CheckConfig, env CheckEnv) (Check, error) {
+	var p struct {
+		URL    string `yaml:"url"`
+		Family int    `yaml:"family"`
+	}
+	if err := cfg.DecodeParams(&p); err != nil {
+		return nil, err
+	}
+	if err := ipFamilyParam(p.Family); err != nil {
+		return nil, err
+	}
+	if p.URL == "" {
+		p.URL = env.Watchdog.HTTPURL
+	}
+	if u, err := url.Parse(p.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("url must be an absolute http or https URL, got %q", p.URL)
+	}
+
+	c := &httpCheck{name: cfg.Name, url: p.URL, label: "HTTP", prefix: "http"}
+	switch p.Family {
+	case 4:
+		c.client = newFamilyHTTPClient(cfg.Timeout, "tcp4")
+		c.label, c.prefix = "HTTP over IPv4", "http4"
+	case 6:
+		c.client = newFamilyHTTPClient(cfg.Timeout, "tcp6")
+		c.label, c.prefix = "HTTP over IPv6", "http6"
+	default:
+		c.client = newWatchdogHTTPClient(cfg.Timeout, env.Uplink)
+	}
+	return c, nil
+}
+
+func (c *httpCheck) Name() string { return c.name }
+
+func (c *httpCheck) Run(ctx context.Context) Result {
+	start := time.Now()
+
+	trace, traceCtx := newHTTPTrace(ctx)
+	req, err := http.NewRequestWithContext(traceCtx, "HEAD", c.url, nil)
+	if err != nil {
+		return Result{
+			Target:  c.url,
+			Err:     err,
+			Message: fmt.Sprintf("✗ %s request creation failed: %v", c.label, err),
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	duration := time.Since(start)
+	timing := trace.timing()
+	details := map[string]any{}
+	timing.addDetails(details)
+
+	if err != nil {
+		details["stage"] = timing.stage
+		stage := timing.stage
+		if s := timing.String(); s != "" {
+			stage += " after " + s
+		}
+		return Result{
+			Status:  StatusFail,
+			Reason:  c.prefix + "_failed",
+			Target:  c.url,
+			Latency: duration,
+			Err:     err,
+			Message: fmt.Sprintf("✗ %s FAILED in %s: %v (took %v)", c.label, stage, err, duration),
+			Details: details,
+		}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	details["http_status"] = resp.StatusCode
+
+	r := Result{
+		Status:  StatusOK,
+		Target:  c.url,
+		Latency: duration,
+		Details: details,
+	}
+	if location := resp.Header.Get("Location"); location != "" {
+		details["location"] = location
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+		r.Message = fmt.Sprintf("✓ %s working: %s%s (took %v)",
+			c.label, resp.Status, timingSuffix(timing), duration)
+	} else {
+		r.Status = StatusWarn
+		r.Reason = c.prefix + "_unexpected_status"
+		r.Message = fmt.Sprintf("⚠ %s unexpected status: %s%s (took %v)",
+			c.label, resp.Status, timingSuffix(timing), duration)
+	}
+	return r
+}
+
+// timingSuffix returns the breakdown of a request to append to its
+// outcome, or nothing when no stage was timed. The report reads the total
+// from "(took ...)", which must stay at the end of the message.
+func timingSuffix(t httpTiming) string {
+	if s := t.String(); s != "" {
+		return ", " + s
+	}
+	return ""
+}
+
+// tcpCheck opens and closes a TCP connection, e.g. to an internal service
+// or a VPN endpoint.
+type tcpCheck struct {
+	name    string
+	target  string
+	network string
+	uplink  *UplinkConfig
+}
+
+func newTCPCheck(cfg CheckConfig, env CheckEnv) (Check, error) {
+	var p struct {
+		Target string `yaml:"target"`
+		Family int    `yaml:"family"`
+	}
+	if err := cfg.DecodeParams(&p); err != nil {
+		return nil, err
+	}
+	if err := ipFamilyParam(p.Family); err != nil {
+		return nil, err
+	}
+	if p.Target == "" {
+		return nil, errors.New("target must be set to host:port")
+	}
+	if err := validateHostPort(p.Target); err != nil {
+		return nil, fmt.Errorf("target: %w", err)
+	}
+
+	network := "tcp"
+	if p.Family != 0 {
+		network = fmt.Sprintf("tcp%d", p.Family)
+	}
+	return &tcpCheck{name: cfg.Name, target: p.Target, network: network, uplink: env.Uplink}, nil
+}
+
+func (c *tcpCheck) Name() string { return c.name }
+
+func (c *tcpCheck) Run(ctx context.Context) Result {
+	start := time.Now()
+	conn, err := newDialer(c.uplink, c.network, 0).DialContext(ctx, c.network, c.target)
+	duration := time.Since(start)
+
+	if err != nil {
+		return Result{
+			Status:  StatusFail,
+			Reason:  "tcp_connect_failed",
+			Target:  c.target,
+			Latency: duration,
+			Err:     err,
+			Message: fmt.Sprintf("✗ Connection to %s failed: %v (took %v)", c.target, err, duration),
+		}
+	}
+	_ = conn.Close()
+
+	return Result{
+		Status:  StatusOK,
+		Target:  c.target,
+		Address: conn.LocalAddr().String(),
+		Latency: duration,
+		Message: fmt.Sprintf("✓ Connected to %s (took %v)", c.target, duration),
+	}
+}
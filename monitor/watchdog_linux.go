@@ -4,33 +4,78 @@ package monitor
 
 import (
 	"fmt"
+	"net"
 
 	"github.com/vishvananda/netlink"
 )
 
-func (m *WatchdogMonitor) checkDefaultRouteLinux() {
-	routes, err := netlink.RouteList(nil, netlink.FAMILY_V4)
+// routeTableResult checks the routing table for an IPv4 or IPv6 default route.
+func routeTableResult(v6 bool) Result {
+	lookup, reason := readDefaultRoute, "no_route"
+	found, missing := "Default route exists", "No default route found"
+	if v6 {
+		lookup, reason = readDefaultRoute6, "no_route6"
+		found, missing = "IPv6 default route exists", "No IPv6 default route found"
+	}
+
+	hasDefault, gw, err := lookup()
 	if err != nil {
-		m.logger.Log("WATCHDOG", fmt.Sprintf("ERROR: Failed to list routes: %v", err))
-		return
+		return Result{
+			Err:     err,
+			Message: fmt.Sprintf("Failed to list routes: %v", err),
+		}
 	}
 
-	hasDefault := false
 	var defaultGw string
+	if gw != nil {
+		defaultGw = gw.String()
+	}
+
+	if !hasDefault {
+		return Result{
+			Status:  StatusFail,
+			Reason:  reason,
+			Message: "✗ " + missing,
+		}
+	}
+	return Result{
+		Status:  StatusOK,
+		Gateway: defaultGw,
+		Message: fmt.Sprintf("✓ %s (via %s)", found, defaultGw),
+	}
+}
+
+// readDefaultRoute looks up the IPv4 default route in the routing table,
+// returning whether one exists and its gateway (nil for a direct route).
+func readDefaultRoute() (bool, net.IP, error) {
+	return defaultRouteFamily(netlink.FAMILY_V4)
+}
+
+// readDefaultRoute6 is readDefaultRoute for IPv6.
+func readDefaultRoute6() (bool, net.IP, error) {
+	return defaultRouteFamily(netlink.FAMILY_V6)
+}
 
+func defaultRouteFamily(family int) (bool, net.IP, error) {
+	routes, err := netlink.RouteList(nil, family)
+	if err != nil {
+		return false, nil, err
+	}
 	for _, route := range routes {
-		if route.Dst == nil {
-			hasDefault = true
-			if route.Gw != nil {
-				defaultGw = route.Gw.String()
-			}
-			break
+		if isDefaultRoute(route.Dst) {
+			return true, route.Gw, nil
 		}
 	}
+	return false, nil, nil
+}
 
-	if hasDefault {
-		m.logger.Log("WATCHDOG", fmt.Sprintf("✓ Default route exists (via %s)", defaultGw))
-	} else {
-		m.logger.Log("WATCHDOG", "✗ WARNING: No default route found")
+// isDefaultRoute reports whether dst is a default destination. Netlink
+// reports it as nil or, depending on the kernel and library version, as
+// 0.0.0.0/0 or ::/0.
+func isDefaultRoute(dst *net.IPNet) bool {
+	if dst == nil {
+		return true
 	}
+	ones, _ := dst.Mask.Size()
+	return ones == 0 && dst.IP.IsUnspecified()
 }
@@ -2,5 +2,14 @@
 
 package monitor
 
-// checkDefaultRouteLinux is unused on non-Linux platforms; defined to satisfy builds.
-func (m *WatchdogMonitor) checkDefaultRouteLinux() {}
+import "net"
+
+// routeTableResult is unused on non-Linux platforms; defined to satisfy builds.
+func routeTableResult(bool) Result { return Result{} }
+
+// readDefaultRoute fails with errNoRouteTable: the routing table cannot be
+// read on this platform.
+func readDefaultRoute() (bool, net.IP, error) { return false, nil, errNoRouteTable }
+
+// readDefaultRoute6 is readDefaultRoute for IPv6.
+func readDefaultRoute6() (bool, net.IP, error) { return false, nil, errNoRouteTable }
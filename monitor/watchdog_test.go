@@ -0,0 +1,201 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubCheck returns result after delay, or a timeout once ctx expires.
+type stubCheck struct {
+	name   string
+	result Result
+	delay  time.Duration
+}
+
+func (c stubCheck) Name() string { return c.name }
+
+func (c stubCheck) Run(ctx context.Context) Result {
+	select {
+	case <-time.After(c.delay):
+		return c.result
+	case <-ctx.Done():
+		return Result{Err: ctx.Err(), Message: c.name + " timed out"}
+	}
+}
+
+// newTestWatchdog returns a watchdog whose emitted events are recorded.
+func newTestWatchdog(t *testing.T) (*WatchdogMonitor, *eventRecorder) {
+	t.Helper()
+	logger, err := NewLogger(filepath.Join(t.TempDir(), "network.log"), LoggerOptions{MinLevel: SeverityCritical + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = logger.Close() })
+	rec := &eventRecorder{}
+	logger.AddHook(rec.record)
+	return NewWatchdogMonitor(context.Background(), logger, DefaultConfig().Watchdog, ""), rec
+}
+
+// scheduled wraps checks for a round; the first reports the dns signal.
+func scheduled(timeout time.Duration, checks ...Check) []scheduledCheck {
+	round := make([]scheduledCheck, len(checks))
+	for i, c := range checks {
+		round[i] = scheduledCheck{check: c, interval: time.Minute, timeout: timeout}
+	}
+	round[0].signal = SignalDNS
+	return round
+}
+
+// summaries returns the round summaries among events.
+func summaries(events []Event) []Event {
+	var found []Event
+	for _, e := range events {
+		if _, ok := e.Fields["results"]; ok {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+func TestWatchdogRound(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	m, rec := newTestWatchdog(t)
+	checks := scheduled(timeout,
+		stubCheck{name: "ok", result: Result{Status: StatusOK, Latency: 5 * time.Millisecond, Message: "fine"}},
+		stubCheck{name: "warn", result: Result{Status: StatusWarn, Reason: "slow_answer", Message: "slow"}},
+		stubCheck{name: "fail", result: Result{Status: StatusFail, Reason: "broken", Message: "broken"}},
+		stubCheck{name: "not run", result: Result{Message: "no permission"}},
+		stubCheck{name: "override", result: Result{Status: StatusFail, Severity: SeverityInfo.Override(), Message: "expected"}},
+		stubCheck{name: "slow", delay: time.Minute},
+	)
+
+	start := time.Now()
+	m.runRound(context.Background(), checks)
+	if took := time.Since(start); took > 10*timeout {
+		t.Fatalf("round took %v, want the slow check cut off at its timeout of %v", took, timeout)
+	}
+
+	rec.mu.Lock()
+	events := slices.Clone(rec.events)
+	rec.mu.Unlock()
+
+	// Every result is logged as it arrives, the slow one last
+	results := make(map[string]Event)
+	var order []string
+	for _, e := range events {
+		if name, ok := e.Fields["check"].(string); ok {
+			results[name] = e
+			order = append(order, name)
+		}
+	}
+	if len(order) != len(checks) || order[len(order)-1] != "slow" {
+		t.Fatalf("results logged in order %q, want every check with slow last", order)
+	}
+	for name, want := range map[string]Severity{
+		"ok": SeverityInfo, "warn": SeverityWarn, "fail": SeverityError, "not run": SeverityError,
+		"override": SeverityInfo, "slow": SeverityError,
+	} {
+		if got := results[name].Severity; got != want {
+			t.Errorf("severity of %s = %v, want %v", name, got, want)
+		}
+	}
+	if e := results["ok"]; e.Signal != SignalDNS || e.Status != StatusOK {
+		t.Errorf("ok result = signal %q status %q, want %s ok", e.Signal, e.Status, SignalDNS)
+	}
+	if e := results["not run"]; e.Signal != "" || e.Status != "" {
+		t.Errorf("not run result = signal %q status %q, want neither", e.Signal, e.Status)
+	}
+
+	sums := summaries(events)
+	if len(sums) != 1 {
+		t.Fatalf("got %d round summaries, want 1", len(sums))
+	}
+	sum := sums[0]
+	if _, ok := events[len(events)-1].Fields["results"]; !ok {
+		t.Error("the summary is not the last event of the round")
+	}
+	if sum.Fields["round"] != int64(1) || sum.Fields["passed"] != 1 || sum.Fields["warned"] != 1 || sum.Fields["failed"] != 4 {
+		t.Errorf("summary fields = %v, want round 1, 1 passed, 1 warned, 4 failed", sum.Fields)
+	}
+	if sum.Severity != SeverityWarn ||
+		!strings.HasPrefix(sum.Message, "Round 1: 1 of 6 checks passed in ") ||
+		!strings.HasSuffix(sum.Message, " (not ok: warn, fail, not run, override, slow)") {
+		t.Errorf("summary = %v %q", sum.Severity, sum.Message)
+	}
+	var statuses, reasons []string
+	for _, entry := range sum.Fields["results"].([]map[string]any) {
+		statuses = append(statuses, entry["status"].(string))
+		reason, _ := entry["reason"].(string)
+		reasons = append(reasons, reason)
+	}
+	if want := []string{"ok", "warn", "fail", "error", "fail", "error"}; !slices.Equal(statuses, want) {
+		t.Errorf("summary statuses = %q, want %q", statuses, want)
+	}
+	if want := []string{"", "slow_answer", "broken", "", "", ""}; !slices.Equal(reasons, want) {
+		t.Errorf("summary reasons = %q, want %q", reasons, want)
+	}
+}
+
+func TestWatchdogRoundAllPassed(t *testing.T) {
+	m, rec := newTestWatchdog(t)
+	checks := scheduled(time.Second, stubCheck{name: "ok", result: Result{Status: StatusOK}})
+	m.runRound(context.Background(), checks)
+	m.runRound(context.Background(), checks)
+
+	sums := summaries(rec.events)
+	if len(sums) != 2 {
+		t.Fatalf("got %d round summaries, want one per round", len(sums))
+	}
+	for i, sum := range sums {
+		if sum.Severity != SeverityInfo || sum.Fields["round"] != int64(i+1) ||
+			!strings.HasPrefix(sum.Message, fmt.Sprintf("Round %d: 1 of 1 checks passed", i+1)) ||
+			strings.Contains(sum.Message, "not ok") {
+			t.Errorf("summary %d = %v %q", i+1, sum.Severity, sum.Message)
+		}
+	}
+}
+
+func TestWatchdogRoundCancelled(t *testing.T) {
+	m, rec := newTestWatchdog(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	checks := scheduled(time.Minute,
+		stubCheck{name: "ok", result: Result{Status: StatusOK}},
+		stubCheck{name: "stuck", delay: time.Minute},
+	)
+	// Cancel once the quick check has reported, while the other still runs
+	m.logger.AddHook(func(e Event) {
+		if e.Fields["check"] == "ok" {
+			cancel()
+		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.runRound(ctx, checks)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("runRound() did not return after cancellation")
+	}
+
+	var logged []string
+	for _, e := range rec.events {
+		if name, ok := e.Fields["check"].(string); ok {
+			logged = append(logged, name)
+		}
+	}
+	if !slices.Equal(logged, []string{"ok"}) {
+		t.Errorf("results logged = %q, want only the one before cancellation", logged)
+	}
+	if sums := summaries(rec.events); len(sums) != 0 {
+		t.Errorf("cancelled round summarized: %q", sums[0].Message)
+	}
+}